@@ -0,0 +1,250 @@
+// Mock OpenID Connect provider, to try the external login locally. Every authorization is approved without
+// asking, for the email on the login_hint parameter (or -email).
+//
+//	go run ./cmd/mockidp -addr :9000 -issuer http://localhost:9000
+//
+// and run the server with OIDC_PROVIDERS=mock, OIDC_MOCK_ISSUER=http://localhost:9000,
+// OIDC_MOCK_CLIENT_ID=gophis and OIDC_MOCK_CLIENT_SECRET=secret.
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/maxolivera/gophis-social-network/internal/auth"
+)
+
+type authorization struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+	expiresAt     time.Time
+}
+
+type server struct {
+	issuer        string
+	clientID      string
+	clientSecret  string
+	email         string
+	emailVerified bool
+	key           *auth.Key
+
+	mu    sync.Mutex
+	codes map[string]authorization
+}
+
+func main() {
+	addr := flag.String("addr", ":9000", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9000", "issuer, must be the URL the server reaches this provider on")
+	clientID := flag.String("client-id", "gophis", "client ID")
+	clientSecret := flag.String("client-secret", "secret", "client secret")
+	email := flag.String("email", "mock.user@example.com", "email of the user, unless the login_hint parameter is sent")
+	emailVerified := flag.Bool("email-verified", true, "whether the email is reported as verified")
+	flag.Parse()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		log.Fatalf("could not generate key: %v", err)
+	}
+
+	s := &server{
+		issuer:        strings.TrimSuffix(*issuer, "/"),
+		clientID:      *clientID,
+		clientSecret:  *clientSecret,
+		email:         *email,
+		emailVerified: *emailVerified,
+		key: &auth.Key{
+			ID:      "mock-" + randomString(4),
+			Method:  jwt.SigningMethodEdDSA,
+			Private: private,
+			Public:  public,
+		},
+		codes: make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.handlerDiscovery)
+	mux.HandleFunc("GET /authorize", s.handlerAuthorize)
+	mux.HandleFunc("POST /token", s.handlerToken)
+	mux.HandleFunc("GET /jwks", s.handlerJWKS)
+
+	log.Printf("mock identity provider listening on %s, issuer %s", *addr, s.issuer)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+func (s *server) handlerDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + "/authorize",
+		"token_endpoint":                        s.issuer + "/token",
+		"jwks_uri":                              s.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{s.key.Method.Alg()},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func (s *server) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, auth.JWKSet{Keys: []auth.JWK{s.key.JWK()}})
+}
+
+func (s *server) handlerAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("client_id") != s.clientID {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	// From here on, errors are sent back to the client
+	values := redirectURI.Query()
+	values.Set("state", query.Get("state"))
+
+	switch {
+	case query.Get("response_type") != "code":
+		values.Set("error", "unsupported_response_type")
+	case !strings.Contains(" "+query.Get("scope")+" ", " openid "):
+		values.Set("error", "invalid_scope")
+	case query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256":
+		values.Set("error", "invalid_request")
+		values.Set("error_description", "PKCE with S256 is required")
+	default:
+		email := s.email
+		if hint := query.Get("login_hint"); hint != "" {
+			email = hint
+		}
+
+		code := randomString(32)
+		s.mu.Lock()
+		s.codes[code] = authorization{
+			redirectURI:   redirectURI.String(),
+			codeChallenge: query.Get("code_challenge"),
+			nonce:         query.Get("nonce"),
+			email:         email,
+			expiresAt:     time.Now().Add(time.Minute),
+		}
+		s.mu.Unlock()
+
+		values.Set("code", code)
+		log.Printf("authorized %s", email)
+	}
+
+	redirectURI.RawQuery = values.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *server) handlerToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request", "invalid form")
+		return
+	}
+
+	// Client authentication, client_secret_basic or client_secret_post
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.clientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(s.clientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client", "")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+
+	// Codes are single use
+	s.mu.Lock()
+	authz, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	switch {
+	case !ok || time.Now().After(authz.expiresAt):
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "unknown or expired code")
+		return
+	case r.PostForm.Get("redirect_uri") != authz.redirectURI:
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri mismatch")
+		return
+	case challenge(r.PostForm.Get("code_verifier")) != authz.codeChallenge:
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "code_verifier mismatch")
+		return
+	}
+
+	currentTime := time.Now()
+	username, _, _ := strings.Cut(authz.email, "@")
+	claims := jwt.MapClaims{
+		"iss":                s.issuer,
+		"sub":                "mock|" + authz.email,
+		"aud":                s.clientID,
+		"iat":                currentTime.Unix(),
+		"exp":                currentTime.Add(5 * time.Minute).Unix(),
+		"nonce":              authz.nonce,
+		"email":              authz.email,
+		"email_verified":     s.emailVerified,
+		"preferred_username": username,
+	}
+	token := jwt.NewWithClaims(s.key.Method, claims)
+	token.Header["kid"] = s.key.ID
+
+	idToken, err := token.SignedString(s.key.Private)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(32),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func tokenError(w http.ResponseWriter, code int, errCode, description string) {
+	writeJSON(w, code, map[string]string{"error": errCode, "error_description": description})
+}
+
+func writeJSON(w http.ResponseWriter, code int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+func challenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func randomString(size int) string {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		log.Fatalf("could not create random string: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
@@ -3,7 +3,12 @@ package main
 import (
 	"context"
 	"expvar"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -12,7 +17,10 @@ import (
 	"github.com/maxolivera/gophis-social-network/internal/auth"
 	"github.com/maxolivera/gophis-social-network/internal/cache"
 	"github.com/maxolivera/gophis-social-network/internal/env"
+	"github.com/maxolivera/gophis-social-network/internal/mailer"
+	"github.com/maxolivera/gophis-social-network/internal/oidc"
 	"github.com/maxolivera/gophis-social-network/internal/ratelimiter"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
 	"github.com/maxolivera/gophis-social-network/internal/storage/postgres"
 	fixedwindow "github.com/maxolivera/gophis-social-network/pkg/fixed-window"
 	"github.com/maxolivera/gophis-social-network/pkg/lru"
@@ -45,38 +53,106 @@ func main() {
 	redisPass, err := env.GetString("REDIS_PASSWORD", logger)
 	redisDb, err := env.GetInt("REDIS_DB", logger)
 	secret, err := env.GetString("JWT_SECRET", logger)
+	signingKey, _ := env.GetString("JWT_SIGNING_KEY", logger)             // Optional
+	verificationKeys, _ := env.GetString("JWT_VERIFICATION_KEYS", logger) // Optional, comma separated
 	requestsLimit, err := env.GetInt("REQUESTS_LIMIT", logger)
 	timeFrame, err := env.GetInt("TIME_FRAME", logger)
 	limiterEnabled, err := env.GetString("LIMITER_ENABLED", logger)
 	corsAllowed, err := env.GetString("CORS_ALLOWED_ORIGIN", logger)
+	frontendUrl, _ := env.GetString("FRONTEND_URL", logger) // Optional, emailed links point to the API without it
+	mailerKind, _ := env.GetString("MAILER", logger)        // Optional, defaults to FILE
+	mailFrom, _ := env.GetString("MAIL_FROM", logger)       // Optional
+	mailDir, _ := env.GetString("MAIL_DIR", logger)         // Optional
+	smtpHost, _ := env.GetString("SMTP_HOST", logger)
+	smtpPort, _ := env.GetInt("SMTP_PORT", logger)
+	smtpUser, _ := env.GetString("SMTP_USERNAME", logger)
+	smtpPass, _ := env.GetString("SMTP_PASSWORD", logger)
+	totpRequiredRoles, _ := env.GetString("TOTP_REQUIRED_ROLES", logger) // Optional, comma separated
+	totpEncryptionKey, _ := env.GetString("TOTP_ENCRYPTION_KEY", logger) // Optional, defaults to JWT_SECRET. Changing it breaks enabled two-factors
+	lockoutThreshold, _ := env.GetInt("LOCKOUT_THRESHOLD", logger)       // Optional, defaults to 5
+	lockoutIPThreshold, _ := env.GetInt("LOCKOUT_IP_THRESHOLD", logger)  // Optional, defaults to 20
+	trustedProxies, _ := env.GetString("TRUSTED_PROXIES", logger)        // Optional, comma separated IPs or CIDRs. Forwarded headers are ignored without it
+	oidcProviders, _ := env.GetString("OIDC_PROVIDERS", logger)          // Optional, comma separated
+	suggestionsInterval, _ := env.GetInt("SUGGESTIONS_INTERVAL", logger) // Optional, minutes, defaults to 60
+	exportDir, _ := env.GetString("EXPORT_DIR", logger)                  // Optional, defaults to a temporary directory. Must be shared by every instance.
+	exportSecret, _ := env.GetString("EXPORT_SECRET", logger)            // Optional, defaults to JWT_SECRET
 
 	if err != nil {
 		logger.Fatalf("error loading env values: %v\n", err)
 	}
+	if mailerKind == "" {
+		mailerKind = "FILE"
+	}
+	if mailFrom == "" {
+		mailFrom = "Gophis Social <no-reply@localhost>"
+	}
+	if lockoutThreshold <= 0 {
+		lockoutThreshold = 5
+	}
+	if lockoutIPThreshold <= 0 {
+		lockoutIPThreshold = 20
+	}
+	if suggestionsInterval <= 0 {
+		suggestionsInterval = 60
+	}
+	if exportDir == "" {
+		exportDir = filepath.Join(os.TempDir(), "gophis-exports")
+	}
+	if exportSecret == "" {
+		exportSecret = secret
+	}
+	if totpEncryptionKey == "" {
+		totpEncryptionKey = secret
+	}
+	proxies, err := parsePrefixes(splitList(trustedProxies))
+	if err != nil {
+		logger.Fatalf("invalid TRUSTED_PROXIES: %v\n", err)
+	}
 
 	// == CONFIG ==
 	cfg := &api.Config{
-		Addr:        addr,
-		CorsAllowed: corsAllowed,
-		Environment: environment,
-		Version:     Version,
-		ApiUrl:      apiUrl,
+		Addr:           addr,
+		CorsAllowed:    corsAllowed,
+		TrustedProxies: proxies,
+		Environment:    environment,
+		Version:        Version,
+		ApiUrl:         apiUrl,
+		FrontendUrl:    frontendUrl,
 		Database: &api.DBConfig{
 			Addr:               dbUrl,
 			MaxOpenConnections: maxOpenConns,
 			MaxIdleConnections: maxIdleConns,
 			MaxIdleTime:        time.Duration(time.Duration(maxIdleTime) * time.Minute),
 		},
-		ExpirationTime: 3 * 24 * time.Hour,
+		ExpirationTime:              3 * 24 * time.Hour,
+		PasswordResetExpirationTime: 30 * time.Minute,
+		EmailChangeExpirationTime:   24 * time.Hour,
+		DeletionGracePeriod:         30 * 24 * time.Hour,
 		Authentication: &api.AuthConfig{
 			BasicAuth: &api.BasicAuth{
 				Username: user,
 				Password: pass,
 			},
 			Token: &api.TokenConfig{
-				Secret:         secret,
-				ExpirationTime: 3 * 24 * time.Hour,
-				Issuer:         "gophissocial",
+				Secret:                secret,
+				SigningKey:            signingKey,
+				VerificationKeys:      splitList(verificationKeys),
+				ExpirationTime:        15 * time.Minute,
+				RefreshExpirationTime: 30 * 24 * time.Hour,
+				Issuer:                "gophissocial",
+			},
+			TwoFactor: &api.TwoFactorConfig{
+				Issuer:                  "Gophis Social",
+				EncryptionKey:           totpEncryptionKey,
+				ChallengeExpirationTime: 5 * time.Minute,
+				RequiredRoles:           roleTypes(splitList(totpRequiredRoles)),
+			},
+			Lockout: &api.LockoutConfig{
+				Threshold:   lockoutThreshold,
+				IPThreshold: lockoutIPThreshold,
+				Window:      15 * time.Minute,
+				BaseDelay:   30 * time.Second,
+				MaxDelay:    time.Hour,
 			},
 		},
 		RateLimiter: &api.RateLimiterConfig{
@@ -84,14 +160,66 @@ func main() {
 			TimeFrame: time.Duration(timeFrame) * time.Second,
 			Enabled:   (limiterEnabled == "TRUE"),
 		},
+		Mailer: &api.MailerConfig{
+			Kind:         mailerKind,
+			From:         mailFrom,
+			Timeout:      time.Minute,
+			MaxRetries:   3,
+			RetryBackoff: 2 * time.Second,
+			Dir:          mailDir,
+			SMTP: &api.SMTPConfig{
+				Host:     smtpHost,
+				Port:     smtpPort,
+				Username: smtpUser,
+				Password: smtpPass,
+			},
+		},
+		Suggestions: &api.SuggestionsConfig{
+			Interval:      time.Duration(suggestionsInterval) * time.Minute,
+			Window:        30 * 24 * time.Hour,
+			AuthorsPerTag: 100,
+			MaxPerUser:    50,
+		},
+		Export: &api.ExportConfig{
+			Dir:            exportDir,
+			Secret:         exportSecret,
+			ExpirationTime: 24 * time.Hour,
+		},
 	}
 
 	// == AUTH ==
-	authenticator := auth.NewJWTAuthenticator(
-		cfg.Authentication.Token.Secret,
-		cfg.Authentication.Token.Issuer,
-		cfg.Authentication.Token.Issuer,
-	)
+	var authenticator auth.Authenticator
+	if cfg.Authentication.Token.SigningKey == "" {
+		authenticator = auth.NewJWTAuthenticator(
+			cfg.Authentication.Token.Secret,
+			cfg.Authentication.Token.Issuer,
+			cfg.Authentication.Token.Issuer,
+		)
+	} else {
+		key, err := auth.LoadKey(cfg.Authentication.Token.SigningKey)
+		if err != nil {
+			logger.Fatalf("could not load signing key: %v\n", err)
+		}
+
+		keys := make([]*auth.Key, len(cfg.Authentication.Token.VerificationKeys))
+		for i, path := range cfg.Authentication.Token.VerificationKeys {
+			keys[i], err = auth.LoadKey(path)
+			if err != nil {
+				logger.Fatalf("could not load verification key: %v\n", err)
+			}
+		}
+
+		authenticator, err = auth.NewJWTAuthenticatorWithKeys(
+			key,
+			keys,
+			cfg.Authentication.Token.Issuer,
+			cfg.Authentication.Token.Issuer,
+		)
+		if err != nil {
+			logger.Fatalf("could not create authenticator: %v\n", err)
+		}
+		logger.Infow("signing tokens with asymmetric key", "kid", key.ID, "alg", key.Method.Alg(), "verification_keys", len(keys)+1)
+	}
 
 	// == CACHE ==
 	var cacheStorage *cache.Storage
@@ -117,6 +245,9 @@ func main() {
 		}
 	} else {
 		cacheConfig.Enabled = false
+		// NOTE(maolivera): Token revocations are always tracked, even if users are not cached
+		lru := lru.NewLRUCache(lruCap, time.Duration(lruTTL)*time.Minute)
+		cacheStorage = cache.NewLRUStorage(lru)
 	}
 	cfg.Cache = cacheConfig
 
@@ -160,6 +291,61 @@ func main() {
 		)
 	}
 
+	// == MAILER ==
+	var mail mailer.Mailer
+	switch cfg.Mailer.Kind {
+	case "SMTP":
+		smtpMailer, err := mailer.NewSMTPMailer(
+			cfg.Mailer.SMTP.Host,
+			cfg.Mailer.SMTP.Port,
+			cfg.Mailer.SMTP.Username,
+			cfg.Mailer.SMTP.Password,
+			cfg.Mailer.From,
+		)
+		if err != nil {
+			logger.Fatalf("could not create SMTP mailer: %v\n", err)
+		}
+		mail = smtpMailer
+	case "FILE":
+		mail = mailer.NewFileMailer(cfg.Mailer.Dir, cfg.Mailer.From, logger)
+	case "MEMORY":
+		mail = mailer.NewMemoryMailer()
+	default:
+		logger.Fatalf("unsupported mailer %q, must be SMTP, FILE or MEMORY\n", cfg.Mailer.Kind)
+	}
+
+	// == ENCRYPTION ==
+	secrets, err := auth.NewSecretBox(cfg.Authentication.TwoFactor.EncryptionKey)
+	if err != nil {
+		logger.Fatalf("could not create the encryption of secrets: %v\n", err)
+	}
+
+	// == IDENTITY PROVIDERS ==
+	// NOTE(maolivera): Each provider reads OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID and OIDC_<NAME>_CLIENT_SECRET
+	providers := make(map[string]*oidc.Provider)
+	for _, name := range splitList(oidcProviders) {
+		prefix := "OIDC_" + strings.ToUpper(name)
+		issuer, _ := env.GetString(prefix+"_ISSUER", logger)
+		clientID, _ := env.GetString(prefix+"_CLIENT_ID", logger)
+		clientSecret, _ := env.GetString(prefix+"_CLIENT_SECRET", logger)
+
+		discoverCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		provider, err := oidc.Discover(discoverCtx, oidc.Config{
+			Name:         name,
+			Issuer:       issuer,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  fmt.Sprintf("%s/v1/oidc/%s/callback", cfg.ApiUrl, name),
+		})
+		cancel()
+		if err != nil {
+			logger.Errorw("skipping identity provider", "provider", name, "error", err)
+			continue
+		}
+		providers[name] = provider
+		logger.Infow("identity provider configured", "provider", name, "issuer", issuer)
+	}
+
 	// == APPLICATION ==
 	app := &api.Application{
 		Config:        cfg,
@@ -169,7 +355,22 @@ func main() {
 		Logger:        logger,
 		Authenticator: authenticator,
 		RateLimiter:   rateLimiter,
+		Mailer:        mail,
+		Secrets:       secrets,
+		OIDCProviders: providers,
+	}
+
+	permissionsCtx, cancelPermissions := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := app.LoadPermissions(permissionsCtx); err != nil {
+		logger.Fatalf("could not load permissions: %v\n", err)
+	}
+	cancelPermissions()
+
+	secretsCtx, cancelSecrets := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := app.EncryptTOTPSecrets(secretsCtx); err != nil {
+		logger.Fatalf("could not encrypt TOTP secrets: %v\n", err)
 	}
+	cancelSecrets()
 
 	expvar.NewString("version").Set(cfg.Version)
 	expvar.Publish("database", expvar.Func(func() any {
@@ -195,3 +396,43 @@ func main() {
 
 	logger.Fatalln(app.Start())
 }
+
+// Splits a comma separated list, ignoring empty values
+func splitList(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Parses IPs and CIDRs, an IP being a prefix with only itself
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, len(values))
+	for i, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, err
+			}
+			prefixes[i] = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		prefixes[i] = prefix.Masked()
+	}
+	return prefixes, nil
+}
+
+func roleTypes(names []string) []models.RoleType {
+	roles := make([]models.RoleType, len(names))
+	for i, name := range names {
+		roles[i] = models.RoleType(name)
+	}
+	return roles
+}
@@ -6,8 +6,10 @@ import (
 	"expvar"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -18,6 +20,8 @@ import (
 	"github.com/maxolivera/gophis-social-network/docs"
 	"github.com/maxolivera/gophis-social-network/internal/auth"
 	"github.com/maxolivera/gophis-social-network/internal/cache"
+	"github.com/maxolivera/gophis-social-network/internal/mailer"
+	"github.com/maxolivera/gophis-social-network/internal/oidc"
 	"github.com/maxolivera/gophis-social-network/internal/ratelimiter"
 	"github.com/maxolivera/gophis-social-network/internal/storage"
 	"github.com/maxolivera/gophis-social-network/internal/storage/models"
@@ -33,19 +37,69 @@ type Application struct {
 	Logger        *zap.SugaredLogger
 	Authenticator auth.Authenticator
 	RateLimiter   ratelimiter.Limiter
+	Mailer        mailer.Mailer
+	// Encrypts the secrets stored which must be read back, e.g. TOTP secrets
+	Secrets *auth.SecretBox
+	// External identity providers, by name
+	OIDCProviders map[string]*oidc.Provider
+
+	// Tracks background tasks, e.g. emails, so they are not lost on shutdown
+	wg sync.WaitGroup
+	// Permissions of each role, see LoadPermissions
+	permissions permissionCache
 }
 
 type Config struct {
-	CorsAllowed    string
-	Addr           string
-	Database       *DBConfig
-	Environment    string
-	Version        string
-	ApiUrl         string
+	CorsAllowed                 string
+	TrustedProxies              []netip.Prefix // Only these can set the address of the client with X-Forwarded-For or X-Real-IP
+	Addr                        string
+	Database                    *DBConfig
+	Environment                 string
+	Version                     string
+	ApiUrl                      string
+	FrontendUrl                 string // Where the links sent by email point to, if the API is used through a frontend
+	ExpirationTime              time.Duration
+	PasswordResetExpirationTime time.Duration
+	EmailChangeExpirationTime   time.Duration
+	DeletionGracePeriod         time.Duration // Time users have to log in and cancel the deletion of their account
+	Authentication              *AuthConfig
+	Cache                       *CacheConfig
+	RateLimiter                 *RateLimiterConfig
+	Mailer                      *MailerConfig
+	Suggestions                 *SuggestionsConfig
+	Export                      *ExportConfig
+}
+
+// NOTE(maolivera): Archives are read from Dir by whichever instance gets the download, so with more than one
+// instance it must be a volume shared by all of them.
+type ExportConfig struct {
+	Dir            string // Where archives are kept until they expire
+	Secret         string // Signs the download links
 	ExpirationTime time.Duration
-	Authentication *AuthConfig
-	Cache          *CacheConfig
-	RateLimiter    *RateLimiterConfig
+}
+
+type SuggestionsConfig struct {
+	Interval      time.Duration // Time between refreshes
+	Window        time.Duration // Only posts of this period count as recent activity and interests
+	AuthorsPerTag int           // Only the authors posting the most with a tag are suggested for it
+	MaxPerUser    int
+}
+
+type MailerConfig struct {
+	Kind         string // SMTP, FILE or MEMORY
+	From         string
+	Timeout      time.Duration
+	MaxRetries   int
+	RetryBackoff time.Duration
+	SMTP         *SMTPConfig
+	Dir          string // Only for FILE. If empty, emails are logged
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
 }
 
 type RateLimiterConfig struct {
@@ -75,12 +129,32 @@ type RedisConfig struct {
 type AuthConfig struct {
 	BasicAuth *BasicAuth
 	Token     *TokenConfig
+	TwoFactor *TwoFactorConfig
+	Lockout   *LockoutConfig
+}
+
+type LockoutConfig struct {
+	Threshold   int           // Failed logins in a row before an account is locked
+	IPThreshold int           // Failed logins in a row before an IP is locked
+	Window      time.Duration // Failures older than this are forgotten
+	BaseDelay   time.Duration // First lock, doubled on every further failure
+	MaxDelay    time.Duration
+}
+
+type TwoFactorConfig struct {
+	Issuer                  string // Shown by authenticator apps
+	EncryptionKey           string // Encrypts the TOTP secrets, which can not be read back with a different one
+	ChallengeExpirationTime time.Duration
+	RequiredRoles           []models.RoleType
 }
 
 type TokenConfig struct {
-	Secret         string
-	ExpirationTime time.Duration
-	Issuer         string
+	Secret                string
+	SigningKey            string   // Path to PEM private key. If empty, tokens are signed with Secret (HS256)
+	VerificationKeys      []string // Paths to PEM keys still accepted for verification, e.g. keys being rotated out
+	ExpirationTime        time.Duration
+	RefreshExpirationTime time.Duration
+	Issuer                string
 }
 
 type BasicAuth struct {
@@ -116,6 +190,14 @@ func (app *Application) Start() error {
 
 	// TODO(maolivera): Maybe move this to main?
 
+	// == Background Jobs ==
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	app.background(func() { app.refreshSuggestionsPeriodically(jobsCtx) })
+	app.background(func() { app.cleanExportsPeriodically(jobsCtx) })
+	app.background(func() { app.purgeUsersPeriodically(jobsCtx) })
+	app.background(func() { app.reloadPermissionsPeriodically(jobsCtx) })
+
 	// == Graceful Shutdown ==
 	shutdown := make(chan error)
 
@@ -143,6 +225,10 @@ func (app *Application) Start() error {
 		return err
 	}
 
+	app.Logger.Infow("waiting for background tasks", "addr", app.Config.Addr, "env", app.Config.Environment)
+	stopJobs()
+	app.wg.Wait()
+
 	app.Logger.Infow("server has stopped", "addr", app.Config.Addr, "env", app.Config.Environment)
 
 	return nil
@@ -152,15 +238,15 @@ func (app *Application) GetHandlers() http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(app.middlewareRealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins: []string{app.Config.ApiUrl}, // Use this to allow specific origin hosts
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match", "If-None-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -180,48 +266,153 @@ func (app *Application) GetHandlers() http.Handler {
 		r.Get("/healthz", app.handlerHealthz)
 		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
 		r.With(app.middlewareBasicAuth).Get("/debug/vars", expvar.Handler().ServeHTTP)
+		r.Get("/.well-known/jwks.json", app.handlerJWKS)
 
 		// Non-auth routes
 		r.Post("/register", app.handlerCreateUser)
 		r.Post("/activate/{token}", app.handlerActivateUser)
+		r.Get("/activate/{token}", app.handlerActivateUser) // Link sent by email
+		r.Post("/email/confirm/{token}", app.handlerConfirmEmailChange)
+		r.Get("/email/confirm/{token}", app.handlerConfirmEmailChange) // Link sent by email
 		r.Post("/token", app.handlerCreateToken)
+		r.Post("/token/refresh", app.handlerRefreshToken)
+		r.Post("/token/2fa", app.handlerTwoFactorLogin)
+		r.Post("/password/forgot", app.handlerForgotPassword)
+		r.Post("/password/reset", app.handlerResetPassword)
+		r.Get("/oidc/{provider}/login", app.handlerOIDCLogin)
+		r.Get("/oidc/{provider}/callback", app.handlerOIDCCallback)
+		r.Get("/exports/{exportID}", app.handlerDownloadExport)
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.middlewareAuthToken)
+			r.Use(app.middlewareSessionOnly)
+
+			r.Post("/logout", app.handlerLogout)
+			r.Post("/logout/all", app.handlerLogoutAll)
+		})
+
+		r.Route("/me", func(r chi.Router) {
+			r.Use(app.middlewareAuthToken)
+
+			// Credentials can not be managed with personal access tokens
+			r.Group(func(r chi.Router) {
+				r.Use(app.middlewareSessionOnly)
+
+				// NOTE(maolivera): Two-factor is not enforced here, otherwise users could not enroll
+				r.Post("/2fa", app.handlerEnrollTwoFactor)
+				r.Post("/2fa/confirm", app.handlerConfirmTwoFactor)
+				r.Delete("/2fa", app.handlerDisableTwoFactor)
+
+				r.Get("/tokens", app.handlerListPersonalAccessTokens)
+				r.Post("/tokens", app.handlerCreatePersonalAccessToken)
+				r.Delete("/tokens/{tokenID}", app.handlerRevokePersonalAccessToken)
+
+				r.Post("/email", app.handlerChangeEmail)
+				r.With(app.middlewareTwoFactorEnforced).Post("/export", app.handlerExportData)
+
+				r.Get("/sessions", app.handlerListSessions)
+				r.Delete("/sessions/{sessionID}", app.handlerRevokeSession)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.middlewareTwoFactorEnforced)
+
+				r.Put("/privacy", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerSetPrivacy))
+
+				r.Get("/follow-requests", app.middlewareRequireScope(models.ScopeUsersRead, app.handlerListFollowRequests))
+				r.Put("/follow-requests/{username}", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerAcceptFollowRequest))
+				r.Delete("/follow-requests/{username}", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerRejectFollowRequest))
+
+				r.Get("/blocks", app.middlewareRequireScope(models.ScopeUsersRead, app.handlerListBlocks))
+				r.Get("/suggestions", app.middlewareRequireScope(models.ScopeUsersRead, app.handlerListSuggestions))
+
+				r.Get("/mutes", app.middlewareRequireScope(models.ScopeUsersRead, app.handlerListMutes))
+				r.Post("/mutes", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerCreateMute))
+				r.Patch("/mutes/{muteID}", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerUpdateMute))
+				r.Delete("/mutes/{muteID}", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerDeleteMute))
+			})
+		})
 
 		// Add routes
 		r.Route("/users", func(r chi.Router) {
 			r.Use(app.middlewareAuthToken)
+			r.Use(app.middlewareTwoFactorEnforced)
 			r.Route("/{username}", func(r chi.Router) {
 				r.Use(app.middlewareRouteUserContext)
 
-				r.Get("/", app.handlerGetUser)
-				r.Patch("/", app.handlerUpdateUser)
-				// TODO(maolivera): add role to modify user
-				// TODO(maolivera): add hard delete for admins
-				r.Delete("/", app.handlerSoftDeleteUser)
+				r.Get("/", app.middlewareRequireScope(models.ScopeUsersRead, app.handlerGetUser))
+				r.Patch("/", app.middlewareRequireScope(models.ScopeUsersWrite, app.middlewareUserPermissions(models.PermissionUsersUpdateAny, app.handlerUpdateUser)))
+				r.Delete("/", app.middlewareRequireScope(models.ScopeUsersWrite, app.middlewareUserPermissions(models.PermissionUsersDeleteAny, app.handlerDeleteUser)))
+				r.With(app.requirePermission(models.PermissionUsersHardDelete)).Delete("/hard", app.handlerHardDeleteUser)
+
+				r.Put("/follow", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerFollowUser))
+				r.Put("/unfollow", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerUnfollowUser))
+				r.Get("/followers", app.middlewareRequireScope(models.ScopeUsersRead, app.handlerListFollowers))
+				r.Get("/following", app.middlewareRequireScope(models.ScopeUsersRead, app.handlerListFollowing))
 
-				r.Put("/follow", app.handlerFollowUser)
-				r.Put("/unfollow", app.handlerUnfollowUser)
+				r.Put("/block", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerBlockUser))
+				r.Put("/unblock", app.middlewareRequireScope(models.ScopeUsersWrite, app.handlerUnblockUser))
 
 			})
 		})
 
 		r.Route("/posts", func(r chi.Router) {
 			r.Use(app.middlewareAuthToken)
+			r.Use(app.middlewareTwoFactorEnforced)
 
-			r.Post("/", app.handlerCreatePost)
+			r.Post("/", app.middlewareRequireScope(models.ScopePostsWrite, app.handlerCreatePost))
 			r.Route("/{postID}", func(r chi.Router) {
 				r.Use(app.middlewarePostContext)
 
-				r.Get("/", app.handlerGetPost)
-				r.Patch("/", app.middlewarePostPermissions(models.RoleModerator, true, app.handlerUpdatePost))
-				r.Delete("/", app.middlewarePostPermissions(models.RoleAdmin, true, app.handlerSoftDeletePost))
-				r.Delete("/hard", app.middlewarePostPermissions(models.RoleAdmin, false, app.handlerHardDeletePost))
+				r.Get("/", app.middlewareRequireScope(models.ScopePostsRead, app.handlerGetPost))
+				r.Get("/revisions", app.middlewareRequireScope(models.ScopePostsRead, app.handlerListPostRevisions))
+				r.Get("/diff", app.middlewareRequireScope(models.ScopePostsRead, app.handlerDiffPost))
+				r.Patch("/", app.middlewareRequireScope(models.ScopePostsWrite, app.middlewarePostPermissions(models.PermissionPostsUpdateAny, true, app.middlewarePostIfMatch(app.handlerUpdatePost))))
+				r.Delete("/", app.middlewareRequireScope(models.ScopePostsWrite, app.middlewarePostPermissions(models.PermissionPostsDeleteAny, true, app.middlewarePostIfMatch(app.handlerSoftDeletePost))))
+				r.Delete("/hard", app.middlewareRequireScope(models.ScopePostsWrite, app.middlewarePostPermissions(models.PermissionPostsHardDelete, false, app.middlewarePostIfMatch(app.handlerHardDeletePost))))
+
+				r.Post("/comment", app.middlewareRequireScope(models.ScopePostsWrite, app.handlerCreateComment))
+			})
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.middlewareAuthToken)
+			r.Use(app.middlewareTwoFactorEnforced)
+
+			r.With(app.requirePermission(models.PermissionLoginAttemptsRead)).Get("/login-attempts", app.handlerListLoginAttempts)
+			r.With(app.requirePermission(models.PermissionAuditLogRead)).Get("/audit-log", app.handlerListAuditLog)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requirePermission(models.PermissionRolesManage))
+
+				r.Get("/roles", app.handlerListRoles)
+				r.Post("/roles", app.handlerCreateRole)
+				r.Get("/permissions", app.handlerListPermissions)
+				r.Put("/roles/{role}/permissions/{permission}", app.handlerGrantPermission)
+				r.Delete("/roles/{role}/permissions/{permission}", app.handlerRevokePermission)
+			})
+
+			r.Route("/deleted", func(r chi.Router) {
+				r.With(app.requirePermission(models.PermissionUsersRestore)).Get("/users", app.handlerListDeletedUsers)
+				r.With(app.requirePermission(models.PermissionUsersRestore)).Post("/users/{userID}/restore", app.handlerRestoreUser)
+
+				r.With(app.requirePermission(models.PermissionPostsRestore)).Get("/posts", app.handlerListDeletedPosts)
+				r.With(app.requirePermission(models.PermissionPostsRestore)).Post("/posts/{postID}/restore", app.handlerRestorePost)
+			})
+
+			r.Route("/users/{username}", func(r chi.Router) {
+				r.Use(app.middlewareRouteUserContext)
+
+				r.With(app.requirePermission(models.PermissionRolesManage)).Put("/role", app.handlerAssignRole)
+				r.With(app.requirePermission(models.PermissionRolesManage)).Delete("/role", app.handlerRevokeRole)
 
-				r.Post("/comment", app.handlerCreateComment)
+				r.With(app.requirePermission(models.PermissionSessionsManage)).Get("/sessions", app.handlerListUserSessions)
+				r.With(app.requirePermission(models.PermissionSessionsManage)).Delete("/sessions/{sessionID}", app.handlerRevokeUserSession)
 			})
 		})
 
-		r.With(app.middlewareAuthToken).Get("/feed", app.handlerFeed)
-		r.With(app.middlewareAuthToken).Get("/search", app.handlerSearch)
+		r.With(app.middlewareAuthToken, app.middlewareTwoFactorEnforced).Get("/feed", app.middlewareRequireScope(models.ScopeFeedRead, app.handlerFeed))
+		r.With(app.middlewareAuthToken, app.middlewareTwoFactorEnforced).Get("/search", app.middlewareRequireScope(models.ScopePostsRead, app.handlerSearch))
 	})
 
 	return r
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/auth"
+	"github.com/maxolivera/gophis-social-network/internal/mailer"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+	"go.uber.org/zap"
+)
+
+const testApiUrl = "http://api.test"
+
+// Application whose emails are kept in memory. Repositories are left nil, each test sets the ones it uses.
+func newTestApplication(t *testing.T) (*Application, *mailer.MemoryMailer) {
+	t.Helper()
+
+	mail := mailer.NewMemoryMailer()
+	app := &Application{
+		Config: &Config{
+			Environment:                 "test",
+			ApiUrl:                      testApiUrl,
+			ExpirationTime:              24 * time.Hour,
+			PasswordResetExpirationTime: time.Hour,
+			Authentication: &AuthConfig{
+				Token: &TokenConfig{
+					Secret:                "test",
+					ExpirationTime:        15 * time.Minute,
+					RefreshExpirationTime: 24 * time.Hour,
+					Issuer:                "test",
+				},
+				TwoFactor: &TwoFactorConfig{
+					Issuer:                  "Test",
+					ChallengeExpirationTime: 5 * time.Minute,
+				},
+				Lockout: &LockoutConfig{
+					Threshold:   3,
+					IPThreshold: 10,
+					Window:      time.Minute,
+					BaseDelay:   time.Minute,
+					MaxDelay:    time.Hour,
+				},
+			},
+			Mailer: &MailerConfig{
+				Kind:         "MEMORY",
+				From:         "Test <no-reply@test>",
+				Timeout:      time.Second,
+				RetryBackoff: time.Millisecond,
+			},
+		},
+		Storage:       &storage.Storage{},
+		Logger:        zap.NewNop().Sugar(),
+		Authenticator: auth.NewJWTAuthenticator("test", "test", "test"),
+		Mailer:        mail,
+	}
+
+	return app, mail
+}
+
+// Sent emails, once the background tasks sending them are done
+func sentEmails(app *Application, mail *mailer.MemoryMailer) []*mailer.Message {
+	app.wg.Wait()
+	return mail.Messages()
+}
+
+// First link of the email starting with `prefix`, empty if there is none
+func emailLink(text, prefix string) string {
+	start := strings.Index(text, prefix)
+	if start < 0 {
+		return ""
+	}
+	link, _, _ := strings.Cut(text[start:], "\n")
+	return strings.TrimSpace(link)
+}
+
+func testUser(username, email string) *models.User {
+	currentTime := time.Now().UTC()
+	return &models.User{
+		ID:        uuid.New(),
+		CreatedAt: currentTime,
+		UpdatedAt: currentTime,
+		Username:  username,
+		Email:     email,
+	}
+}
+
+// Users kept in memory. Calling any other method panics.
+type fakeUserRepository struct {
+	storage.UserRepository
+
+	mu        sync.Mutex
+	users     map[uuid.UUID]*models.User
+	passwords map[uuid.UUID]string
+	// Last tokens created, by email
+	invitations    map[string][]byte
+	passwordResets map[string][]byte
+}
+
+func newFakeUserRepository() *fakeUserRepository {
+	return &fakeUserRepository{
+		users:          make(map[uuid.UUID]*models.User),
+		passwords:      make(map[uuid.UUID]string),
+		invitations:    make(map[string][]byte),
+		passwordResets: make(map[string][]byte),
+	}
+}
+
+func (r *fakeUserRepository) add(user *models.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[user.ID] = user
+}
+
+func (r *fakeUserRepository) addWithPassword(user *models.User, password string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[user.ID] = user
+	r.passwords[user.ID] = password
+}
+
+func (r *fakeUserRepository) byEmail(email string) *models.User {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user
+		}
+	}
+	return nil
+}
+
+func (r *fakeUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, storage.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepository) GetByEmailAndPassword(ctx context.Context, email, password string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.byEmail(email)
+	if user == nil || r.passwords[user.ID] != password {
+		return nil, storage.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *fakeUserRepository) CreateAndInvite(ctx context.Context, user *models.UserWithPassword, token []byte, exp time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.byEmail(user.User.Email) != nil {
+		return storage.ErrEmailUnavailable
+	}
+	created := user.User
+	r.users[created.ID] = &created
+	r.invitations[created.Email] = token
+	return nil
+}
+
+func (r *fakeUserRepository) CreatePasswordReset(ctx context.Context, email string, token []byte, exp time.Duration) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.byEmail(email)
+	if user == nil {
+		return nil, storage.ErrNoUser
+	}
+	r.passwordResets[email] = token
+	return user, nil
+}
+
+// Failed logins and locks kept in memory. Attempts are not recorded.
+type fakeLoginAttemptRepository struct {
+	storage.LoginAttemptRepository
+
+	mu       sync.Mutex
+	failures map[string]int
+	locks    map[string]time.Time
+}
+
+func newFakeLoginAttemptRepository() *fakeLoginAttemptRepository {
+	return &fakeLoginAttemptRepository{
+		failures: make(map[string]int),
+		locks:    make(map[string]time.Time),
+	}
+}
+
+func (r *fakeLoginAttemptRepository) Record(ctx context.Context, attempt *models.LoginAttempt) error {
+	return nil
+}
+
+func (r *fakeLoginAttemptRepository) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if until := r.locks[key]; time.Now().Before(until) {
+		return until, nil
+	}
+	return time.Time{}, nil
+}
+
+func (r *fakeLoginAttemptRepository) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[key]++
+	return r.failures[key], nil
+}
+
+func (r *fakeLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.locks[key] = until
+	return nil
+}
+
+func (r *fakeLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failures, key)
+	return nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Block godoc
+//
+//	@Summary		Blocks an User
+//	@Description	Logged user will block user at /{username}. Both users stop following each other, can not follow each other again, and do not see the posts and comments of each other. This is an idempotent endpoint.
+//	@tags			users
+//	@Param			username	path	string	true	"User to block"
+//	@Success		204			"User was blocked"
+//	@Failure		400			{object}	error	"Users can not block themselves"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		404			{object}	error	"User at /{username} was not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/users/{username}/block [put]
+func (app *Application) handlerBlockUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	routeUser := getRouteUser(r)
+	loggedUser := getLoggedUser(r)
+
+	if routeUser.ID == loggedUser.ID {
+		err := errors.New("users can not block themselves")
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	if err := app.Storage.Blocks.Block(ctx, loggedUser.ID, routeUser.ID); err != nil {
+		err = fmt.Errorf("error during blocking user: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	app.Logger.Infow("user blocked", "username", routeUser.Username, "by", loggedUser.Username)
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// Unblock godoc
+//
+//	@Summary		Unblocks an User
+//	@Description	Logged user will unblock user at /{username}. Follows removed by the block are not restored. This is an idempotent endpoint.
+//	@tags			users
+//	@Param			username	path	string	true	"User to unblock"
+//	@Success		204			"User was unblocked"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		404			{object}	error	"User at /{username} was not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/users/{username}/unblock [put]
+func (app *Application) handlerUnblockUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	routeUser := getRouteUser(r)
+	loggedUser := getLoggedUser(r)
+
+	if err := app.Storage.Blocks.Unblock(ctx, loggedUser.ID, routeUser.ID); err != nil {
+		err = fmt.Errorf("error during unblocking user: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	app.Logger.Infow("user unblocked", "username", routeUser.Username, "by", loggedUser.Username)
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// List Blocks godoc
+//
+//	@Summary		Lists blocked users
+//	@Description	Lists the users blocked by the logged user, newest first
+//	@tags			users
+//	@Produce		json
+//	@Success		200	{array}		models.Block
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/blocks [get]
+func (app *Application) handlerListBlocks(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	blocks, err := app.Storage.Blocks.GetByUser(ctx, user.ID)
+	if err != nil {
+		err = fmt.Errorf("error fetching users blocked by %s: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, blocks)
+}
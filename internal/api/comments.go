@@ -25,6 +25,7 @@ type CreateCommentPayload struct {
 //	@Success		200		{object}	models.Comment
 //	@Failure		500		{object}	error	"Something went wrong on the server"
 //	@Failure		401		{object}	error	"User not logged in"
+//	@Failure		403		{object}	error	"The author of the post or the user blocked the other"
 //	@Failure		404		{object}	error	"User or post not found"
 //	@Failure		400		{object}	error	"Some parameter was either not provided or is invalid (e.g. content too long)"
 //	@Security		ApiKeyAuth
@@ -47,12 +48,25 @@ func (app *Application) handlerCreateComment(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// NOTE(maolivera): Blocked users can not see the post anyway, unless they moderate it
+	blocked, err := app.Storage.Blocks.IsBlocked(ctx, post.UserID, user.ID)
+	if err != nil {
+		err = fmt.Errorf("error checking if user is blocked: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if blocked {
+		err := fmt.Errorf("user %s tried to comment on post %v, but one of them blocked the other", user.Username, post.ID)
+		app.respondWithError(w, r, http.StatusForbidden, err, "you can not comment on this post")
+		return
+	}
+
 	// create comment
 	id := uuid.New()
 	currentTime := time.Now().UTC()
 	comment := &models.Comment{
 		ID:        id,
-		User:      user,
+		User:      models.ReducedUser{ID: user.ID, Username: user.Username},
 		PostID:    post.ID,
 		CreatedAt: currentTime,
 		UpdatedAt: currentTime,
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+// Users purged at once by the background job
+const purgeBatchSize = 100
+
+type DeletionResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+// Delete User godoc
+//
+//	@Summary		Requests the deletion of a User
+//	@Description	Schedules the deletion of the user after a grace period of 30 days, and logs it out everywhere. Logging in before it ends cancels the deletion. Afterwards its posts, follows and credentials are deleted, its comments are kept under an anonymous username, and its username and email become available. Only the user itself, or staff with the users:delete_any permission, can request it.
+//	@Tags			users
+//	@Produce		json
+//	@Param			username	path		string	true	"Username"
+//	@Success		202			{object}	DeletionResponse
+//	@Failure		403			{object}	error	"Forbidden"
+//	@Failure		404			{object}	error	"User not found"
+//	@Failure		409			{object}	error	"The deletion was already requested"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/users/{username} [delete]
+func (app *Application) handlerDeleteUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getRouteUser(r)
+
+	// NOTE(maolivera): Requesting it again would only postpone the deletion
+	if user.DeletionScheduledAt != nil {
+		err := fmt.Errorf("deletion of user %s was already scheduled at %v", user.Username, *user.DeletionScheduledAt)
+		app.respondWithError(w, r, http.StatusConflict, err, "deletion already requested")
+		return
+	}
+
+	at := time.Now().UTC().Add(app.Config.DeletionGracePeriod)
+	if err := app.Storage.Users.ScheduleDeletion(ctx, user.ID, at); err != nil {
+		switch err {
+		case storage.ErrNoUser:
+			err := fmt.Errorf("deletion of user %s was not scheduled because it was not found", user.Username)
+			app.respondWithError(w, r, http.StatusNotFound, err, "user not found")
+		default:
+			err := fmt.Errorf("deletion of user %s could not be scheduled: %v", user.Username, err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	app.Logger.Infow("user deletion scheduled", "username", user.Username, "by", getLoggedUser(r).Username, "at", at)
+
+	// Log out everywhere, so the deletion is only cancelled by logging in again
+	if err := app.revokeUserTokens(ctx, user); err != nil {
+		app.Logger.Errorw("could not revoke tokens of user to delete", "username", user.Username, "error", err.Error())
+	}
+
+	if app.Config.Cache.Enabled {
+		app.Cache.Users.Delete(ctx, user.Username)
+	}
+
+	app.sendNotification(user.Email, user.Username,
+		"Your Gophis Social account will be deleted",
+		fmt.Sprintf("The deletion of your account was requested, and it will be deleted on %s. If you want to keep it, just log in before then.", at.Format(time.RFC1123)),
+	)
+
+	app.respondWithJSON(w, r, http.StatusAccepted, &DeletionResponse{DeletionScheduledAt: at})
+}
+
+// Cancels the scheduled deletion of a user who logged in
+func (app *Application) cancelDeletion(ctx context.Context, user *models.User) error {
+	if err := app.Storage.Users.CancelDeletion(ctx, user.ID); err != nil {
+		return fmt.Errorf("error cancelling deletion of user %s: %v", user.Username, err)
+	}
+	user.DeletionScheduledAt = nil
+	app.Logger.Infow("user deletion cancelled", "username", user.Username)
+
+	if app.Config.Cache.Enabled {
+		app.Cache.Users.Delete(ctx, user.Username)
+	}
+
+	return nil
+}
+
+// Purges the users whose deletion is due now and then on every hour, until ctx is done
+func (app *Application) purgeUsersPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		app.purgeUsers(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *Application) purgeUsers(ctx context.Context) {
+	for {
+		users, err := app.Storage.Users.GetToPurge(ctx, time.Now().UTC(), purgeBatchSize)
+		if err != nil {
+			app.Logger.Errorw("could not fetch users to purge", "error", err.Error())
+			return
+		}
+
+		for _, user := range users {
+			// NOTE(maolivera): Every instance purges them, the deletion being locked makes it harmless
+			if err := app.Storage.Users.Purge(ctx, user.ID); err != nil {
+				if err == storage.ErrNoUser {
+					continue // Cancelled or purged meanwhile
+				}
+				app.Logger.Errorw("could not purge user", "username", user.Username, "error", err.Error())
+				return
+			}
+			app.Logger.Infow("user purged", "username", user.Username, "id", user.ID)
+
+			if app.Config.Cache.Enabled {
+				app.Cache.Users.Delete(ctx, user.Username)
+			}
+		}
+
+		if len(users) < purgeBatchSize {
+			return
+		}
+	}
+}
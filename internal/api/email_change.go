@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/maxolivera/gophis-social-network/internal/mailer"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+)
+
+type ChangeEmailPayload struct {
+	Email string `json:"email"`
+	// Current password of the user
+	Password string `json:"password"`
+}
+
+// Change Email godoc
+//
+//	@Summary		Requests an email change
+//	@Description	Sends a confirmation token to the new address, and a notice to the current one. The email only changes once the token is confirmed on /email/confirm/{token}.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body	ChangeEmailPayload	true	"New email and current password"
+//	@Success		202		"Confirmation was sent to the new address"
+//	@Failure		400		{object}	error	"Some parameter was either not provided or invalid."
+//	@Failure		401		{object}	error	"Unauthorized or invalid password"
+//	@Failure		423		{object}	error	"Password checks locked after too many invalid passwords"
+//	@Failure		429		{object}	error	"Too many failed logins from the IP"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/email [post]
+func (app *Application) handlerChangeEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	user := getLoggedUser(r)
+
+	in := ChangeEmailPayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when changing an email: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	{ // Validate input
+		// Empty input
+		if in.Email == "" || in.Password == "" {
+			err := errors.New("email and password are required")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		// Email
+		if len(in.Email) > 255 {
+			err := errors.New("email is too long")
+			app.respondWithError(w, r, http.StatusBadRequest, err, "email is too long")
+			return
+		}
+		if _, err := mail.ParseAddress(in.Email); err != nil {
+			err := fmt.Errorf("email is invalid: %v", err)
+			app.respondWithError(w, r, http.StatusBadRequest, err, "email is invalid")
+			return
+		}
+		if strings.EqualFold(in.Email, user.Email) {
+			err := errors.New("email is the current one")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+	}
+
+	// NOTE(maolivera): The password is asked again, a stolen token alone must not be enough to move the account
+	if !app.checkUserPassword(w, r, user, in.Password, "invalid password") {
+		return
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		err = fmt.Errorf("error creating token: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if err := app.Storage.Users.CreateEmailChange(ctx, user.ID, in.Email, token, app.Config.EmailChangeExpirationTime); err != nil {
+		err = fmt.Errorf("error creating email change: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	app.Logger.Infow("email change requested", "username", user.Username)
+
+	encodedToken := base64.URLEncoding.EncodeToString(token)
+	app.sendEmail(in.Email, mailer.TemplateEmailChange, mailer.TokenData{
+		Username:  user.Username,
+		URL:       app.linkURL("/email/confirm/%s", url.QueryEscape(encodedToken)),
+		Token:     encodedToken,
+		ExpiresIn: app.Config.EmailChangeExpirationTime.String(),
+	})
+	app.sendNotification(user.Email, user.Username,
+		"Your Gophis Social email is being changed",
+		fmt.Sprintf("Someone requested to change the email of your account to %s. It will only change once the new address is confirmed. If it was not you, reset your password and close your sessions.", in.Email),
+	)
+
+	app.respondWithJSON(w, r, http.StatusAccepted, nil)
+}
+
+// Confirm Email Change godoc
+//
+//	@Summary		Confirms an email change
+//	@Description	Changes the email of the account which requested the change. The token can only be used once.
+//	@Tags			users
+//	@Produce		json
+//	@Param			token	path	string	true	"Confirmation token"
+//	@Success		204		"Email was changed"
+//	@Failure		400		{object}	error	"Invalid token"
+//	@Failure		404		{object}	error	"Token not found or expired"
+//	@Failure		409		{object}	error	"Email was taken meanwhile"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Router			/email/confirm/{token} [post]
+//	@Router			/email/confirm/{token} [get]
+func (app *Application) handlerConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	// Decode token
+	tokenStr := r.PathValue("token")
+	if tokenStr == "" {
+		err := fmt.Errorf("token required but it was not found, url: %s", r.URL.String())
+		app.respondWithError(w, r, http.StatusBadRequest, err, "token not provided/found")
+		return
+	}
+	decodedToken, err := url.QueryUnescape(tokenStr)
+	if err != nil {
+		err := fmt.Errorf("error unescaping token: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid token")
+		return
+	}
+	token, err := base64.URLEncoding.DecodeString(decodedToken)
+	if err != nil {
+		err := fmt.Errorf("error decoding token string: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid token")
+		return
+	}
+
+	user, err := app.Storage.Users.ConfirmEmailChange(ctx, token)
+	if err != nil {
+		switch err {
+		case storage.ErrNoToken:
+			app.respondWithError(w, r, http.StatusNotFound, err, "token not found or expired")
+		case storage.ErrNoUser:
+			app.respondWithError(w, r, http.StatusNotFound, err, "user not found")
+		case storage.ErrEmailUnavailable:
+			app.respondWithError(w, r, http.StatusConflict, err, "email is not available")
+		default:
+			err = fmt.Errorf("error confirming email change: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	app.Logger.Infow("email changed", "username", user.Username)
+
+	if app.Config.Cache.Enabled {
+		app.Cache.Users.Delete(ctx, user.Username)
+	}
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestChangeEmailLocksAfterInvalidPasswords(t *testing.T) {
+	app, _ := newTestApplication(t)
+	users := newFakeUserRepository()
+	app.Storage.Users = users
+	app.Storage.LoginAttempts = newFakeLoginAttemptRepository()
+	user := testUser("gopher", "gopher@example.com")
+	users.addWithPassword(user, "secret")
+
+	changeEmail := func(password string) int {
+		body := `{"email": "new@example.com", "password": "` + password + `"}`
+		r := httptest.NewRequest(http.MethodPost, "/v1/me/email", strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), contextKeyLoggedUser, user))
+		w := httptest.NewRecorder()
+		app.handlerChangeEmail(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < app.Config.Authentication.Lockout.Threshold; i++ {
+		if code := changeEmail("wrong"); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: expected status %d, got %d", i+1, http.StatusUnauthorized, code)
+		}
+	}
+
+	// Even the right password is refused until the lock expires
+	if code := changeEmail("secret"); code != http.StatusLocked {
+		t.Errorf("expected status %d, got %d", http.StatusLocked, code)
+	}
+}
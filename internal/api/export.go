@@ -0,0 +1,326 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/mailer"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+// Users whose follows are fetched at once while exporting
+const exportFollowsPageSize = 1000
+
+// Longest an export takes to be generated. Later, another export of the user can be requested.
+const exportTimeout = 5 * time.Minute
+
+type ExportResponse struct {
+	ID uuid.UUID `json:"id"`
+}
+
+// Export Data godoc
+//
+//	@Summary		Exports the data of the logged user
+//	@Description	Gathers the profile, posts, comments, follows, follow requests, blocks, mutes and pending invitations of the logged user into a ZIP of JSON files. It is generated on the background, and a download link which expires is sent to the user email once it is ready.
+//	@Tags			users
+//	@Produce		json
+//	@Success		202	{object}	ExportResponse
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		409	{object}	error	"An export of the user is already being generated"
+//	@Failure		500	{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/export [post]
+func (app *Application) handlerExportData(w http.ResponseWriter, r *http.Request) {
+	user := getLoggedUser(r)
+	id := uuid.New()
+
+	// NOTE(maolivera): Tracked on the database, so repeated requests do not generate many archives whichever
+	// instance gets them
+	staleBefore := time.Now().UTC().Add(-exportTimeout)
+	if err := app.Storage.Exports.Start(r.Context(), user.ID, id, staleBefore); err != nil {
+		switch err {
+		case storage.ErrInProgress:
+			err := fmt.Errorf("user %s requested an export while another one was being generated", user.Username)
+			app.respondWithError(w, r, http.StatusConflict, err, "your data is already being exported")
+		default:
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	app.Logger.Infow("data export requested", "username", user.Username, "export_id", id)
+
+	app.background(func() {
+		defer app.finishExport(user, id)
+
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+
+		if err := app.exportData(ctx, id, user.ID); err != nil {
+			app.Logger.Errorw("could not export data", "username", user.Username, "export_id", id, "error", err.Error())
+			app.sendNotification(user.Email, user.Username,
+				"Your Gophis Social data could not be exported",
+				"Something went wrong while gathering the data of your account. Please, request it again later.",
+			)
+			return
+		}
+		app.Logger.Infow("data exported", "username", user.Username, "export_id", id)
+
+		expiresAt := time.Now().UTC().Add(app.Config.Export.ExpirationTime)
+		app.sendEmail(user.Email, mailer.TemplateExport, mailer.ExportData{
+			Username:  user.Username,
+			URL:       app.exportURL(id, expiresAt),
+			ExpiresIn: app.Config.Export.ExpirationTime.String(),
+		})
+	})
+
+	app.respondWithJSON(w, r, http.StatusAccepted, &ExportResponse{ID: id})
+}
+
+// Download Export godoc
+//
+//	@Summary		Downloads an export
+//	@Description	Downloads the archive of an export. The link is sent by email, and it does not require to be logged in.
+//	@Tags			users
+//	@Produce		application/zip
+//	@Param			exportID	path	string	true	"Export ID"
+//	@Param			expires		query	int		true	"Unix time when the link expires"
+//	@Param			signature	query	string	true	"Signature of the link"
+//	@Success		200			"ZIP archive"
+//	@Failure		403			{object}	error	"Invalid signature"
+//	@Failure		404			{object}	error	"Export not found"
+//	@Failure		410			{object}	error	"Link expired"
+//	@Router			/exports/{exportID} [get]
+func (app *Application) handlerDownloadExport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	id, err := uuid.Parse(r.PathValue("exportID"))
+	if err != nil {
+		app.respondWithError(w, r, http.StatusNotFound, err, "export not found")
+		return
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusForbidden, err, "invalid link")
+		return
+	}
+
+	expected := app.signExport(id, expires)
+	if !hmac.Equal([]byte(query.Get("signature")), []byte(expected)) {
+		err := fmt.Errorf("invalid signature of export %v", id)
+		app.respondWithError(w, r, http.StatusForbidden, err, "invalid link")
+		return
+	}
+	if time.Now().UTC().Unix() > expires {
+		err := fmt.Errorf("link of export %v expired", id)
+		app.respondWithError(w, r, http.StatusGone, err, "link expired")
+		return
+	}
+
+	file, err := os.Open(app.exportPath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			app.respondWithError(w, r, http.StatusNotFound, err, "export not found")
+			return
+		}
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gophis-export-%s.zip"`, id))
+	http.ServeContent(w, r, "export.zip", info.ModTime(), file)
+}
+
+// Lets the user request another export, logging if it could not
+func (app *Application) finishExport(user *models.User, id uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), storage.QueryTimeDuration)
+	defer cancel()
+
+	if err := app.Storage.Exports.Finish(ctx, user.ID, id); err != nil {
+		app.Logger.Errorw("could not finish export", "username", user.Username, "export_id", id, "error", err.Error())
+	}
+}
+
+// Gathers the data of the user and writes the archive of the export
+func (app *Application) exportData(ctx context.Context, id, userID uuid.UUID) error {
+	user, err := app.Storage.Users.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching user: %v", err)
+	}
+	invitations, err := app.Storage.Users.GetInvitations(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching invitations: %v", err)
+	}
+	posts, err := app.Storage.Posts.GetByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching posts: %v", err)
+	}
+	comments, err := app.Storage.Comments.GetByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching comments: %v", err)
+	}
+	followers, err := app.exportFollows(ctx, userID, app.Storage.Followers.GetFollowers)
+	if err != nil {
+		return fmt.Errorf("error fetching followers: %v", err)
+	}
+	following, err := app.exportFollows(ctx, userID, app.Storage.Followers.GetFollowing)
+	if err != nil {
+		return fmt.Errorf("error fetching following: %v", err)
+	}
+	followRequests, err := app.Storage.Followers.GetRequests(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching follow requests: %v", err)
+	}
+	blocks, err := app.Storage.Blocks.GetByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching blocks: %v", err)
+	}
+	mutes, err := app.Storage.Mutes.GetByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error fetching mutes: %v", err)
+	}
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", user.SelfView()},
+		{"invitations.json", invitations},
+		{"posts.json", posts},
+		{"comments.json", comments},
+		{"followers.json", followers},
+		{"following.json", following},
+		{"follow_requests.json", followRequests},
+		{"blocks.json", blocks},
+		{"mutes.json", mutes},
+	}
+
+	if err := os.MkdirAll(app.Config.Export.Dir, 0o700); err != nil {
+		return fmt.Errorf("error creating export directory: %v", err)
+	}
+	// NOTE(maolivera): Written to a temporary file first, so a half written archive is never downloaded
+	tmp, err := os.CreateTemp(app.Config.Export.Dir, "export-*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating archive: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive := zip.NewWriter(tmp)
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return fmt.Errorf("error adding %s to archive: %v", file.name, err)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(file.data); err != nil {
+			return fmt.Errorf("error writing %s: %v", file.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("error closing archive: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error closing archive: %v", err)
+	}
+
+	return os.Rename(tmp.Name(), app.exportPath(id))
+}
+
+// Fetches every page of the followers or following, depending on `fetch`, of a user
+func (app *Application) exportFollows(
+	ctx context.Context,
+	userID uuid.UUID,
+	fetch func(context.Context, uuid.UUID, int32, *models.Follow) ([]*models.Follow, error),
+) ([]*models.Follow, error) {
+	follows := []*models.Follow{}
+	var after *models.Follow
+	for {
+		page, err := fetch(ctx, userID, exportFollowsPageSize, after)
+		if err != nil {
+			return nil, err
+		}
+		follows = append(follows, page...)
+		if len(page) < exportFollowsPageSize {
+			return follows, nil
+		}
+		after = page[len(page)-1]
+	}
+}
+
+// Deletes the expired archives now and then on every hour, until ctx is done
+func (app *Application) cleanExportsPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		app.cleanExports()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *Application) cleanExports() {
+	entries, err := os.ReadDir(app.Config.Export.Dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			app.Logger.Errorw("could not read export directory", "error", err.Error())
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < app.Config.Export.ExpirationTime {
+			continue
+		}
+		if err := os.Remove(filepath.Join(app.Config.Export.Dir, entry.Name())); err != nil {
+			app.Logger.Errorw("could not delete expired export", "file", entry.Name(), "error", err.Error())
+		}
+	}
+}
+
+func (app *Application) exportPath(id uuid.UUID) string {
+	return filepath.Join(app.Config.Export.Dir, id.String()+".zip")
+}
+
+// Signs the ID of the export and when its link expires, so links can not be forged nor extended
+func (app *Application) signExport(id uuid.UUID, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(app.Config.Export.Secret))
+	fmt.Fprintf(mac, "%s.%d", id, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (app *Application) exportURL(id uuid.UUID, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires, 10)},
+		"signature": {app.signExport(id, expires)},
+	}
+	return app.externalURL("/exports/%s?%s", id, query.Encode())
+}
@@ -1,19 +1,40 @@
 package api
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
 )
 
+type PrivacyPayload struct {
+	IsPrivate *bool `json:"is_private"`
+}
+
+type FollowsResponse struct {
+	Users []*models.Follow `json:"users"`
+	// Cursor of the next page, empty on the last one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
 // Follow godoc
 //
 //	@Summary		Follows an User
-//	@Description	Logged user will start following user at /{username}. This is an idempotent endpoint, which means that it will always produce the same result, or in other words, if some user tries to follow someone who is already following it, nothing will happen
+//	@Description	Logged user will start following user at /{username}. This is an idempotent endpoint, which means that it will always produce the same result, or in other words, if some user tries to follow someone who is already following it, nothing will happen. If the user is private, a follow request is sent instead, which the user must accept.
 //	@tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			username	path	string	true	"User to follow"
+//	@Success		202			"User is private, a follow request was sent"
 //	@Success		204			"Follower will follow username"
+//	@Failure		403			{object}	error	"One of the users blocked the other"
 //	@Failure		500			{object}	error
 //	@Failure		404			{object}	error	"User at /{username} was not found"
 //	@Security		ApiKeyAuth
@@ -23,6 +44,38 @@ func (app *Application) handlerFollowUser(w http.ResponseWriter, r *http.Request
 	routeUser := getRouteUser(r)
 	loggedUser := getLoggedUser(r)
 
+	blocked, err := app.Storage.Blocks.IsBlocked(ctx, routeUser.ID, loggedUser.ID)
+	if err != nil {
+		err = fmt.Errorf("error checking if user is blocked: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if blocked {
+		err := fmt.Errorf("user %s tried to follow %s, but one of them blocked the other", loggedUser.Username, routeUser.Username)
+		app.respondWithError(w, r, http.StatusForbidden, err, "you can not follow this user")
+		return
+	}
+
+	if routeUser.IsPrivate && routeUser.ID != loggedUser.ID {
+		following, err := app.Storage.Followers.IsFollowing(ctx, routeUser.ID, loggedUser.ID)
+		if err != nil {
+			err = fmt.Errorf("error checking if user is followed: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			return
+		}
+		if !following {
+			if err := app.Storage.Followers.Request(ctx, routeUser.ID, loggedUser.ID); err != nil {
+				err = fmt.Errorf("error requesting to follow user: %v", err)
+				app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+				return
+			}
+			app.Logger.Infow("follow requested", "username", routeUser.Username, "by", loggedUser.Username)
+
+			app.respondWithJSON(w, r, http.StatusAccepted, nil)
+			return
+		}
+	}
+
 	if err := app.Storage.Followers.Follow(ctx, routeUser.ID, loggedUser.ID); err != nil {
 		err = fmt.Errorf("error during following user: %v", err)
 		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
@@ -35,7 +88,7 @@ func (app *Application) handlerFollowUser(w http.ResponseWriter, r *http.Request
 // Unfollow godoc
 //
 //	@Summary		Unfollows an User
-//	@Description	Logged user will start following user at /{username}. This is an idempotent endpoint, which means that it will always produce the same result, or in other words, if some user tries to follow someone who is already following it, nothing will happen
+//	@Description	Logged user will stop following user at /{username}, or cancel its pending follow request. This is an idempotent endpoint, which means that it will always produce the same result, or in other words, if some user tries to unfollow someone who it is not following, nothing will happen
 //	@tags			users
 //	@Accept			json
 //	@Produce		json
@@ -50,9 +103,273 @@ func (app *Application) handlerUnfollowUser(w http.ResponseWriter, r *http.Reque
 	routeUser := getRouteUser(r)
 	loggedUser := getLoggedUser(r)
 
-	if err := app.Storage.Followers.Follow(ctx, routeUser.ID, loggedUser.ID); err != nil {
-		err = fmt.Errorf("error during following user: %v", err)
+	if err := app.Storage.Followers.Unfollow(ctx, routeUser.ID, loggedUser.ID); err != nil {
+		err = fmt.Errorf("error during unfollowing user: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// Set Privacy godoc
+//
+//	@Summary		Makes the logged user private or public
+//	@Description	Only followers can see the posts of private users, and following them requires their approval. Pending follow requests are accepted when the user becomes public.
+//	@tags			users
+//	@Accept			json
+//	@Param			Payload	body	PrivacyPayload	true	"Whether the user is private"
+//	@Success		204		"Privacy was changed"
+//	@Failure		400		{object}	error	"Some parameter was either not provided or invalid."
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/privacy [put]
+func (app *Application) handlerSetPrivacy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	in := PrivacyPayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when changing privacy: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if in.IsPrivate == nil {
+		err := errors.New("is_private is required")
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	if err := app.Storage.Users.SetPrivate(ctx, user.ID, *in.IsPrivate); err != nil {
+		err = fmt.Errorf("error changing privacy of user %s: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	app.Logger.Infow("privacy changed", "username", user.Username, "is_private", *in.IsPrivate)
+
+	if app.Config.Cache.Enabled {
+		app.Cache.Users.Delete(ctx, user.Username)
+	}
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// List Follow Requests godoc
+//
+//	@Summary		Lists follow requests
+//	@Description	Lists the pending requests to follow the logged user, newest first
+//	@tags			users
+//	@Produce		json
+//	@Success		200	{array}		models.FollowRequest
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/follow-requests [get]
+func (app *Application) handlerListFollowRequests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	requests, err := app.Storage.Followers.GetRequests(ctx, user.ID)
+	if err != nil {
+		err = fmt.Errorf("error fetching follow requests of user %s: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, requests)
+}
+
+// Accept Follow Request godoc
+//
+//	@Summary		Accepts a follow request
+//	@tags			users
+//	@Param			username	path	string	true	"User who requested to follow"
+//	@Success		204			"User at /{username} now follows the logged user"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		404			{object}	error	"User or request not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/follow-requests/{username} [put]
+func (app *Application) handlerAcceptFollowRequest(w http.ResponseWriter, r *http.Request) {
+	app.answerFollowRequest(w, r, true)
+}
+
+// Reject Follow Request godoc
+//
+//	@Summary		Rejects a follow request
+//	@tags			users
+//	@Param			username	path	string	true	"User who requested to follow"
+//	@Success		204			"Request was rejected"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		404			{object}	error	"User or request not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/follow-requests/{username} [delete]
+func (app *Application) handlerRejectFollowRequest(w http.ResponseWriter, r *http.Request) {
+	app.answerFollowRequest(w, r, false)
+}
+
+// Accepts or rejects, depending on `accept`, the request of the user at /{username} to follow the logged user
+func (app *Application) answerFollowRequest(w http.ResponseWriter, r *http.Request, accept bool) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	requester, err := app.getUser(r, r.PathValue("username"))
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "user not found")
+		default:
+			err = fmt.Errorf("error fetching user: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	if accept {
+		err = app.Storage.Followers.AcceptRequest(ctx, user.ID, requester.ID)
+	} else {
+		err = app.Storage.Followers.RejectRequest(ctx, user.ID, requester.ID)
+	}
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "follow request not found")
+		default:
+			err = fmt.Errorf("error answering follow request: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	app.Logger.Infow("follow request answered", "username", user.Username, "requester", requester.Username, "accepted", accept)
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// List Followers godoc
+//
+//	@Summary		Lists the followers of an User
+//	@Description	Lists the users following the user at /{username}, newest first. The lists of private users are only visible to their followers.
+//	@tags			users
+//	@Produce		json
+//	@Param			username	path		string	true	"Username"
+//	@Param			limit		query		int		false	"Max number of users, up to 100"	default(20)
+//	@Param			cursor		query		string	false	"Next cursor of the previous page"
+//	@Success		200			{object}	FollowsResponse
+//	@Failure		400			{object}	error	"Invalid limit or cursor"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"User is private or blocked"
+//	@Failure		404			{object}	error	"User at /{username} was not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/users/{username}/followers [get]
+func (app *Application) handlerListFollowers(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.Storage.Followers.GetFollowers)
+}
+
+// List Following godoc
+//
+//	@Summary		Lists the users followed by an User
+//	@Description	Lists the users followed by the user at /{username}, newest first. The lists of private users are only visible to their followers.
+//	@tags			users
+//	@Produce		json
+//	@Param			username	path		string	true	"Username"
+//	@Param			limit		query		int		false	"Max number of users, up to 100"	default(20)
+//	@Param			cursor		query		string	false	"Next cursor of the previous page"
+//	@Success		200			{object}	FollowsResponse
+//	@Failure		400			{object}	error	"Invalid limit or cursor"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"User is private or blocked"
+//	@Failure		404			{object}	error	"User at /{username} was not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/users/{username}/following [get]
+func (app *Application) handlerListFollowing(w http.ResponseWriter, r *http.Request) {
+	app.listFollows(w, r, app.Storage.Followers.GetFollowing)
+}
+
+// Responds with a page of the followers or following, depending on `fetch`, of the user at /{username}
+func (app *Application) listFollows(
+	w http.ResponseWriter,
+	r *http.Request,
+	fetch func(context.Context, uuid.UUID, int32, *models.Follow) ([]*models.Follow, error),
+) {
+	ctx := r.Context()
+	routeUser := getRouteUser(r)
+	loggedUser := getLoggedUser(r)
+
+	// Validate input
+	limit, _, err := readPagination(r, 20, 100)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	var after *models.Follow
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		after, err = decodeFollowCursor(cursor)
+		if err != nil {
+			app.respondWithError(w, r, http.StatusBadRequest, err, "invalid cursor")
+			return
+		}
+	}
+
+	// Same rules as the posts: private users only show them to their followers
+	canView, err := app.Storage.Followers.CanViewPosts(ctx, routeUser.ID, loggedUser.ID)
+	if err != nil {
+		err = fmt.Errorf("error checking if user %s can view user %s: %v", loggedUser.Username, routeUser.Username, err)
 		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
 		return
 	}
+	if !canView {
+		err := fmt.Errorf("user %s can not view the follows of user %s", loggedUser.Username, routeUser.Username)
+		app.respondWithError(w, r, http.StatusForbidden, err, "you can not view the follows of this user")
+		return
+	}
+
+	// NOTE(maolivera): One more row is fetched to know if there is a next page
+	follows, err := fetch(ctx, routeUser.ID, limit+1, after)
+	if err != nil {
+		err = fmt.Errorf("error fetching follows of user %s: %v", routeUser.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	out := &FollowsResponse{Users: follows}
+	if len(follows) > int(limit) {
+		out.Users = follows[:limit]
+		out.NextCursor = encodeFollowCursor(out.Users[limit-1])
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, out)
+}
+
+// Cursors are opaque to clients, they hold when the follow started and the ID of the user, which breaks ties
+func encodeFollowCursor(f *models.Follow) string {
+	raw := f.CreatedAt.Format(time.RFC3339Nano) + "|" + f.User.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFollowCursor(cursor string) (*models.Follow, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding cursor: %v", err)
+	}
+	createdAtStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, fmt.Errorf("malformed cursor: %q", raw)
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing cursor time: %v", err)
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing cursor id: %v", err)
+	}
+
+	return &models.Follow{
+		User:      models.ReducedUser{ID: id},
+		CreatedAt: createdAt,
+	}, nil
 }
@@ -2,7 +2,9 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 )
 
 const MAX_BYTES = 1_048_578 // 1 MB
@@ -84,6 +86,31 @@ func readJSON(w http.ResponseWriter, r *http.Request, data any) error {
 	return decoder.Decode(data)
 }
 
+// Reads `limit` and `offset` from the query string. Limit defaults to `defaultLimit` and is capped at `maxLimit`.
+func readPagination(r *http.Request, defaultLimit, maxLimit int32) (int32, int32, error) {
+	query := r.URL.Query()
+	limit := defaultLimit
+	offset := int32(0)
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		value, err := strconv.ParseInt(limitStr, 10, 32)
+		if err != nil || value < 1 {
+			return 0, 0, fmt.Errorf("limit must be a positive integer")
+		}
+		limit = min(int32(value), maxLimit)
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		value, err := strconv.ParseInt(offsetStr, 10, 32)
+		if err != nil || value < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+		offset = int32(value)
+	}
+
+	return limit, offset, nil
+}
+
 func (app *Application) unauthorizedBasicErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.Logger.Warnf("unauthorized basic error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
 
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"net/http"
+)
+
+// JWKS godoc
+//
+//	@Summary		Public signing keys
+//	@Description	Publishes the public keys which can verify the tokens issued by this service, as a JSON Web Key Set. Tokens reference their key by the `kid` header. Empty if tokens are signed with a shared secret.
+//	@Tags			authorization
+//	@Produce		json
+//	@Success		200	{object}	auth.JWKSet
+//	@Router			/.well-known/jwks.json [get]
+func (app *Application) handlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	app.respondWithJSON(w, r, http.StatusOK, app.Authenticator.JWKS())
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+// Keys of the failed logins counters
+func loginAccountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func loginIPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Kept apart from the account key, as logging in with the password resets that one
+func twoFactorKey(userID uuid.UUID) string {
+	return "2fa:" + userID.String()
+}
+
+// Kept apart from the account key too, the password is asked again to logged users before sensitive changes
+func passwordKey(userID uuid.UUID) string {
+	return "password:" + userID.String()
+}
+
+// Address of the client, without port. middlewareRealIP already replaced it when behind a trusted proxy.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Checks if logins are locked for the account or the IP. If they are, it responds (423 or 429, with
+// Retry-After) and returns false.
+func (app *Application) checkLoginLock(w http.ResponseWriter, r *http.Request, email, ip string) bool {
+	return app.checkLock(w, r, email, loginAccountKey(email), ip)
+}
+
+// Same as checkLoginLock, for the second factor of the user
+func (app *Application) checkTwoFactorLock(w http.ResponseWriter, r *http.Request, user *models.User, ip string) bool {
+	return app.checkLock(w, r, user.Email, twoFactorKey(user.ID), ip)
+}
+
+func (app *Application) checkLock(w http.ResponseWriter, r *http.Request, email, accountKey, ip string) bool {
+	ctx := r.Context()
+
+	ipLockedUntil, err := app.Storage.LoginAttempts.LockedUntil(ctx, loginIPKey(ip))
+	if err != nil {
+		err = fmt.Errorf("error checking login lock of IP %s: %v", ip, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return false
+	}
+	if !ipLockedUntil.IsZero() {
+		app.recordLoginAttempt(ctx, r, email, ip, models.LoginThrottled)
+		app.Logger.Warnw("login throttled", "ip", ip, "until", ipLockedUntil)
+
+		w.Header().Set("Retry-After", retryAfterSeconds(ipLockedUntil))
+		err := fmt.Errorf("logins from %s are locked until %v", ip, ipLockedUntil)
+		app.respondWithError(w, r, http.StatusTooManyRequests, err, "too many failed logins, try again later")
+		return false
+	}
+
+	accountLockedUntil, err := app.Storage.LoginAttempts.LockedUntil(ctx, accountKey)
+	if err != nil {
+		err = fmt.Errorf("error checking login lock of account: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return false
+	}
+	if !accountLockedUntil.IsZero() {
+		app.recordLoginAttempt(ctx, r, email, ip, models.LoginLocked)
+
+		w.Header().Set("Retry-After", retryAfterSeconds(accountLockedUntil))
+		err := fmt.Errorf("logins for %s are locked until %v", email, accountLockedUntil)
+		app.respondWithError(w, r, http.StatusLocked, err, "account is temporarily locked, try again later")
+		return false
+	}
+
+	return true
+}
+
+// Counts a failed login against the account and the IP, locking them once they reach their threshold
+func (app *Application) recordLoginFailure(ctx context.Context, r *http.Request, email, ip string) {
+	app.recordFailure(ctx, r, email, loginAccountKey(email), ip, models.LoginInvalidCredentials)
+}
+
+// Counts a wrong second factor against the user and the IP, the same way as failed logins
+func (app *Application) recordTwoFactorFailure(ctx context.Context, r *http.Request, user *models.User, ip string) {
+	app.recordFailure(ctx, r, user.Email, twoFactorKey(user.ID), ip, models.LoginInvalidSecondFactor)
+}
+
+func (app *Application) recordFailure(ctx context.Context, r *http.Request, email, accountKey, ip string, outcome models.LoginOutcome) {
+	cfg := app.Config.Authentication.Lockout
+	app.recordLoginAttempt(ctx, r, email, ip, outcome)
+
+	for key, threshold := range map[string]int{
+		accountKey:     cfg.Threshold,
+		loginIPKey(ip): cfg.IPThreshold,
+	} {
+		failures, err := app.Storage.LoginAttempts.Fail(ctx, key, cfg.Window)
+		if err != nil {
+			app.Logger.Errorw("could not record failed login", "key", key, "error", err)
+			continue
+		}
+
+		delay := lockoutDelay(cfg, failures, threshold)
+		if delay == 0 {
+			continue
+		}
+
+		until := time.Now().UTC().Add(delay)
+		if err := app.Storage.LoginAttempts.Lock(ctx, key, until); err != nil {
+			app.Logger.Errorw("could not lock logins", "key", key, "error", err)
+			continue
+		}
+		app.Logger.Warnw("logins locked", "key", key, "failures", failures, "until", until)
+	}
+}
+
+// Forgets the failed logins of the account. The ones of the IP are kept, otherwise an attacker owning any
+// account could reset them.
+func (app *Application) recordLoginSuccess(ctx context.Context, r *http.Request, email, ip string) {
+	app.recordLoginAttempt(ctx, r, email, ip, models.LoginSuccess)
+
+	if err := app.Storage.LoginAttempts.Reset(ctx, loginAccountKey(email)); err != nil {
+		app.Logger.Errorw("could not reset failed logins", "error", err)
+	}
+}
+
+// Forgets the wrong second factors of the user. The login itself was already recorded with the password.
+func (app *Application) recordTwoFactorSuccess(ctx context.Context, user *models.User) {
+	if err := app.Storage.LoginAttempts.Reset(ctx, twoFactorKey(user.ID)); err != nil {
+		app.Logger.Errorw("could not reset failed second factors", "error", err)
+	}
+}
+
+// Checks the password asked again to a logged user before a sensitive change. Failures are counted like failed
+// logins, otherwise a stolen token would be enough to guess it. If it is wrong or the checks are locked, it
+// responds (401 with `message`, 423 or 429) and returns false.
+func (app *Application) checkUserPassword(w http.ResponseWriter, r *http.Request, user *models.User, password, message string) bool {
+	ctx := r.Context()
+	ip := clientIP(r)
+	if !app.checkLock(w, r, user.Email, passwordKey(user.ID), ip) {
+		return false
+	}
+
+	if _, err := app.Storage.Users.GetByEmailAndPassword(ctx, user.Email, password); err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.recordFailure(ctx, r, user.Email, passwordKey(user.ID), ip, models.LoginInvalidCredentials)
+			err = fmt.Errorf("invalid password of user %s", user.Username)
+			app.respondWithError(w, r, http.StatusUnauthorized, err, message)
+		default:
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return false
+	}
+
+	if err := app.Storage.LoginAttempts.Reset(ctx, passwordKey(user.ID)); err != nil {
+		app.Logger.Errorw("could not reset failed password checks", "error", err)
+	}
+	return true
+}
+
+func (app *Application) recordLoginAttempt(ctx context.Context, r *http.Request, email, ip string, outcome models.LoginOutcome) {
+	attempt := &models.LoginAttempt{
+		ID:        uuid.New(),
+		Email:     strings.ToLower(email),
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+		Outcome:   outcome,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := app.Storage.LoginAttempts.Record(ctx, attempt); err != nil {
+		app.Logger.Errorw("could not record login attempt", "outcome", outcome, "error", err)
+	}
+}
+
+// Lock duration after `failures` in a row. It starts at BaseDelay once the threshold is reached, and doubles
+// on every further failure up to MaxDelay.
+func lockoutDelay(cfg *LockoutConfig, failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	delay := cfg.BaseDelay
+	for i := threshold; i < failures && delay < cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, cfg.MaxDelay)
+}
+
+func retryAfterSeconds(until time.Time) string {
+	return strconv.Itoa(int(math.Ceil(time.Until(until).Seconds())))
+}
+
+// List Login Attempts godoc
+//
+//	@Summary		Lists login attempts
+//	@Description	Lists login attempts, newest first, so admins can review suspicious activity
+//	@Tags			admin
+//	@Produce		json
+//	@Param			email	query		string	false	"Filter by email"
+//	@Param			ip		query		string	false	"Filter by IP"
+//	@Param			failed	query		bool	false	"Only failed attempts"
+//	@Param			limit	query		int		false	"Number of attempts. Default 50; Maximum 200"
+//	@Param			offset	query		int		false	"Offset. Default 0"
+//	@Success		200		{array}		models.LoginAttempt
+//	@Failure		400		{object}	error	"Invalid parameters"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		403		{object}	error	"Forbidden"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/login-attempts [get]
+func (app *Application) handlerListLoginAttempts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	limit, offset, err := readPagination(r, 50, 200)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	failedOnly := query.Get("failed") == "true"
+
+	attempts, err := app.Storage.LoginAttempts.List(ctx, strings.ToLower(query.Get("email")), query.Get("ip"), failedOnly, limit, offset)
+	if err != nil {
+		err = fmt.Errorf("error fetching login attempts: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, attempts)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/maxolivera/gophis-social-network/internal/mailer"
+)
+
+// Runs fn on a goroutine tracked by the application, so the graceful shutdown waits for it
+func (app *Application) background(fn func()) {
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				app.Logger.Errorw("panic on background task", "error", err)
+			}
+		}()
+
+		fn()
+	}()
+}
+
+// Renders the template and sends it to `to` on the background, retrying on failure
+func (app *Application) sendEmail(to, template string, data any) {
+	app.background(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), app.Config.Mailer.Timeout)
+		defer cancel()
+
+		msg, err := mailer.Render(template, data)
+		if err != nil {
+			app.Logger.Errorw("could not render email", "template", template, "error", err.Error())
+			return
+		}
+		msg.To = to
+
+		if err := mailer.SendWithRetry(ctx, app.Mailer, msg, app.Config.Mailer.MaxRetries, app.Config.Mailer.RetryBackoff); err != nil {
+			app.Logger.Errorw("could not send email", "template", template, "error", err.Error())
+			return
+		}
+
+		app.Logger.Infow("email sent", "template", template)
+	})
+}
+
+// Sends a notification email to the user
+func (app *Application) sendNotification(to, username, subject, message string) {
+	app.sendEmail(to, mailer.TemplateNotification, mailer.NotificationData{
+		Username: username,
+		Subject:  subject,
+		Message:  message,
+	})
+}
+
+// Builds an URL of the API, `path` must start with a slash
+func (app *Application) externalURL(path string, args ...any) string {
+	return app.Config.ApiUrl + "/v1" + fmt.Sprintf(path, args...)
+}
+
+// Builds an URL of a page of the frontend, `path` must start with a slash. Empty if there is no frontend.
+func (app *Application) frontendURL(path string, args ...any) string {
+	if app.Config.FrontendUrl == "" {
+		return ""
+	}
+	return app.Config.FrontendUrl + fmt.Sprintf(path, args...)
+}
+
+// Builds the URL of a link sent by email, which points to the frontend if there is one, and to the API otherwise.
+// The route of the API must answer to GET, which is what opening the link does.
+func (app *Application) linkURL(path string, args ...any) string {
+	if link := app.frontendURL(path, args...); link != "" {
+		return link
+	}
+	return app.externalURL(path, args...)
+}
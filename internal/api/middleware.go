@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
 	"time"
 
@@ -22,12 +24,66 @@ const (
 	contextKeyLoggedUser     = contextKey("loggedUser")
 	contextKeyRouteUser      = contextKey("routeUser")
 	contextKeyLoggedUserRole = contextKey("loggedUserRole")
+	contextKeyTokenClaims    = contextKey("tokenClaims")
+	contextKeyAccessToken    = contextKey("personalAccessToken")
 )
 
+// Replaces the address of the client with the one forwarded by a trusted proxy. Forwarded headers sent by
+// anyone else are ignored, otherwise clients could pick the address the rate limiter and the lockout see.
+func (app *Application) middlewareRealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.isTrustedProxy(clientIP(r)) {
+			if ip := app.forwardedIP(r); ip != "" {
+				r.RemoteAddr = ip
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Address of the client according to the forwarded headers. X-Forwarded-For is read from the right, as
+// entries on its left were sent by the client, skipping the trusted proxies the request went through.
+func (app *Application) forwardedIP(r *http.Request) string {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				return ""
+			}
+			if i == 0 || !app.isTrustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+
+	if xrip := strings.TrimSpace(r.Header.Get("X-Real-IP")); xrip != "" {
+		if _, err := netip.ParseAddr(xrip); err == nil {
+			return xrip
+		}
+	}
+
+	return ""
+}
+
+func (app *Application) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, proxy := range app.Config.TrustedProxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 func (app *Application) middlewareRateLimiter(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.Config.RateLimiter.Enabled {
-			if allow, retryAfter := app.RateLimiter.Allow(r.RemoteAddr); !allow {
+			if allow, retryAfter := app.RateLimiter.Allow(clientIP(r)); !allow {
 				app.rateLimiteExceededErrorResponse(w, r, retryAfter.String())
 				return
 			}
@@ -52,7 +108,13 @@ func (app *Application) middlewareAuthToken(next http.Handler) http.Handler {
 			return
 		}
 
+		ctx := r.Context()
 		tokenStr := parts[1]
+		if strings.HasPrefix(tokenStr, models.PersonalAccessTokenPrefix) {
+			app.authPersonalAccessToken(w, r, next, tokenStr)
+			return
+		}
+
 		token, err := app.Authenticator.ValidateToken(tokenStr)
 		if err != nil {
 			err := fmt.Errorf("error during token validation: %v", err)
@@ -60,18 +122,46 @@ func (app *Application) middlewareAuthToken(next http.Handler) http.Handler {
 			return
 		}
 
-		// parse user id
 		claims := token.Claims.(jwt.MapClaims)
-		usernameRaw, ok := claims["sub"]
-		if !ok {
-			err := errors.New("user ID (sub) not found in token claims")
+
+		// check revocation
+		jti, err := getStringClaim(claims, "jti")
+		if err != nil {
 			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+			return
+		}
+		revoked, err := app.Cache.Revocations.IsRevoked(ctx, jti)
+		if err != nil {
+			err = fmt.Errorf("error checking token revocation: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			return
+		}
+		if revoked {
+			err := fmt.Errorf("token %s was revoked", jti)
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+			return
 		}
 
-		username, ok := usernameRaw.(string)
-		if !ok {
-			err := errors.New("user ID (sub) in token claims is not a valid string")
+		// check if the session was revoked (e.g. from another device)
+		if sid, err := getStringClaim(claims, "sid"); err == nil {
+			revoked, err := app.Cache.Revocations.IsRevoked(ctx, sid)
+			if err != nil {
+				err = fmt.Errorf("error checking session revocation: %v", err)
+				app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+				return
+			}
+			if revoked {
+				err := fmt.Errorf("session %s was revoked", sid)
+				app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+				return
+			}
+		}
+
+		// parse user id
+		username, err := getStringClaim(claims, "sub")
+		if err != nil {
 			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+			return
 		}
 
 		user, err := app.getUser(r, username)
@@ -86,12 +176,141 @@ func (app *Application) middlewareAuthToken(next http.Handler) http.Handler {
 			return
 		}
 
-		ctx := r.Context()
+		// check if every token of the user was revoked (e.g. log out from all sessions)
+		revokedUntil, err := app.Cache.Revocations.RevokedUntil(ctx, user.ID.String())
+		if err != nil {
+			err = fmt.Errorf("error checking user revocation: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			return
+		}
+		if !revokedUntil.IsZero() {
+			iat, err := claims.GetIssuedAt()
+			if err != nil || iat == nil || !iat.Time.After(revokedUntil) {
+				err := fmt.Errorf("tokens of user %s were revoked until %v", user.Username, revokedUntil)
+				app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+				return
+			}
+		}
+
 		ctx = context.WithValue(ctx, contextKeyLoggedUser, user)
+		ctx = context.WithValue(ctx, contextKeyTokenClaims, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// Authenticates a request made with a personal access token instead of a JWT. The token is stored in the context,
+// so its scopes can be checked.
+func (app *Application) authPersonalAccessToken(w http.ResponseWriter, r *http.Request, next http.Handler, tokenStr string) {
+	ctx := r.Context()
+
+	token, err := app.Storage.PersonalAccessTokens.GetByToken(ctx, tokenStr)
+	if err != nil {
+		switch err {
+		case storage.ErrNoToken:
+			err = errors.New("personal access token not found or revoked")
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		default:
+			err = fmt.Errorf("error fetching personal access token: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	if token.Expired() {
+		err := fmt.Errorf("personal access token %s expired", token.ID)
+		app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	user, err := app.Storage.Users.GetByID(ctx, token.UserID)
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			err = fmt.Errorf("owner of personal access token %s not found", token.ID)
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		default:
+			err = fmt.Errorf("error retrieving user from database: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	// The admin scope only lasts while the role keeps the permission to grant it
+	if slices.Contains(token.Scopes, models.ScopeAdmin) && !app.hasPermission(user, models.PermissionTokensAdmin) {
+		token.Scopes = slices.DeleteFunc(slices.Clone(token.Scopes), func(scope string) bool {
+			return scope == models.ScopeAdmin
+		})
+	}
+
+	// NOTE(maolivera): Updated at most once a minute, to avoid a write on every request
+	if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > time.Minute {
+		if err := app.Storage.PersonalAccessTokens.Touch(ctx, token.ID); err != nil {
+			app.Logger.Warnw("could not update last use of personal access token", "id", token.ID, "error", err)
+		}
+	}
+
+	ctx = context.WithValue(ctx, contextKeyLoggedUser, user)
+	ctx = context.WithValue(ctx, contextKeyAccessToken, token)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// Rejects requests made with a personal access token. Used on routes which manage credentials.
+func (app *Application) middlewareSessionOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := getPersonalAccessToken(r); token != nil {
+			err := fmt.Errorf("personal access token %s used on a session only route", token.ID)
+			app.respondWithError(w, r, http.StatusForbidden, err, "personal access tokens are not allowed on this route")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Rejects requests made with a personal access token which was not granted `scope`
+func (app *Application) middlewareRequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasScope(r, scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+			err := fmt.Errorf("personal access token lacks scope %s", scope)
+			app.respondWithError(w, r, http.StatusForbidden, err, fmt.Sprintf("token lacks the %s scope", scope))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Rejects users whose role requires two-factor authentication until they enable it. It must be used after
+// middlewareAuthToken, and not on the routes to enroll.
+func (app *Application) middlewareTwoFactorEnforced(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getLoggedUser(r)
+
+		if !user.TwoFactorEnabled && app.twoFactorRequired(user) {
+			err := fmt.Errorf("user %s must enable two-factor, required for role %s", user.Username, user.Role.Name)
+			app.respondWithError(w, r, http.StatusForbidden, err, "two-factor authentication is required for your role, enable it on /v1/me/2fa")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Returns the claim `key` as a string
+func getStringClaim(claims jwt.MapClaims, key string) (string, error) {
+	raw, ok := claims[key]
+	if !ok {
+		return "", fmt.Errorf("%s not found in token claims", key)
+	}
+
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("%s in token claims is not a valid string", key)
+	}
+
+	return value, nil
+}
+
 func (app *Application) middlewareBasicAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Read auth header
@@ -167,7 +386,22 @@ func (app *Application) middlewarePostContext(next http.Handler) http.Handler {
 			}
 			return
 		}
-		comments, err := app.Storage.Comments.GetByPostID(ctx, post.ID)
+
+		// Posts of private users are only visible to their followers, and posts of users who blocked or were
+		// blocked are not visible at all. Moderators can see every post.
+		user := getLoggedUser(r)
+		canView, err := app.Storage.Followers.CanViewPosts(ctx, post.UserID, user.ID)
+		if err != nil {
+			err = fmt.Errorf("error checking if user %s can view post %v: %v", user.Username, post.ID, err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			return
+		}
+		if !canView && !app.hasPermission(user, models.PermissionPostsUpdateAny) && !app.hasPermission(user, models.PermissionPostsDeleteAny) {
+			err := fmt.Errorf("user %s can not view post %v", user.Username, post.ID)
+			app.respondWithError(w, r, http.StatusNotFound, err, "post not found")
+			return
+		}
+		comments, err := app.Storage.Comments.GetByPostID(ctx, post.ID, user.ID)
 		if err != nil {
 			switch err {
 			case storage.ErrNoRows:
@@ -184,10 +418,9 @@ func (app *Application) middlewarePostContext(next http.Handler) http.Handler {
 	})
 }
 
-// Is `userAllowed` is true, it will allow the user to perform the action "on itself", if not, it will only be allowed if role matches
-func (app *Application) middlewarePostPermissions(requiredRole models.RoleType, userAllowed bool, next http.HandlerFunc) http.HandlerFunc {
+// Is `userAllowed` is true, it will allow the user to perform the action "on itself", if not, it will only be allowed if its role has `permission`
+func (app *Application) middlewarePostPermissions(permission string, userAllowed bool, next http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
 		user := getLoggedUser(r)
 		posts := getPost(r)
 
@@ -197,17 +430,58 @@ func (app *Application) middlewarePostPermissions(requiredRole models.RoleType,
 			return
 		}
 
-		// If not, check if role level is enough
-		role, err := app.Storage.Roles.GetByName(ctx, string(requiredRole))
-		if err != nil {
-			err = fmt.Errorf("error during role fetching: %v", err)
-			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		// If not, the token must be allowed to act as admin and the role must have the permission
+		if !hasScope(r, models.ScopeAdmin) {
+			err := fmt.Errorf("personal access token lacks scope %s", models.ScopeAdmin)
+			app.respondWithError(w, r, http.StatusForbidden, err, "forbidden")
+			return
+		}
+
+		if !app.hasPermission(user, permission) {
+			err := fmt.Errorf("role %s of user %s lacks permission %s", user.Role.Name, user.Username, permission)
+			app.respondWithError(w, r, http.StatusForbidden, err, "forbidden")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Honors the If-Match header of requests changing a post, so they fail instead of overwriting an edit they did not
+// see. Requests without it act on the version just loaded.
+func (app *Application) middlewarePostIfMatch(next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post := getPost(r)
+
+		if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !etagMatchesVersion(ifMatch, post.Version) {
+			err := fmt.Errorf("version %d of post %v does not match If-Match %s", post.Version, post.ID, ifMatch)
+			app.respondWithError(w, r, http.StatusPreconditionFailed, err, "the post was modified")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Lets the user of the route act on itself. Anyone else needs the given permission, and a token allowed to act as admin.
+func (app *Application) middlewareUserPermissions(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := getLoggedUser(r)
+		routeUser := getRouteUser(r)
+
+		if user.ID == routeUser.ID {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !hasScope(r, models.ScopeAdmin) {
+			err := fmt.Errorf("personal access token lacks scope %s", models.ScopeAdmin)
+			app.respondWithError(w, r, http.StatusForbidden, err, "forbidden")
 			return
 		}
 
-		if user.Role.Level < role.Level {
-			// TODO(maolivera): Maybe add a field to the Application struct to keep the roles in memory?
-			err := fmt.Errorf("role is not enough. Required '%s %d' vs '%s %d'", role.Name, role.Level, string(user.Role.Name), user.Role.Level)
+		if !app.hasPermission(user, permission) {
+			err := fmt.Errorf("role %s of user %s lacks permission %s", user.Role.Name, user.Username, permission)
 			app.respondWithError(w, r, http.StatusForbidden, err, "forbidden")
 			return
 		}
@@ -224,6 +498,28 @@ func getLoggedUser(r *http.Request) *models.User {
 	return r.Context().Value(contextKeyLoggedUser).(*models.User)
 }
 
+// Nil if the request was made with a personal access token
+func getTokenClaims(r *http.Request) jwt.MapClaims {
+	claims, _ := r.Context().Value(contextKeyTokenClaims).(jwt.MapClaims)
+	return claims
+}
+
+// Nil unless the request was made with a personal access token
+func getPersonalAccessToken(r *http.Request) *models.PersonalAccessToken {
+	token, _ := r.Context().Value(contextKeyAccessToken).(*models.PersonalAccessToken)
+	return token
+}
+
+// Reports if the request may use `scope`. Only personal access tokens are limited by scopes.
+func hasScope(r *http.Request, scope string) bool {
+	token := getPersonalAccessToken(r)
+	if token == nil {
+		return true
+	}
+
+	return slices.Contains(token.Scopes, scope)
+}
+
 func getPost(r *http.Request) *models.Post {
 	return r.Context().Value(contextKeyPost).(*models.Post)
 }
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type CreateMutePayload struct {
+	// One of user, tag or keyword
+	Kind string `json:"kind"`
+	// Username, tag or keyword to mute
+	Value string `json:"value"`
+	// Hours until the mute expires. If zero, it never expires.
+	ExpiresInHours int `json:"expires_in_hours"`
+}
+
+type UpdateMutePayload struct {
+	// Hours from now until the mute expires. If zero, it never expires.
+	ExpiresInHours int `json:"expires_in_hours"`
+}
+
+// List Mutes godoc
+//
+//	@Summary		Lists mutes
+//	@Description	Lists the users, tags and keywords muted by the logged user which did not expire, newest first
+//	@Tags			users
+//	@Produce		json
+//	@Success		200	{array}		models.Mute
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/mutes [get]
+func (app *Application) handlerListMutes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	mutes, err := app.Storage.Mutes.GetByUser(ctx, user.ID)
+	if err != nil {
+		err = fmt.Errorf("error fetching mutes of user %s: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, mutes)
+}
+
+// Create Mute godoc
+//
+//	@Summary		Mutes a user, tag or keyword
+//	@Description	Posts of muted users, with muted tags or containing muted keywords are hidden from the feed and search of the logged user. The other side is not notified. Muting something already muted only changes when it expires.
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body		CreateMutePayload	true	"Kind, value and expiration"
+//	@Success		201		{object}	models.Mute
+//	@Failure		400		{object}	error	"Some parameter was either not provided or invalid."
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		404		{object}	error	"User to mute not found"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/mutes [post]
+func (app *Application) handlerCreateMute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	in := CreateMutePayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when creating a mute: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	in.Value = strings.TrimSpace(in.Value)
+	{ // Validate input
+		// Kind
+		kind := models.MuteKind(in.Kind)
+		if kind != models.MuteKindUser && kind != models.MuteKindTag && kind != models.MuteKindKeyword {
+			err := errors.New("kind must be user, tag or keyword")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		// Value
+		if in.Value == "" {
+			err := errors.New("value is required")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if len(in.Value) > 100 {
+			err := errors.New("value is too long")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		// Expiration
+		if err := validateMuteExpiration(in.ExpiresInHours); err != nil {
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+	}
+
+	currentTime := time.Now().UTC()
+	mute := &models.Mute{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Kind:      models.MuteKind(in.Kind),
+		CreatedAt: currentTime,
+		ExpiresAt: muteExpiration(currentTime, in.ExpiresInHours),
+	}
+
+	if mute.Kind == models.MuteKindUser {
+		mutedUser, err := app.getUser(r, in.Value)
+		if err != nil {
+			switch err {
+			case storage.ErrNoRows:
+				app.respondWithError(w, r, http.StatusNotFound, err, "user not found")
+			default:
+				err = fmt.Errorf("error fetching user: %v", err)
+				app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			}
+			return
+		}
+		if mutedUser.ID == user.ID {
+			err := errors.New("users can not mute themselves")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		mute.MutedUser = &models.ReducedUser{ID: mutedUser.ID, Username: mutedUser.Username}
+	} else {
+		mute.Value = strings.ToLower(in.Value)
+	}
+
+	if err := app.Storage.Mutes.Create(ctx, mute); err != nil {
+		err = fmt.Errorf("error creating mute: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusCreated, mute)
+}
+
+// Update Mute godoc
+//
+//	@Summary		Changes when a mute expires
+//	@Tags			users
+//	@Accept			json
+//	@Param			muteID	path	string				true	"Mute ID"
+//	@Param			Payload	body	UpdateMutePayload	true	"Expiration"
+//	@Success		204		"Mute was updated"
+//	@Failure		400		{object}	error	"Some parameter was either not provided or invalid."
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		404		{object}	error	"Mute not found"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/mutes/{muteID} [patch]
+func (app *Application) handlerUpdateMute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	id, err := uuid.Parse(r.PathValue("muteID"))
+	if err != nil {
+		err := fmt.Errorf("invalid mute_id: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid mute_id")
+		return
+	}
+
+	in := UpdateMutePayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when updating a mute: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if err := validateMuteExpiration(in.ExpiresInHours); err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	expiresAt := muteExpiration(time.Now().UTC(), in.ExpiresInHours)
+	if err := app.Storage.Mutes.UpdateExpiration(ctx, user.ID, id, expiresAt); err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "mute not found")
+		default:
+			err = fmt.Errorf("error updating mute: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// Delete Mute godoc
+//
+//	@Summary		Deletes a mute
+//	@Tags			users
+//	@Param			muteID	path	string	true	"Mute ID"
+//	@Success		204		"Mute was deleted"
+//	@Failure		400		{object}	error	"Invalid mute ID"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		404		{object}	error	"Mute not found"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/mutes/{muteID} [delete]
+func (app *Application) handlerDeleteMute(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	id, err := uuid.Parse(r.PathValue("muteID"))
+	if err != nil {
+		err := fmt.Errorf("invalid mute_id: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid mute_id")
+		return
+	}
+
+	if err := app.Storage.Mutes.Delete(ctx, user.ID, id); err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "mute not found")
+		default:
+			err = fmt.Errorf("error deleting mute: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+func validateMuteExpiration(hours int) error {
+	if hours < 0 || hours > 24*365 {
+		return errors.New("expires_in_hours must be between 0 and 8760")
+	}
+	return nil
+}
+
+// Nil if the mute never expires
+func muteExpiration(from time.Time, hours int) *time.Time {
+	if hours == 0 {
+		return nil
+	}
+	expiresAt := from.Add(time.Duration(hours) * time.Hour)
+	return &expiresAt
+}
@@ -0,0 +1,276 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/oidc"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+const oidcStateExpirationTime = 10 * time.Minute
+
+var errUnverifiedEmail = errors.New("identity provider did not verify the email")
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9_.-]+`)
+
+// OIDC Login godoc
+//
+//	@Summary		Starts a login with an external identity provider
+//	@Description	Redirects to the identity provider, which will redirect back to the callback once the user signs in. It uses the authorization code flow with PKCE.
+//	@Tags			authorization
+//	@Param			provider	path	string	true	"Name of the provider"
+//	@Success		302			"Redirect to the identity provider"
+//	@Failure		404			{object}	error	"Unknown provider"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Router			/oidc/{provider}/login [get]
+func (app *Application) handlerOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	name := r.PathValue("provider")
+	provider, ok := app.OIDCProviders[name]
+	if !ok {
+		err := fmt.Errorf("unknown identity provider %s", name)
+		app.respondWithError(w, r, http.StatusNotFound, err, "unknown identity provider")
+		return
+	}
+
+	state := make([]byte, 32)
+	if _, err := rand.Read(state); err != nil {
+		err = fmt.Errorf("error creating state: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if err := app.Storage.Identities.CreateState(ctx, &models.OIDCState{
+		State:        state,
+		Provider:     name,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, oidcStateExpirationTime); err != nil {
+		err = fmt.Errorf("error storing login state: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	encodedState := base64.RawURLEncoding.EncodeToString(state)
+	http.Redirect(w, r, provider.AuthCodeURL(encodedState, nonce, challenge), http.StatusFound)
+}
+
+// OIDC Callback godoc
+//
+//	@Summary		Completes a login with an external identity provider
+//	@Description	Exchanges the authorization code for the identity of the user. Unknown identities are linked to the account with the same verified email, or a new account is created. If the account enabled two-factor, a challenge is returned instead of the tokens.
+//	@Tags			authorization
+//	@Produce		json
+//	@Param			provider	path		string						true	"Name of the provider"
+//	@Param			code		query		string						true	"Authorization code"
+//	@Param			state		query		string						true	"State"
+//	@Success		201			{object}	TokenResponse				"Token"
+//	@Success		200			{object}	TwoFactorChallengeResponse	"Two-factor challenge"
+//	@Failure		400			{object}	error						"Invalid or expired state"
+//	@Failure		401			{object}	error						"The provider denied the login or the identity is invalid"
+//	@Failure		404			{object}	error						"Unknown provider"
+//	@Failure		409			{object}	error						"Email is taken by an account which is not active"
+//	@Failure		502			{object}	error						"The provider could not be reached"
+//	@Failure		500			{object}	error						"Something went wrong on the server"
+//	@Router			/oidc/{provider}/callback [get]
+func (app *Application) handlerOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	query := r.URL.Query()
+
+	name := r.PathValue("provider")
+	provider, ok := app.OIDCProviders[name]
+	if !ok {
+		err := fmt.Errorf("unknown identity provider %s", name)
+		app.respondWithError(w, r, http.StatusNotFound, err, "unknown identity provider")
+		return
+	}
+
+	if errCode := query.Get("error"); errCode != "" {
+		err := fmt.Errorf("identity provider %s denied the login: %s %s", name, errCode, query.Get("error_description"))
+		app.respondWithError(w, r, http.StatusUnauthorized, err, "the identity provider denied the login")
+		return
+	}
+
+	// 1. Validate state
+	state, err := base64.RawURLEncoding.DecodeString(query.Get("state"))
+	if err != nil || len(state) == 0 || query.Get("code") == "" {
+		err := errors.New("state or code is missing or malformed")
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid state or code")
+		return
+	}
+	pending, err := app.Storage.Identities.ConsumeState(ctx, state)
+	if err != nil {
+		switch err {
+		case storage.ErrNoToken:
+			app.respondWithError(w, r, http.StatusBadRequest, err, "invalid or expired state")
+		default:
+			err = fmt.Errorf("error consuming login state: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	if pending.Provider != name {
+		err := fmt.Errorf("state was created for provider %s, not %s", pending.Provider, name)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid or expired state")
+		return
+	}
+
+	// 2. Exchange code
+	rawIDToken, err := provider.Exchange(ctx, query.Get("code"), pending.CodeVerifier)
+	if err != nil {
+		err = fmt.Errorf("error exchanging code with %s: %v", name, err)
+		app.respondWithError(w, r, http.StatusBadGateway, err, "could not complete the login with the identity provider")
+		return
+	}
+
+	// 3. Verify identity
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, pending.Nonce)
+	if err != nil {
+		err = fmt.Errorf("error verifying ID token of %s: %v", name, err)
+		app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	// 4. Find, link or provision user
+	user, err := app.oidcUser(ctx, name, claims)
+	if err != nil {
+		switch err {
+		case errUnverifiedEmail:
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "the identity provider did not verify your email")
+		case storage.ErrEmailUnavailable:
+			app.respondWithError(w, r, http.StatusConflict, err, "email is not available")
+		case storage.ErrNoRows:
+			err = fmt.Errorf("user linked to %s identity %s not found", name, claims.Subject)
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		default:
+			err = fmt.Errorf("error finding user of %s identity: %v", name, err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	app.completeLogin(ctx, w, r, user)
+}
+
+// Finds the user linked to the external identity. Unknown identities are linked to the active user with the
+// same email, or a new user is provisioned. Both require the provider to have verified the email.
+func (app *Application) oidcUser(ctx context.Context, provider string, claims *oidc.Claims) (*models.User, error) {
+	userID, err := app.Storage.Identities.GetUserID(ctx, provider, claims.Subject)
+	switch err {
+	case nil:
+		if err := app.Storage.Identities.Touch(ctx, provider, claims.Subject); err != nil {
+			app.Logger.Warnw("could not update last login of identity", "provider", provider, "error", err)
+		}
+		return app.Storage.Users.GetByID(ctx, userID)
+	case storage.ErrNoRows:
+	default:
+		return nil, err
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errUnverifiedEmail
+	}
+
+	identity := &models.UserIdentity{
+		Provider:  provider,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	// 1. Link to an existing account
+	userID, err = app.Storage.Identities.Link(ctx, identity)
+	switch err {
+	case nil:
+		app.Logger.Infow("identity linked", "provider", provider, "id", userID)
+		return app.Storage.Users.GetByID(ctx, userID)
+	case storage.ErrNoUser:
+	default:
+		return nil, err
+	}
+
+	// 2. Provision a new account
+	// NOTE(maolivera): The password is random, the user can set one with the forgot password flow
+	password, err := oidc.RandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	base := usernameFromClaims(claims)
+	for attempt := 0; attempt < 5; attempt++ {
+		username := base
+		if attempt > 0 {
+			username = fmt.Sprintf("%s%04d", base, mathrand.IntN(10_000))
+		}
+
+		currentTime := time.Now().UTC()
+		user := &models.UserWithPassword{
+			User: models.User{
+				ID:        uuid.New(),
+				CreatedAt: currentTime,
+				UpdatedAt: currentTime,
+				Email:     claims.Email,
+				Username:  username,
+			},
+			Password: password,
+		}
+
+		err := app.Storage.Identities.CreateWithUser(ctx, user, identity)
+		switch err {
+		case nil:
+			app.Logger.Infow("user provisioned from identity", "provider", provider, "username", username)
+			return app.Storage.Users.GetByID(ctx, user.User.ID)
+		case storage.ErrUsernameUnavailable:
+			continue
+		default:
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("could not find an available username for %s", base)
+}
+
+// Username derived from the preferred username, or the email, of the identity
+func usernameFromClaims(claims *oidc.Claims) string {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = claims.Email
+	}
+	base, _, _ = strings.Cut(strings.ToLower(base), "@")
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+
+	if base == "" {
+		base = "user"
+	}
+	if len(base) > 90 {
+		base = base[:90]
+	}
+
+	return base
+}
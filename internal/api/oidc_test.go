@@ -0,0 +1,400 @@
+package api
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/oidc"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+const (
+	testProvider = "mock"
+	testClientID = "gophis"
+)
+
+// Identity provider which signs in whoever it is told to. Codes are single-use and require the PKCE verifier.
+type mockIdP struct {
+	*httptest.Server
+	key ed25519.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, codes: make(map[string]mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidc.Metadata{
+			Issuer:                idp.URL,
+			AuthorizationEndpoint: idp.URL + "/authorize",
+			TokenEndpoint:         idp.URL + "/token",
+			JWKSURI:               idp.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("GET /keys", func(w http.ResponseWriter, r *http.Request) {
+		public := key.Public().(ed25519.PublicKey)
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"kid": "test",
+				"use": "sig",
+				"x":   base64.RawURLEncoding.EncodeToString(public),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", idp.handleToken)
+
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// Signs in the user on the authorization URL, returning the code sent back to the callback
+func (idp *mockIdP) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 code challenge, got %q", query.Get("code_challenge_method"))
+	}
+
+	if _, ok := claims["nonce"]; !ok {
+		claims["nonce"] = query.Get("nonce")
+	}
+	currentTime := time.Now()
+	claims["iss"] = idp.URL
+	claims["aud"] = query.Get("client_id")
+	claims["iat"] = currentTime.Unix()
+	claims["exp"] = currentTime.Add(time.Minute).Unix()
+
+	code := uuid.NewString()
+	idp.mu.Lock()
+	idp.codes[code] = mockAuthorization{challenge: query.Get("code_challenge"), claims: claims}
+	idp.mu.Unlock()
+
+	return code
+}
+
+func (idp *mockIdP) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	authorization, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	if !ok || oidc.S256Challenge(r.PostForm.Get("code_verifier")) != authorization.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, authorization.claims)
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+// Application configured with the mock provider
+func newOIDCTestApplication(t *testing.T, idp *mockIdP) (*Application, *fakeUserRepository, *fakeIdentityRepository) {
+	t.Helper()
+
+	app, _ := newTestApplication(t)
+	provider, err := oidc.Discover(context.Background(), oidc.Config{
+		Name:        testProvider,
+		Issuer:      idp.URL,
+		ClientID:    testClientID,
+		RedirectURL: testApiUrl + "/v1/oidc/" + testProvider + "/callback",
+	})
+	if err != nil {
+		t.Fatalf("could not discover mock provider: %v", err)
+	}
+	app.OIDCProviders = map[string]*oidc.Provider{testProvider: provider}
+
+	users := newFakeUserRepository()
+	identities := &fakeIdentityRepository{
+		users:      users,
+		states:     make(map[string]*models.OIDCState),
+		identities: make(map[string]uuid.UUID),
+	}
+	app.Storage.Users = users
+	app.Storage.Identities = identities
+	app.Storage.TwoFactor = fakeTwoFactorRepository{}
+	app.Storage.Sessions = &fakeSessionRepository{}
+
+	return app, users, identities
+}
+
+// Starts a login, returning the URL of the provider it redirects to
+func startOIDCLogin(t *testing.T, app *Application) string {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/oidc/"+testProvider+"/login", nil)
+	r.SetPathValue("provider", testProvider)
+	app.handlerOIDCLogin(w, r)
+
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusFound, w.Code, w.Body)
+	}
+	return w.Header().Get("Location")
+}
+
+func oidcCallback(app *Application, authURL, code string) *httptest.ResponseRecorder {
+	parsed, _ := url.Parse(authURL)
+	query := url.Values{}
+	query.Set("code", code)
+	query.Set("state", parsed.Query().Get("state"))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/v1/oidc/"+testProvider+"/callback?"+query.Encode(), nil)
+	r.SetPathValue("provider", testProvider)
+	app.handlerOIDCCallback(w, r)
+	return w
+}
+
+func TestOIDCCallbackProvisionsUser(t *testing.T) {
+	idp := newMockIdP(t)
+	app, users, identities := newOIDCTestApplication(t, idp)
+
+	authURL := startOIDCLogin(t, app)
+	code := idp.authorize(t, authURL, jwt.MapClaims{
+		"sub":                "subject-1",
+		"email":              "gopher@example.com",
+		"email_verified":     true,
+		"preferred_username": "Gopher",
+	})
+	w := oidcCallback(app, authURL, code)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	out := TokenResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&out); err != nil || out.Token == "" || out.RefreshToken == "" {
+		t.Fatalf("expected tokens, got %s (%v)", w.Body, err)
+	}
+
+	userID, ok := identities.identities[testProvider+"/subject-1"]
+	if !ok {
+		t.Fatal("expected identity to be linked")
+	}
+	user, err := users.GetByID(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("expected user to be provisioned: %v", err)
+	}
+	if user.Username != "gopher" || user.Email != "gopher@example.com" {
+		t.Errorf("expected user gopher <gopher@example.com>, got %s <%s>", user.Username, user.Email)
+	}
+}
+
+func TestOIDCCallbackLinksExistingUser(t *testing.T) {
+	idp := newMockIdP(t)
+	app, users, identities := newOIDCTestApplication(t, idp)
+	existing := testUser("gopher", "gopher@example.com")
+	users.add(existing)
+
+	authURL := startOIDCLogin(t, app)
+	code := idp.authorize(t, authURL, jwt.MapClaims{
+		"sub":            "subject-1",
+		"email":          "gopher@example.com",
+		"email_verified": true,
+	})
+	w := oidcCallback(app, authURL, code)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	if userID := identities.identities[testProvider+"/subject-1"]; userID != existing.ID {
+		t.Errorf("expected identity linked to %v, got %v", existing.ID, userID)
+	}
+	if len(users.users) != 1 {
+		t.Errorf("expected no user to be provisioned, got %d users", len(users.users))
+	}
+}
+
+func TestOIDCCallbackRejectsUnverifiedEmail(t *testing.T) {
+	idp := newMockIdP(t)
+	app, users, _ := newOIDCTestApplication(t, idp)
+	users.add(testUser("gopher", "gopher@example.com"))
+
+	authURL := startOIDCLogin(t, app)
+	code := idp.authorize(t, authURL, jwt.MapClaims{
+		"sub":            "subject-1",
+		"email":          "gopher@example.com",
+		"email_verified": false,
+	})
+	w := oidcCallback(app, authURL, code)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnauthorized, w.Code, w.Body)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	idp := newMockIdP(t)
+	app, _, _ := newOIDCTestApplication(t, idp)
+
+	authURL := startOIDCLogin(t, app)
+	code := idp.authorize(t, authURL, jwt.MapClaims{
+		"sub":            "subject-1",
+		"email":          "gopher@example.com",
+		"email_verified": true,
+		"nonce":          "replayed",
+	})
+	w := oidcCallback(app, authURL, code)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusUnauthorized, w.Code, w.Body)
+	}
+}
+
+func TestOIDCCallbackRejectsReusedState(t *testing.T) {
+	idp := newMockIdP(t)
+	app, _, _ := newOIDCTestApplication(t, idp)
+	claims := jwt.MapClaims{
+		"sub":            "subject-1",
+		"email":          "gopher@example.com",
+		"email_verified": true,
+	}
+
+	authURL := startOIDCLogin(t, app)
+	if w := oidcCallback(app, authURL, idp.authorize(t, authURL, claims)); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	w := oidcCallback(app, authURL, idp.authorize(t, authURL, claims))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body)
+	}
+}
+
+// Identities and pending logins kept in memory, linked to the users of the fake user repository
+type fakeIdentityRepository struct {
+	storage.IdentityRepository
+	users *fakeUserRepository
+
+	mu         sync.Mutex
+	states     map[string]*models.OIDCState
+	identities map[string]uuid.UUID // By provider and subject
+}
+
+func (r *fakeIdentityRepository) GetUserID(ctx context.Context, provider, subject string) (uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, ok := r.identities[provider+"/"+subject]
+	if !ok {
+		return uuid.Nil, storage.ErrNoRows
+	}
+	return userID, nil
+}
+
+func (r *fakeIdentityRepository) Touch(ctx context.Context, provider, subject string) error {
+	return nil
+}
+
+func (r *fakeIdentityRepository) Link(ctx context.Context, identity *models.UserIdentity) (uuid.UUID, error) {
+	r.users.mu.Lock()
+	user := r.users.byEmail(identity.Email)
+	r.users.mu.Unlock()
+	if user == nil {
+		return uuid.Nil, storage.ErrNoUser
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identities[identity.Provider+"/"+identity.Subject] = user.ID
+	return user.ID, nil
+}
+
+func (r *fakeIdentityRepository) CreateWithUser(ctx context.Context, user *models.UserWithPassword, identity *models.UserIdentity) error {
+	created := user.User
+	r.users.add(&created)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.identities[identity.Provider+"/"+identity.Subject] = created.ID
+	return nil
+}
+
+func (r *fakeIdentityRepository) CreateState(ctx context.Context, state *models.OIDCState, exp time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.states[string(state.State)] = state
+	return nil
+}
+
+func (r *fakeIdentityRepository) ConsumeState(ctx context.Context, state []byte) (*models.OIDCState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pending, ok := r.states[string(state)]
+	if !ok {
+		return nil, storage.ErrNoToken
+	}
+	delete(r.states, string(state))
+	return pending, nil
+}
+
+// No user enabled two-factor
+type fakeTwoFactorRepository struct {
+	storage.TwoFactorRepository
+}
+
+func (fakeTwoFactorRepository) Get(ctx context.Context, userID uuid.UUID) (*models.TOTP, error) {
+	return nil, storage.ErrNoRows
+}
+
+type fakeSessionRepository struct {
+	storage.SessionRepository
+}
+
+func (*fakeSessionRepository) Create(ctx context.Context, session *models.Session, token *models.RefreshToken) error {
+	return nil
+}
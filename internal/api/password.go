@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/maxolivera/gophis-social-network/internal/mailer"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+)
+
+type ForgotPasswordPayload struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordPayload struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// Forgot Password godoc
+//
+//	@Summary		Requests a password reset
+//	@Description	Creates a single-use, time-limited token to reset the password of the account with the given email. The response is the same whether the account exists or not.
+//	@Tags			authorization
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body	ForgotPasswordPayload	true	"Account email"
+//	@Success		202		"If the account exists, a reset token was created"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Failure		400		{object}	error	"Some parameter was either not provided or invalid."
+//	@Router			/password/forgot [post]
+func (app *Application) handlerForgotPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	in := ForgotPasswordPayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when requesting a password reset: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	{ // Validate input
+		if in.Email == "" {
+			err := errors.New("email is required")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if len(in.Email) > 255 {
+			err := errors.New("email is too long")
+			app.respondWithError(w, r, http.StatusBadRequest, err, "email is too long")
+			return
+		}
+		if _, err := mail.ParseAddress(in.Email); err != nil {
+			err := fmt.Errorf("email is invalid: %v", err)
+			app.respondWithError(w, r, http.StatusBadRequest, err, "email is invalid")
+			return
+		}
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		err = fmt.Errorf("error creating token: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	user, err := app.Storage.Users.CreatePasswordReset(ctx, in.Email, token, app.Config.PasswordResetExpirationTime)
+	if err != nil {
+		switch err {
+		case storage.ErrNoUser:
+			// NOTE(maolivera): Same response as success, otherwise emails could be enumerated
+			app.Logger.Infow("password reset requested for unknown email")
+			app.respondWithJSON(w, r, http.StatusAccepted, nil)
+		default:
+			err = fmt.Errorf("error creating password reset: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	// NOTE(maolivera): The new password is sent along the token, so only a frontend can offer a link
+	encodedToken := base64.URLEncoding.EncodeToString(token)
+	app.sendEmail(user.Email, mailer.TemplatePasswordReset, mailer.TokenData{
+		Username:  user.Username,
+		URL:       app.frontendURL("/password/reset?token=%s", url.QueryEscape(encodedToken)),
+		Token:     encodedToken,
+		ExpiresIn: app.Config.PasswordResetExpirationTime.String(),
+	})
+
+	app.respondWithJSON(w, r, http.StatusAccepted, nil)
+}
+
+// Reset Password godoc
+//
+//	@Summary		Resets a password
+//	@Description	Changes the password of the account which requested the reset token. The token can only be used once, every session of the account is closed and its personal access tokens are revoked.
+//	@Tags			authorization
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body	ResetPasswordPayload	true	"Reset token and new password"
+//	@Success		204		"Password was changed"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Failure		400		{object}	error	"Some parameter was either not provided or invalid."
+//	@Failure		404		{object}	error	"Token not found or expired"
+//	@Router			/password/reset [post]
+func (app *Application) handlerResetPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	in := ResetPasswordPayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when resetting a password: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	{ // Validate input
+		if in.Token == "" || in.Password == "" {
+			err := errors.New("token and password are required")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		// Password
+		if len(in.Password) > 72 {
+			err := errors.New("password is too long")
+			app.respondWithError(w, r, http.StatusBadRequest, err, "password is too long")
+			return
+		}
+		if len(in.Password) < 3 {
+			err := errors.New("password is too short")
+			app.respondWithError(w, r, http.StatusBadRequest, err, "password is too short")
+			return
+		}
+	}
+
+	token, err := base64.URLEncoding.DecodeString(in.Token)
+	if err != nil {
+		err := fmt.Errorf("error decoding token string: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid token")
+		return
+	}
+
+	user, err := app.Storage.Users.ResetPassword(ctx, token, in.Password)
+	if err != nil {
+		switch err {
+		case storage.ErrNoToken:
+			err = errors.New("password reset token not found or expired")
+			app.respondWithError(w, r, http.StatusNotFound, err, "token not found or expired")
+		case storage.ErrNoUser:
+			app.respondWithError(w, r, http.StatusNotFound, err, "user not found")
+		default:
+			err = fmt.Errorf("error during password reset: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	// Close every session
+	if err := app.revokeUserTokens(ctx, user); err != nil {
+		err = fmt.Errorf("password was reset but tokens of user %s could not be revoked: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	app.Logger.Infow("password reset", "username", user.Username, "id", fmt.Sprintf("%x", user.ID))
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestForgotPasswordSendsResetEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		frontendUrl string
+		linkPrefix  string
+	}{
+		// The new password is sent along the token, so only a frontend can offer a link
+		{"without frontend", "", ""},
+		{"with frontend", "http://app.test", "http://app.test/password/reset?token="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mail := newTestApplication(t)
+			app.Config.FrontendUrl = tt.frontendUrl
+			users := newFakeUserRepository()
+			app.Storage.Users = users
+			users.add(testUser("gopher", "gopher@example.com"))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/v1/password/forgot", strings.NewReader(`{"email": "gopher@example.com"}`))
+			app.handlerForgotPassword(w, r)
+
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body)
+			}
+
+			emails := sentEmails(app, mail)
+			if len(emails) != 1 {
+				t.Fatalf("expected 1 email, got %d", len(emails))
+			}
+			email := emails[0]
+			if email.To != "gopher@example.com" {
+				t.Errorf("expected email to gopher@example.com, got %s", email.To)
+			}
+
+			token := base64.URLEncoding.EncodeToString(users.passwordResets["gopher@example.com"])
+			if !strings.Contains(email.Text, token) {
+				t.Errorf("expected token %s in email, got:\n%s", token, email.Text)
+			}
+			if tt.linkPrefix == "" {
+				if strings.Contains(email.Text, "http") {
+					t.Errorf("expected no link in email, got:\n%s", email.Text)
+				}
+				return
+			}
+			link := tt.linkPrefix + url.QueryEscape(token)
+			if !strings.Contains(email.Text, link) {
+				t.Errorf("expected link %s in email, got:\n%s", link, email.Text)
+			}
+		})
+	}
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	app, mail := newTestApplication(t)
+	app.Storage.Users = newFakeUserRepository()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/v1/password/forgot", strings.NewReader(`{"email": "nobody@example.com"}`))
+	app.handlerForgotPassword(w, r)
+
+	// Same response as for known emails, otherwise they could be enumerated
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body)
+	}
+	if emails := sentEmails(app, mail); len(emails) != 0 {
+		t.Errorf("expected no email, got %d", len(emails))
+	}
+}
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+// Permissions of each role, kept in memory so they are not queried on every request
+type permissionCache struct {
+	mu    sync.RWMutex
+	roles map[models.RoleType]map[string]bool
+}
+
+type PermissionsResponse struct {
+	Permissions []*models.Permission `json:"permissions"`
+	// Permissions granted to each role
+	Roles map[models.RoleType][]string `json:"roles"`
+}
+
+// Time between reloads of the permissions, which is how long other instances take to see a change
+const permissionsReloadInterval = time.Minute
+
+// Loads the permissions of every role. It must be called on startup, and it is called again after every change.
+func (app *Application) LoadPermissions(ctx context.Context) error {
+	byRole, err := app.Storage.Permissions.GetByRole(ctx)
+	if err != nil {
+		return fmt.Errorf("error loading permissions: %v", err)
+	}
+
+	roles := make(map[models.RoleType]map[string]bool, len(byRole))
+	for role, permissions := range byRole {
+		roles[role] = make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			roles[role][permission] = true
+		}
+	}
+
+	app.permissions.mu.Lock()
+	app.permissions.roles = roles
+	app.permissions.mu.Unlock()
+
+	return nil
+}
+
+// Reports if the role of the user was granted the permission
+func (app *Application) hasPermission(user *models.User, permission string) bool {
+	app.permissions.mu.RLock()
+	defer app.permissions.mu.RUnlock()
+
+	return app.permissions.roles[user.Role.Name][permission]
+}
+
+// Reloads the permissions on every interval, so changes made on other instances are seen, until ctx is done
+func (app *Application) reloadPermissionsPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(permissionsReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := app.LoadPermissions(ctx); err != nil {
+			app.Logger.Errorw("could not reload permissions", "error", err.Error())
+		}
+	}
+}
+
+// Names of the permissions granted to a role, sorted
+func (app *Application) rolePermissions(role models.RoleType) []string {
+	app.permissions.mu.RLock()
+	defer app.permissions.mu.RUnlock()
+
+	permissions := make([]string, 0, len(app.permissions.roles[role]))
+	for permission := range app.permissions.roles[role] {
+		permissions = append(permissions, permission)
+	}
+	slices.Sort(permissions)
+
+	return permissions
+}
+
+// Only allows users whose role was granted the permission. Personal access tokens also need the admin scope.
+func (app *Application) requirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := getLoggedUser(r)
+
+			if !hasScope(r, models.ScopeAdmin) {
+				err := fmt.Errorf("personal access token lacks scope %s", models.ScopeAdmin)
+				app.respondWithError(w, r, http.StatusForbidden, err, "forbidden")
+				return
+			}
+
+			if !app.hasPermission(user, permission) {
+				err := fmt.Errorf("role %s of user %s lacks permission %s", user.Role.Name, user.Username, permission)
+				app.respondWithError(w, r, http.StatusForbidden, err, "forbidden")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// List Permissions godoc
+//
+//	@Summary		Lists permissions
+//	@Description	Lists every permission, and the ones granted to each role
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	PermissionsResponse
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		403	{object}	error	"Forbidden"
+//	@Failure		500	{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/permissions [get]
+func (app *Application) handlerListPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	permissions, err := app.Storage.Permissions.GetAll(ctx)
+	if err != nil {
+		err = fmt.Errorf("error fetching permissions: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	byRole, err := app.Storage.Permissions.GetByRole(ctx)
+	if err != nil {
+		err = fmt.Errorf("error fetching permissions of roles: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	out := &PermissionsResponse{
+		Permissions: permissions,
+		Roles:       byRole,
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, out)
+}
+
+// Grant Permission godoc
+//
+//	@Summary		Grants a permission to a role
+//	@Tags			admin
+//	@Param			role		path	string	true	"Role name"
+//	@Param			permission	path	string	true	"Permission name"
+//	@Success		204			"Permission was granted"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"Forbidden"
+//	@Failure		404			{object}	error	"Role or permission not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles/{role}/permissions/{permission} [put]
+func (app *Application) handlerGrantPermission(w http.ResponseWriter, r *http.Request) {
+	app.changeRolePermission(w, r, models.AuditPermissionGranted)
+}
+
+// Revoke Permission godoc
+//
+//	@Summary		Revokes a permission of a role
+//	@Tags			admin
+//	@Param			role		path	string	true	"Role name"
+//	@Param			permission	path	string	true	"Permission name"
+//	@Success		204			"Permission was revoked"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"Forbidden"
+//	@Failure		404			{object}	error	"Role not found or it does not have the permission"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles/{role}/permissions/{permission} [delete]
+func (app *Application) handlerRevokePermission(w http.ResponseWriter, r *http.Request) {
+	app.changeRolePermission(w, r, models.AuditPermissionRevoked)
+}
+
+// Grants or revokes, depending on `action`, the permission of the route to the role of the route
+func (app *Application) changeRolePermission(w http.ResponseWriter, r *http.Request, action models.AuditAction) {
+	ctx := r.Context()
+	actor := getLoggedUser(r)
+	roleName := models.RoleType(r.PathValue("role"))
+	permission := r.PathValue("permission")
+
+	// NOTE(maolivera): Otherwise admins could lock themselves out
+	if roleName == actor.Role.Name {
+		err := fmt.Errorf("user %s tried to change the permissions of its own role", actor.Username)
+		app.respondWithError(w, r, http.StatusForbidden, err, "you can not change the permissions of your own role")
+		return
+	}
+
+	role, err := app.Storage.Roles.GetByName(ctx, string(roleName))
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "role not found")
+		default:
+			err = fmt.Errorf("error during role fetching: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	if role.Level > actor.Role.Level {
+		err := fmt.Errorf("user %s tried to change the permissions of role %s, whose level is higher", actor.Username, role.Name)
+		app.respondWithError(w, r, http.StatusForbidden, err, "forbidden")
+		return
+	}
+
+	entry, err := models.NewAuditLogEntry(actor.ID, action, "role", string(role.Name), map[string]any{
+		"permission": permission,
+	})
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if action == models.AuditPermissionGranted {
+		err = app.Storage.Permissions.Grant(ctx, role.Name, permission, entry)
+	} else {
+		err = app.Storage.Permissions.Revoke(ctx, role.Name, permission, entry)
+	}
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "permission not found")
+		default:
+			err = fmt.Errorf("error changing permission %s of role %s: %v", permission, role.Name, err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	app.Logger.Infow("permissions changed", "role", role.Name, "permission", permission, "action", action, "by", actor.Username)
+
+	if err := app.LoadPermissions(ctx); err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type CreatePersonalAccessTokenPayload struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// Days until the token expires. If zero, it never expires.
+	ExpiresInDays int `json:"expires_in_days"`
+}
+
+type PersonalAccessTokenResponse struct {
+	// Only shown once
+	Token               string                      `json:"token"`
+	PersonalAccessToken *models.PersonalAccessToken `json:"personal_access_token"`
+}
+
+// List Personal Access Tokens godoc
+//
+//	@Summary		Lists personal access tokens
+//	@Description	Lists the non-revoked personal access tokens of the logged user. The tokens themselves are never shown again after creation.
+//	@Tags			authorization
+//	@Produce		json
+//	@Success		200	{array}		models.PersonalAccessToken	"Tokens"
+//	@Failure		401	{object}	error						"Unauthorized"
+//	@Failure		500	{object}	error						"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/tokens [get]
+func (app *Application) handlerListPersonalAccessTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	tokens, err := app.Storage.PersonalAccessTokens.GetByUser(ctx, user.ID)
+	if err != nil {
+		err = fmt.Errorf("error fetching personal access tokens: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, tokens)
+}
+
+// Create Personal Access Token godoc
+//
+//	@Summary		Creates a personal access token
+//	@Description	Creates a named, long-lived token for bots and integrations, limited to the given scopes. It is used as a Bearer token, like the ones from /token.
+//	@Tags			authorization
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body		CreatePersonalAccessTokenPayload	true	"Name, scopes and expiration"
+//	@Success		201		{object}	PersonalAccessTokenResponse			"Token"
+//	@Failure		400		{object}	error								"Some parameter was either not provided or invalid."
+//	@Failure		401		{object}	error								"Unauthorized"
+//	@Failure		403		{object}	error								"Scope not allowed for the role"
+//	@Failure		500		{object}	error								"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/tokens [post]
+func (app *Application) handlerCreatePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	in := CreatePersonalAccessTokenPayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when creating a personal access token: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	{ // Validate input
+		// Name
+		if in.Name == "" {
+			err := errors.New("name is required")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if len(in.Name) > 100 {
+			err := errors.New("name is too long")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		// Scopes
+		if len(in.Scopes) == 0 {
+			err := errors.New("at least one scope is required")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		for _, scope := range in.Scopes {
+			if !slices.Contains(models.Scopes, scope) {
+				err := fmt.Errorf("unknown scope %s", scope)
+				app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+				return
+			}
+		}
+		// Expiration
+		if in.ExpiresInDays < 0 || in.ExpiresInDays > 365 {
+			err := errors.New("expires_in_days must be between 0 and 365")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+	}
+
+	if slices.Contains(in.Scopes, models.ScopeAdmin) && !app.hasPermission(user, models.PermissionTokensAdmin) {
+		err := fmt.Errorf("user %s with role %s requested the admin scope", user.Username, user.Role.Name)
+		app.respondWithError(w, r, http.StatusForbidden, err, "the admin scope requires the tokens:admin permission")
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		err = fmt.Errorf("error creating personal access token: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	scopes := slices.Clone(in.Scopes)
+	slices.Sort(scopes)
+
+	currentTime := time.Now().UTC()
+	token := &models.PersonalAccessToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		Name:      in.Name,
+		Token:     models.PersonalAccessTokenPrefix + base64.RawURLEncoding.EncodeToString(secret),
+		Scopes:    slices.Compact(scopes),
+		CreatedAt: currentTime,
+	}
+	if in.ExpiresInDays > 0 {
+		expiresAt := currentTime.AddDate(0, 0, in.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := app.Storage.PersonalAccessTokens.Create(ctx, token); err != nil {
+		err = fmt.Errorf("error storing personal access token: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	app.Logger.Infow("personal access token created", "username", user.Username, "id", token.ID, "scopes", token.Scopes)
+
+	out := &PersonalAccessTokenResponse{
+		Token:               token.Token,
+		PersonalAccessToken: token,
+	}
+
+	app.respondWithJSON(w, r, http.StatusCreated, out)
+}
+
+// Revoke Personal Access Token godoc
+//
+//	@Summary		Revokes a personal access token
+//	@Tags			authorization
+//	@Param			tokenID	path	string	true	"Token ID"
+//	@Success		204		"Token was revoked"
+//	@Failure		400		{object}	error	"Invalid token ID"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		404		{object}	error	"Token not found"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/tokens/{tokenID} [delete]
+func (app *Application) handlerRevokePersonalAccessToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	id, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		err := fmt.Errorf("invalid token_id: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid token_id")
+		return
+	}
+
+	if err := app.Storage.PersonalAccessTokens.Revoke(ctx, user.ID, id); err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "token not found")
+		default:
+			err = fmt.Errorf("error revoking personal access token: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
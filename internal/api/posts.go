@@ -1,9 +1,12 @@
 package api
 
 import (
-	"errors"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -48,14 +51,8 @@ func (app *Application) handlerCreatePost(w http.ResponseWriter, r *http.Request
 			app.respondWithError(w, r, http.StatusBadRequest, err, "something is missing")
 			return
 		}
-		if len(in.Title) > MAX_TITLE_LENGTH {
-			err := fmt.Errorf("title is too long, max is %d vs. current %d", MAX_TITLE_LENGTH, len(in.Title))
-			app.respondWithError(w, r, http.StatusBadRequest, err, "title is too long")
-			return
-		}
-		if len(in.Content) > MAX_CONTENT_LENGTH {
-			err := fmt.Errorf("content is too long, max is %d vs. current %d", MAX_CONTENT_LENGTH, len(in.Content))
-			app.respondWithError(w, r, http.StatusBadRequest, err, "content is too long")
+		if err := validatePostLength(in.Title, in.Content); err != nil {
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
 			return
 		}
 	}
@@ -90,14 +87,29 @@ func (app *Application) handlerCreatePost(w http.ResponseWriter, r *http.Request
 //	@Description	Fetch a post
 //	@Tags			posts
 //	@Produce		json
-//	@Param			postID	path		string	true	"Post ID"
-//	@Success		200		{object}	models.Post
-//	@Failure		404		{object}	error	"Post not found"
-//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Param			postID			path		string	true	"Post ID"
+//	@Param			If-None-Match	header		string	false	"ETag of a version already fetched"
+//	@Success		200				{object}	models.Post
+//	@Success		304				"The post did not change"
+//	@Header			200,304			{string}	ETag	"Version of the post and hash of its JSON, comments included"
+//	@Failure		404				{object}	error	"Post not found"
+//	@Failure		500				{object}	error	"Something went wrong on the server"
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [get]
 func (app *Application) handlerGetPost(w http.ResponseWriter, r *http.Request) {
 	post := getPost(r)
+	etag, err := postETag(post)
+	if err != nil {
+		err = fmt.Errorf("error computing ETag of post %v: %v", post.ID, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagNoneMatch(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
 	app.respondWithJSON(w, r, http.StatusOK, post)
 }
@@ -108,10 +120,12 @@ func (app *Application) handlerGetPost(w http.ResponseWriter, r *http.Request) {
 //	@Description	The post will be marked as "deleted" on the database, it will not appear in any feed nor it can be accessed, but it will not be deleted from the database
 //	@Tags			posts
 //	@Produce		json
-//	@Param			postID	path	uuid	true	"Post ID"
-//	@Success		204		"The post was deleted"
-//	@Failure		404		{object}	error	"Post not found"
-//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Param			postID		path	uuid	true	"Post ID"
+//	@Param			If-Match	header	string	false	"ETag of the version to delete"
+//	@Success		204			"The post was deleted"
+//	@Failure		404			{object}	error	"Post not found"
+//	@Failure		412			{object}	error	"The post was modified"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [delete]
 func (app *Application) handlerSoftDeletePost(w http.ResponseWriter, r *http.Request) {
@@ -122,8 +136,7 @@ func (app *Application) handlerSoftDeletePost(w http.ResponseWriter, r *http.Req
 	if err != nil {
 		switch err {
 		case storage.ErrNoRows:
-			err := errors.New("post not found")
-			app.respondWithError(w, r, http.StatusNotFound, err, err.Error())
+			app.respondWithPostChanged(w, r, post)
 		default:
 			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
 		}
@@ -139,10 +152,12 @@ func (app *Application) handlerSoftDeletePost(w http.ResponseWriter, r *http.Req
 //	@Description	The post will be deleted. Only for admins.
 //	@Tags			posts, admin
 //	@Produce		json
-//	@Param			postID	path	uuid	true	"Post ID"
-//	@Success		204		"The post was deleted"
-//	@Failure		404		{object}	error	"Post not found"
-//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Param			postID		path	uuid	true	"Post ID"
+//	@Param			If-Match	header	string	false	"ETag of the version to delete"
+//	@Success		204			"The post was deleted"
+//	@Failure		404			{object}	error	"Post not found"
+//	@Failure		412			{object}	error	"The post was modified"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{postID}/hard [delete]
 func (app *Application) handlerHardDeletePost(w http.ResponseWriter, r *http.Request) {
@@ -153,8 +168,7 @@ func (app *Application) handlerHardDeletePost(w http.ResponseWriter, r *http.Req
 	if err != nil {
 		switch err {
 		case storage.ErrNoRows:
-			err := errors.New("post not found")
-			app.respondWithError(w, r, http.StatusNotFound, err, err.Error())
+			app.respondWithPostChanged(w, r, post)
 		default:
 			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
 		}
@@ -173,14 +187,18 @@ type UpdatePostPayload struct {
 // Update Post godoc
 //
 //	@Summary		Updates a Post
-//	@Description	Updates a Post.
+//	@Description	Updates a Post. Its previous content is kept as a revision, and its version is increased.
 //	@Tags			posts
 //	@Produce		json
-//	@Param			postID	path		string				true	"Post ID"
-//	@Param			Payload	body		UpdatePostPayload	true	"Updated post payload"
-//	@Success		200		{object}	models.Post			"New Post"
-//	@Failure		404		{object}	error				"Post not found"
-//	@Failure		500		{object}	error				"Something went wrong on the server"
+//	@Param			postID		path		string				true	"Post ID"
+//	@Param			If-Match	header		string				false	"ETag of the version to update"
+//	@Param			Payload		body		UpdatePostPayload	true	"Updated post payload"
+//	@Success		200			{object}	models.Post			"New Post"
+//	@Header			200			{string}	ETag				"Version of the new post"
+//	@Failure		400			{object}	error				"Title or content too long"
+//	@Failure		404			{object}	error				"Post not found"
+//	@Failure		412			{object}	error				"The post was modified"
+//	@Failure		500			{object}	error				"Something went wrong on the server"
 //	@Security		ApiKeyAuth
 //	@Router			/posts/{postID} [patch]
 func (app *Application) handlerUpdatePost(w http.ResponseWriter, r *http.Request) {
@@ -191,9 +209,15 @@ func (app *Application) handlerUpdatePost(w http.ResponseWriter, r *http.Request
 	if err := readJSON(w, r, &in); err != nil {
 		err = fmt.Errorf("error reading input parameters: %v", err)
 		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if err := validatePostLength(in.Title, in.Content); err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
 	}
 
 	newPost := &models.Post{
+		ID:      post.ID,
 		Title:   in.Title,
 		Content: in.Content,
 		Tags:    in.Tags,
@@ -204,13 +228,76 @@ func (app *Application) handlerUpdatePost(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		switch err {
 		case storage.ErrNoRows:
-			err = fmt.Errorf("post with id: %v not found", post.ID)
-			app.respondWithError(w, r, http.StatusNotFound, err, "post not found")
+			app.respondWithPostChanged(w, r, post)
 		default:
 			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
 		}
 		return
 	}
 
+	if etag, err := postETag(updatedPost); err == nil {
+		w.Header().Set("ETag", etag)
+	}
 	app.respondWithJSON(w, r, http.StatusOK, updatedPost)
 }
+
+// Checks the length of the title and content of a post, returning an error which can be shown to the user
+func validatePostLength(title, content string) error {
+	if len(title) > MAX_TITLE_LENGTH {
+		return fmt.Errorf("title is too long, max is %d characters", MAX_TITLE_LENGTH)
+	}
+	if len(content) > MAX_CONTENT_LENGTH {
+		return fmt.Errorf("content is too long, max is %d characters", MAX_CONTENT_LENGTH)
+	}
+	return nil
+}
+
+// Responds to a change of a post which found it deleted or edited after it was loaded. It was a precondition of the
+// request if it had If-Match.
+func (app *Application) respondWithPostChanged(w http.ResponseWriter, r *http.Request, post *models.Post) {
+	if r.Header.Get("If-Match") != "" {
+		err := fmt.Errorf("post %v was modified after version %d", post.ID, post.Version)
+		app.respondWithError(w, r, http.StatusPreconditionFailed, err, "the post was modified")
+		return
+	}
+	err := fmt.Errorf("post %v not found", post.ID)
+	app.respondWithError(w, r, http.StatusNotFound, err, "post not found")
+}
+
+// Strong ETag of the JSON of a post, made of its version and a hash of the JSON. The hash tells apart the comments
+// added meanwhile and the ones each viewer can see, which do not change the version.
+func postETag(post *models.Post) (string, error) {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf(`"v%d-%s"`, post.Version, hex.EncodeToString(sum[:8])), nil
+}
+
+// Checks if the list of ETags of an If-None-Match header matches `etag`, comparing them weakly
+func etagNoneMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks if the list of ETags of an If-Match header matches the version of a post. Only the version is compared, so
+// requests are not refused because of comments they did not change. Weak ETags never match.
+func etagMatchesVersion(header string, version int32) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		tagVersion, _, _ := strings.Cut(strings.Trim(candidate, `"`), "-")
+		if strings.HasPrefix(candidate, `"`) && tagVersion == fmt.Sprintf("v%d", version) {
+			return true
+		}
+	}
+	return false
+}
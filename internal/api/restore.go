@@ -0,0 +1,235 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+// List Deleted Users godoc
+//
+//	@Summary		Lists deleted users
+//	@Description	Lists the users which can be restored, most recently deleted first. These are the users whose deletion is pending, which are purged once their grace period ends, and the ones soft deleted before deletions had one. Purged users are not listed.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			username	query		string	false	"Filter by username"
+//	@Param			since		query		string	false	"Only deleted, or to be purged, on or after this date (YYYY-MM-DD)"
+//	@Param			until		query		string	false	"Only deleted, or to be purged, before this date (YYYY-MM-DD)"
+//	@Param			limit		query		int		false	"Number of users. Default 50; Maximum 200"
+//	@Param			offset		query		int		false	"Offset. Default 0"
+//	@Success		200			{array}		models.AdminUser
+//	@Failure		400			{object}	error	"Invalid parameters"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"Forbidden"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/deleted/users [get]
+func (app *Application) handlerListDeletedUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Validate input
+	limit, offset, err := readPagination(r, 50, 200)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	since, until, err := readDeletionDates(r)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	users, err := app.Storage.Users.GetDeleted(ctx, r.URL.Query().Get("username"), since, until, limit, offset)
+	if err != nil {
+		err = fmt.Errorf("error fetching deleted users: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	views := make([]*models.AdminUser, len(users))
+	for i, user := range users {
+		views[i] = user.AdminView(app.rolePermissions(user.Role.Name))
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, views)
+}
+
+// Restore User godoc
+//
+//	@Summary		Restores a deleted user
+//	@Description	Cancels the pending deletion of a user, or undoes its soft deletion. Purged users can not be restored.
+//	@Tags			admin
+//	@Produce		json
+//	@Param			userID	path		string	true	"User ID"
+//	@Success		200		{object}	models.AdminUser
+//	@Failure		400		{object}	error	"Invalid ID"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		403		{object}	error	"Forbidden"
+//	@Failure		404		{object}	error	"Deleted user not found"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/deleted/users/{userID}/restore [post]
+func (app *Application) handlerRestoreUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actor := getLoggedUser(r)
+
+	// Validate input
+	id, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		err := fmt.Errorf("invalid user_id: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid user_id")
+		return
+	}
+
+	entry, err := models.NewAuditLogEntry(actor.ID, models.AuditUserRestored, "user", id.String(), nil)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if err := app.Storage.Users.Restore(ctx, id, entry); err != nil {
+		switch err {
+		case storage.ErrNoUser:
+			err := fmt.Errorf("user %v was not restored because no deleted user was found", id)
+			app.respondWithError(w, r, http.StatusNotFound, err, "deleted user not found")
+		default:
+			err := fmt.Errorf("error restoring user %v: %v", id, err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	user, err := app.Storage.Users.GetByID(ctx, id)
+	if err != nil {
+		err = fmt.Errorf("error fetching user after restoring it: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	app.Logger.Infow("user restored", "username", user.Username, "by", actor.Username)
+
+	if app.Config.Cache.Enabled {
+		app.Cache.Users.Delete(ctx, user.Username)
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, user.AdminView(app.rolePermissions(user.Role.Name)))
+}
+
+// List Deleted Posts godoc
+//
+//	@Summary		Lists soft deleted posts
+//	@Description	Lists soft deleted posts, most recently deleted first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			username	query		string	false	"Filter by username of the author"
+//	@Param			since		query		string	false	"Only deleted on or after this date (YYYY-MM-DD)"
+//	@Param			until		query		string	false	"Only deleted before this date (YYYY-MM-DD)"
+//	@Param			limit		query		int		false	"Number of posts. Default 50; Maximum 200"
+//	@Param			offset		query		int		false	"Offset. Default 0"
+//	@Success		200			{array}		models.Post
+//	@Failure		400			{object}	error	"Invalid parameters"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"Forbidden"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/deleted/posts [get]
+func (app *Application) handlerListDeletedPosts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Validate input
+	limit, offset, err := readPagination(r, 50, 200)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	since, until, err := readDeletionDates(r)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	posts, err := app.Storage.Posts.GetDeleted(ctx, r.URL.Query().Get("username"), since, until, limit, offset)
+	if err != nil {
+		err = fmt.Errorf("error fetching deleted posts: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, posts)
+}
+
+// Restore Post godoc
+//
+//	@Summary		Restores a soft deleted post
+//	@Description	Undoes the soft deletion of a post
+//	@Tags			admin
+//	@Produce		json
+//	@Param			postID	path		string	true	"Post ID"
+//	@Success		200		{object}	models.Post
+//	@Failure		400		{object}	error	"Invalid ID"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		403		{object}	error	"Forbidden"
+//	@Failure		404		{object}	error	"Deleted post not found"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/deleted/posts/{postID}/restore [post]
+func (app *Application) handlerRestorePost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actor := getLoggedUser(r)
+
+	// Validate input
+	id, err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		err := fmt.Errorf("invalid post_id: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid post_id")
+		return
+	}
+
+	entry, err := models.NewAuditLogEntry(actor.ID, models.AuditPostRestored, "post", id.String(), nil)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	post, err := app.Storage.Posts.Restore(ctx, id, entry)
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			err := fmt.Errorf("post %v was not restored because no deleted post was found", id)
+			app.respondWithError(w, r, http.StatusNotFound, err, "deleted post not found")
+		default:
+			err := fmt.Errorf("error restoring post %v: %v", id, err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	app.Logger.Infow("post restored", "post_id", post.ID, "by", actor.Username)
+
+	app.respondWithJSON(w, r, http.StatusOK, post)
+}
+
+// Reads the `since` and `until` dates which filter when resources were deleted
+func readDeletionDates(r *http.Request) (*time.Time, *time.Time, error) {
+	query := r.URL.Query()
+	var since, until *time.Time
+
+	if sinceStr := query.Get("since"); sinceStr != "" {
+		sinceDate, err := time.Parse("2006-01-02", sinceStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("since must be a date like 2006-01-02")
+		}
+		since = &sinceDate
+	}
+	if untilStr := query.Get("until"); untilStr != "" {
+		untilDate, err := time.Parse("2006-01-02", untilStr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("until must be a date like 2006-01-02")
+		}
+		until = &untilDate
+	}
+
+	return since, until, nil
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+	"github.com/maxolivera/gophis-social-network/pkg/diff"
+)
+
+type PostDiffResponse struct {
+	From        int32       `json:"from"`
+	To          int32       `json:"to"`
+	Title       []diff.Edit `json:"title"`
+	Content     []diff.Edit `json:"content"`
+	TagsAdded   []string    `json:"tags_added"`
+	TagsRemoved []string    `json:"tags_removed"`
+}
+
+// List Post Revisions godoc
+//
+//	@Summary		Lists the revisions of a post
+//	@Description	Lists the previous versions of a post, kept each time it was edited, most recent first. The current version is the post itself.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		string	true	"Post ID"
+//	@Success		200		{array}		models.PostRevision
+//	@Failure		404		{object}	error	"Post not found"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/revisions [get]
+func (app *Application) handlerListPostRevisions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	post := getPost(r)
+
+	revisions, err := app.Storage.Posts.GetRevisions(ctx, post.ID)
+	if err != nil {
+		err = fmt.Errorf("error fetching revisions of post %v: %v", post.ID, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, revisions)
+}
+
+// Diff Post godoc
+//
+//	@Summary		Compares two versions of a post
+//	@Description	Diffs the title and content of two versions of a post word by word, and lists the tags added and removed between them. By default it compares the current version with the previous one.
+//	@Tags			posts
+//	@Produce		json
+//	@Param			postID	path		string	true	"Post ID"
+//	@Param			from	query		int		false	"Older version. Default the one before `to`"
+//	@Param			to		query		int		false	"Newer version. Default the current one"
+//	@Success		200		{object}	PostDiffResponse
+//	@Failure		400		{object}	error	"Invalid versions"
+//	@Failure		404		{object}	error	"Post or revision not found"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/diff [get]
+func (app *Application) handlerDiffPost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	post := getPost(r)
+	query := r.URL.Query()
+
+	// Validate input
+	to := post.Version
+	if toStr := query.Get("to"); toStr != "" {
+		parsed, err := strconv.ParseInt(toStr, 10, 32)
+		if err != nil || parsed < 0 || int32(parsed) > post.Version {
+			err := fmt.Errorf("to must be a version between 0 and %d", post.Version)
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		to = int32(parsed)
+	}
+	from := to - 1
+	if fromStr := query.Get("from"); fromStr != "" {
+		parsed, err := strconv.ParseInt(fromStr, 10, 32)
+		if err != nil || parsed < 0 || int32(parsed) > to {
+			err := fmt.Errorf("from must be a version between 0 and %d", to)
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		from = int32(parsed)
+	}
+	if from < 0 {
+		err := fmt.Errorf("post %v was never edited", post.ID)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "the post was never edited")
+		return
+	}
+
+	older, err := app.postRevision(ctx, post, from)
+	if err != nil {
+		app.respondWithRevisionError(w, r, post, from, err)
+		return
+	}
+	newer, err := app.postRevision(ctx, post, to)
+	if err != nil {
+		app.respondWithRevisionError(w, r, post, to, err)
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, &PostDiffResponse{
+		From:        from,
+		To:          to,
+		Title:       diff.Words(older.Title, newer.Title),
+		Content:     diff.Words(older.Content, newer.Content),
+		TagsAdded:   missingTags(newer.Tags, older.Tags),
+		TagsRemoved: missingTags(older.Tags, newer.Tags),
+	})
+}
+
+// Fetches a version of the post, which is the post itself when it is the current one
+func (app *Application) postRevision(ctx context.Context, post *models.Post, version int32) (*models.PostRevision, error) {
+	if version == post.Version {
+		return &models.PostRevision{
+			Version: post.Version,
+			Title:   post.Title,
+			Content: post.Content,
+			Tags:    post.Tags,
+		}, nil
+	}
+	return app.Storage.Posts.GetRevision(ctx, post.ID, version)
+}
+
+func (app *Application) respondWithRevisionError(w http.ResponseWriter, r *http.Request, post *models.Post, version int32, err error) {
+	switch err {
+	case storage.ErrNoRows:
+		err := fmt.Errorf("revision %d of post %v not found", version, post.ID)
+		app.respondWithError(w, r, http.StatusNotFound, err, "revision not found")
+	default:
+		err := fmt.Errorf("error fetching revision %d of post %v: %v", version, post.ID, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+	}
+}
+
+// Returns the tags of `a` which are not in `b`
+func missingTags(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, tag := range b {
+		inB[tag] = true
+	}
+
+	missing := []string{}
+	for _, tag := range a {
+		if !inB[tag] {
+			missing = append(missing, tag)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+var roleNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,49}$`)
+
+type CreateRolePayload struct {
+	Name        string `json:"name"`
+	Level       int    `json:"level"`
+	Description string `json:"description"`
+}
+
+type AssignRolePayload struct {
+	Role string `json:"role"`
+}
+
+// List Roles godoc
+//
+//	@Summary		Lists roles
+//	@Description	Lists every role, by level
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{array}		models.Role
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		403	{object}	error	"Forbidden"
+//	@Failure		500	{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [get]
+func (app *Application) handlerListRoles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	roles, err := app.Storage.Roles.GetAll(ctx)
+	if err != nil {
+		err = fmt.Errorf("error fetching roles: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, roles)
+}
+
+// Create Role godoc
+//
+//	@Summary		Creates a role
+//	@Description	Creates a custom role. Its level can not be higher than the one of the admin creating it.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body		CreateRolePayload	true	"Name, level and description"
+//	@Success		201		{object}	models.Role
+//	@Failure		400		{object}	error	"Some parameter was either not provided or invalid."
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		403		{object}	error	"Level higher than the one of the admin"
+//	@Failure		409		{object}	error	"Name already taken"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/roles [post]
+func (app *Application) handlerCreateRole(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actor := getLoggedUser(r)
+
+	in := CreateRolePayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when creating a role: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	{ // Validate input
+		// Name
+		if !roleNameRegex.MatchString(in.Name) {
+			err := errors.New("name must be lowercase, start with a letter and have at most 50 letters, digits, '-' or '_'")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		// Level
+		if in.Level < 0 {
+			err := errors.New("level can not be negative")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if in.Level > actor.Role.Level {
+			err := fmt.Errorf("user %s tried to create role %s with level %d, above its own", actor.Username, in.Name, in.Level)
+			app.respondWithError(w, r, http.StatusForbidden, err, "level can not be higher than your own")
+			return
+		}
+		// Description
+		if len(in.Description) > 500 {
+			err := errors.New("description is too long")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+	}
+
+	role := &models.Role{
+		Name:        models.RoleType(in.Name),
+		Level:       in.Level,
+		Description: in.Description,
+	}
+
+	entry, err := models.NewAuditLogEntry(actor.ID, models.AuditRoleCreated, "role", in.Name, map[string]any{
+		"level": in.Level,
+	})
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if err := app.Storage.Roles.Create(ctx, role, entry); err != nil {
+		switch err {
+		case storage.ErrConflict:
+			app.respondWithError(w, r, http.StatusConflict, err, "role already exists")
+		default:
+			err = fmt.Errorf("error creating role: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	app.Logger.Infow("role created", "name", role.Name, "level", role.Level, "by", actor.Username)
+
+	app.respondWithJSON(w, r, http.StatusCreated, role)
+}
+
+// Assign Role godoc
+//
+//	@Summary		Assigns a role to a user
+//	@Description	Replaces the role of a user. Admins can not change their own role, nor assign roles, or change users, above their own level.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			username	path		string				true	"Username"
+//	@Param			Payload		body		AssignRolePayload	true	"Role name"
+//	@Success		200			{object}	models.AdminUser
+//	@Failure		400			{object}	error	"Some parameter was either not provided or invalid."
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"Forbidden"
+//	@Failure		404			{object}	error	"User or role not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{username}/role [put]
+func (app *Application) handlerAssignRole(w http.ResponseWriter, r *http.Request) {
+	in := AssignRolePayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when assigning a role: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if in.Role == "" {
+		err := errors.New("role is required")
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	app.changeUserRole(w, r, models.RoleType(in.Role))
+}
+
+// Revoke Role godoc
+//
+//	@Summary		Revokes the role of a user
+//	@Description	Sets the role of a user back to the default one (user)
+//	@Tags			admin
+//	@Produce		json
+//	@Param			username	path		string	true	"Username"
+//	@Success		200			{object}	models.AdminUser
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"Forbidden"
+//	@Failure		404			{object}	error	"User not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{username}/role [delete]
+func (app *Application) handlerRevokeRole(w http.ResponseWriter, r *http.Request) {
+	app.changeUserRole(w, r, models.RoleUser)
+}
+
+// Changes the role of the route user to `name`, on behalf of the logged user
+func (app *Application) changeUserRole(w http.ResponseWriter, r *http.Request, name models.RoleType) {
+	ctx := r.Context()
+	actor := getLoggedUser(r)
+	user := getRouteUser(r)
+
+	if user.ID == actor.ID {
+		err := fmt.Errorf("user %s tried to change its own role", actor.Username)
+		app.respondWithError(w, r, http.StatusForbidden, err, "you can not change your own role")
+		return
+	}
+	if user.Role.Level > actor.Role.Level {
+		err := fmt.Errorf("user %s tried to change the role of %s, whose level is higher", actor.Username, user.Username)
+		app.respondWithError(w, r, http.StatusForbidden, err, "forbidden")
+		return
+	}
+
+	role, err := app.Storage.Roles.GetByName(ctx, string(name))
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "role not found")
+		default:
+			err = fmt.Errorf("error during role fetching: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	if role.Level > actor.Role.Level {
+		err := fmt.Errorf("user %s tried to assign role %s, whose level is higher than its own", actor.Username, role.Name)
+		app.respondWithError(w, r, http.StatusForbidden, err, "role level can not be higher than your own")
+		return
+	}
+
+	if role.Name != user.Role.Name {
+		entry, err := models.NewAuditLogEntry(actor.ID, models.AuditUserRoleChanged, "user", user.ID.String(), map[string]any{
+			"username": user.Username,
+			"from":     user.Role.Name,
+			"to":       role.Name,
+		})
+		if err != nil {
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			return
+		}
+
+		if err := app.Storage.Roles.AssignToUser(ctx, user.ID, role.Name, entry); err != nil {
+			switch err {
+			case storage.ErrNoRows:
+				app.respondWithError(w, r, http.StatusNotFound, err, "role not found")
+			case storage.ErrNoUser:
+				app.respondWithError(w, r, http.StatusNotFound, err, "user not found")
+			default:
+				err = fmt.Errorf("error assigning role: %v", err)
+				app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			}
+			return
+		}
+		app.Logger.Infow("role changed", "username", user.Username, "from", user.Role.Name, "to", role.Name, "by", actor.Username)
+
+		if app.Config.Cache.Enabled {
+			app.Cache.Users.Delete(ctx, user.Username)
+		}
+	}
+
+	changedUser, err := app.Storage.Users.GetByID(ctx, user.ID)
+	if err != nil {
+		err = fmt.Errorf("error fetching user after changing its role: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, changedUser.AdminView(app.rolePermissions(changedUser.Role.Name)))
+}
+
+// List Audit Log godoc
+//
+//	@Summary		Lists the audit log
+//	@Description	Lists administrative changes, newest first
+//	@Tags			admin
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of entries. Default 50; Maximum 200"
+//	@Param			offset	query		int	false	"Offset. Default 0"
+//	@Success		200		{array}		models.AuditLogEntry
+//	@Failure		400		{object}	error	"Invalid parameters"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		403		{object}	error	"Forbidden"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/audit-log [get]
+func (app *Application) handlerListAuditLog(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, offset, err := readPagination(r, 50, 200)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	entries, err := app.Storage.AuditLog.List(ctx, limit, offset)
+	if err != nil {
+		err = fmt.Errorf("error fetching audit log: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, entries)
+}
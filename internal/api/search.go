@@ -133,7 +133,7 @@ func (app *Application) handlerSearch(w http.ResponseWriter, r *http.Request) {
 	}
 
 	feed, err := app.Storage.Posts.Search(
-		ctx, word, tags, limit, offset, sort, since, until,
+		ctx, getLoggedUser(r), word, tags, limit, offset, sort, since, until,
 	)
 	if err != nil {
 		switch err {
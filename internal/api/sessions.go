@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+// List Sessions godoc
+//
+//	@Summary		Lists active sessions
+//	@Description	Lists the devices where the logged user is logged in, most recently seen first. The session of the request is marked as current.
+//	@Tags			authorization
+//	@Produce		json
+//	@Success		200	{array}		models.Session	"Sessions"
+//	@Failure		401	{object}	error			"Unauthorized"
+//	@Failure		500	{object}	error			"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/sessions [get]
+func (app *Application) handlerListSessions(w http.ResponseWriter, r *http.Request) {
+	app.listSessions(w, r, getLoggedUser(r))
+}
+
+// Revoke Session godoc
+//
+//	@Summary		Revokes a session
+//	@Description	Logs out a device of the logged user. Its access tokens stop working immediately and its refresh token can not be used anymore.
+//	@Tags			authorization
+//	@Param			sessionID	path	string	true	"Session ID"
+//	@Success		204			"Session was revoked"
+//	@Failure		400			{object}	error	"Invalid session ID"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		404			{object}	error	"Session not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/sessions/{sessionID} [delete]
+func (app *Application) handlerRevokeSession(w http.ResponseWriter, r *http.Request) {
+	app.revokeSession(w, r, getLoggedUser(r))
+}
+
+// List User Sessions godoc
+//
+//	@Summary		Lists active sessions of a user
+//	@Tags			admin
+//	@Produce		json
+//	@Param			username	path		string			true	"Username"
+//	@Success		200			{array}		models.Session	"Sessions"
+//	@Failure		401			{object}	error			"Unauthorized"
+//	@Failure		403			{object}	error			"Forbidden"
+//	@Failure		404			{object}	error			"User not found"
+//	@Failure		500			{object}	error			"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{username}/sessions [get]
+func (app *Application) handlerListUserSessions(w http.ResponseWriter, r *http.Request) {
+	app.listSessions(w, r, getRouteUser(r))
+}
+
+// Revoke User Session godoc
+//
+//	@Summary		Revokes a session of a user
+//	@Tags			admin
+//	@Param			username	path	string	true	"Username"
+//	@Param			sessionID	path	string	true	"Session ID"
+//	@Success		204			"Session was revoked"
+//	@Failure		400			{object}	error	"Invalid session ID"
+//	@Failure		401			{object}	error	"Unauthorized"
+//	@Failure		403			{object}	error	"Forbidden"
+//	@Failure		404			{object}	error	"User or session not found"
+//	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/admin/users/{username}/sessions/{sessionID} [delete]
+func (app *Application) handlerRevokeUserSession(w http.ResponseWriter, r *http.Request) {
+	app.revokeSession(w, r, getRouteUser(r))
+}
+
+func (app *Application) listSessions(w http.ResponseWriter, r *http.Request, user *models.User) {
+	ctx := r.Context()
+
+	sessions, err := app.Storage.Sessions.GetByUser(ctx, user.ID)
+	if err != nil {
+		err = fmt.Errorf("error fetching sessions of user %s: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if claims := getTokenClaims(r); claims != nil {
+		sid, _ := getStringClaim(claims, "sid")
+		for _, session := range sessions {
+			session.Current = session.ID.String() == sid
+		}
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, sessions)
+}
+
+func (app *Application) revokeSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	ctx := r.Context()
+
+	id, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		err := fmt.Errorf("invalid session_id: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid session_id")
+		return
+	}
+
+	if err := app.Storage.Sessions.Revoke(ctx, user.ID, id); err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "session not found")
+		default:
+			err = fmt.Errorf("error revoking session: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	// NOTE(maolivera): Access tokens of the session are still valid until they expire, so the session is
+	// revoked as a whole for their lifetime
+	if err := app.Cache.Revocations.Revoke(ctx, id.String(), app.Config.Authentication.Token.ExpirationTime); err != nil {
+		err = fmt.Errorf("error revoking access tokens of session: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	app.Logger.Infow("session revoked", "username", user.Username, "id", id, "by", getLoggedUser(r).Username)
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
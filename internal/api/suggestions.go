@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+)
+
+// List Suggestions godoc
+//
+//	@Summary		Suggests users to follow
+//	@Description	Lists users the logged user may want to follow, best first. They are ranked by how many of the followed users follow them, the tags used by both on their recent posts and how active they are. Suggestions are computed periodically, so new users and follows take a while to be taken into account.
+//	@Tags			users
+//	@Produce		json
+//	@Param			limit	query		int	false	"Max number of suggestions, up to 50"	default(10)
+//	@Success		200		{array}		models.Suggestion
+//	@Failure		400		{object}	error	"Invalid limit"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/suggestions [get]
+func (app *Application) handlerListSuggestions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	// Validate input
+	limit, _, err := readPagination(r, 10, int32(app.Config.Suggestions.MaxPerUser))
+	if err != nil {
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	suggestions, err := app.Storage.Suggestions.GetByUser(ctx, user.ID, limit)
+	if err != nil {
+		err = fmt.Errorf("error fetching suggestions of user %s: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, suggestions)
+}
+
+// Refreshes the suggestions now and then on every interval, until ctx is done
+func (app *Application) refreshSuggestionsPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(app.Config.Suggestions.Interval)
+	defer ticker.Stop()
+
+	for {
+		app.refreshSuggestions(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (app *Application) refreshSuggestions(ctx context.Context) {
+	// NOTE(maolivera): Every instance tries on its own interval, but only one can refresh them at a time. The others
+	// skip their turn instead of waiting, the suggestions will be fresh anyway.
+	ctx, cancel := context.WithTimeout(ctx, app.Config.Suggestions.Interval)
+	defer cancel()
+
+	start := time.Now()
+	since := start.UTC().Add(-app.Config.Suggestions.Window)
+	refreshed, err := app.Storage.Suggestions.Refresh(ctx, since, int32(app.Config.Suggestions.AuthorsPerTag), int32(app.Config.Suggestions.MaxPerUser))
+	if err == storage.ErrInProgress {
+		app.Logger.Infow("suggestions are being refreshed by another instance")
+		return
+	}
+	if err != nil {
+		app.Logger.Errorw("could not refresh suggestions", "error", err.Error())
+		return
+	}
+
+	app.Logger.Infow("suggestions refreshed", "suggestions", refreshed, "duration", time.Since(start).String())
+}
@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,7 +11,9 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
 )
 
 type CreateTokenPayload struct {
@@ -17,22 +21,32 @@ type CreateTokenPayload struct {
 	Password string
 }
 
+type RefreshTokenPayload struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 // Create Token godoc
 //
 //	@Summary		Creates a Token
-//	@Description	Creates a Token for the user
+//	@Description	Creates a short-lived access Token and a refresh Token for the user. If the user enabled two-factor, a challenge is returned instead, which must be completed on /token/2fa.
 //	@Tags			authorization
 //	@Accept			json
 //	@Produce		json
-//	@Param			Payload	body		CreateTokenPayload	true	"User credentials"
-//	@Success		201		{object}	TokenResponse		"Token"
-//	@Failure		500		{object}	error				"Something went wrong on the server"
-//	@Failure		409		{object}	error				"Either email or username already taken"
-//	@Failure		400		{object}	error				"Some parameter was either not provided or invalid."
+//	@Param			Payload	body		CreateTokenPayload			true	"User credentials"
+//	@Success		201		{object}	TokenResponse				"Token"
+//	@Success		200		{object}	TwoFactorChallengeResponse	"Two-factor challenge"
+//	@Failure		500		{object}	error						"Something went wrong on the server"
+//	@Failure		409		{object}	error						"Either email or username already taken"
+//	@Failure		400		{object}	error						"Some parameter was either not provided or invalid."
+//	@Failure		401		{object}	error						"Invalid credentials"
+//	@Failure		423		{object}	error						"Account locked after too many failed logins"
+//	@Failure		429		{object}	error						"Too many failed logins from the IP"
 //	@Router			/token [post]
 func (app *Application) handlerCreateToken(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -75,10 +89,18 @@ func (app *Application) handlerCreateToken(w http.ResponseWriter, r *http.Reques
 			return
 		}
 	}
+
+	// Brute-force protection, checked before spending time on bcrypt
+	ip := clientIP(r)
+	if !app.checkLoginLock(w, r, in.Email, ip) {
+		return
+	}
+
 	user, err := app.Storage.Users.GetByEmailAndPassword(ctx, in.Email, in.Password)
 	if err != nil {
 		switch err {
 		case storage.ErrNoRows:
+			app.recordLoginFailure(ctx, r, in.Email, ip)
 			// NOTE(maolivera): Returning 404 is insecure
 			app.unauthorizedBasicErrorResponse(w, r, err)
 		default:
@@ -86,24 +108,290 @@ func (app *Application) handlerCreateToken(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
+	app.recordLoginSuccess(ctx, r, in.Email, ip)
+
+	app.completeLogin(ctx, w, r, user)
+}
+
+// Responds with the tokens of a user whose credentials were verified, or with a two-factor challenge if the
+// user enabled it
+func (app *Application) completeLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, user *models.User) {
+	// Second factor
+	totp, err := app.Storage.TwoFactor.Get(ctx, user.ID)
+	if err != nil && err != storage.ErrNoRows {
+		err = fmt.Errorf("error fetching two-factor of user %s: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if err == nil && totp.Enabled() {
+		challenge, err := app.createTwoFactorChallenge(ctx, user)
+		if err != nil {
+			err = fmt.Errorf("error creating two-factor challenge: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			return
+		}
+
+		app.respondWithJSON(w, r, http.StatusOK, challenge)
+		return
+	}
+
+	out, err := app.createTokens(ctx, r, user)
+	if err != nil {
+		err = fmt.Errorf("error creating tokens: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	// Send response
+	app.respondWithJSON(w, r, http.StatusCreated, out)
+}
+
+// Refresh Token godoc
+//
+//	@Summary		Refreshes a Token
+//	@Description	Exchanges a refresh token for a new access token and a new refresh token. Each refresh token can only be used once, reusing it revokes every token issued from the same login.
+//	@Tags			authorization
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body		RefreshTokenPayload	true	"Refresh token"
+//	@Success		201		{object}	TokenResponse		"Token"
+//	@Failure		500		{object}	error				"Something went wrong on the server"
+//	@Failure		401		{object}	error				"Refresh token is invalid, expired or was already used"
+//	@Failure		400		{object}	error				"Some parameter was either not provided or invalid."
+//	@Router			/token/refresh [post]
+func (app *Application) handlerRefreshToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	in := RefreshTokenPayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when refreshing a token: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if in.RefreshToken == "" {
+		err := errors.New("refresh token is required")
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+	token, err := base64.URLEncoding.DecodeString(in.RefreshToken)
+	if err != nil {
+		err := fmt.Errorf("error decoding refresh token: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid refresh token")
+		return
+	}
+
+	next, err := app.newRefreshToken(uuid.Nil, uuid.Nil)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	current, err := app.Storage.Tokens.Rotate(ctx, token, next)
+	if err != nil {
+		switch err {
+		case storage.ErrNoToken:
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		case storage.ErrTokenReused:
+			app.Logger.Warnw("refresh token reuse detected, token family revoked", "method", r.Method, "path", r.URL.Path)
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		default:
+			err = fmt.Errorf("error rotating refresh token: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
 
+	user, err := app.Storage.Users.GetByID(ctx, current.UserID)
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			// NOTE(maolivera): User was deleted or deactivated after login
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		default:
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	accessToken, err := app.createAccessToken(user, current.FamilyID)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	// NOTE(maolivera): Clients refresh at least once per access token, which is precise enough for last seen
+	if err := app.Storage.Sessions.Touch(ctx, current.FamilyID, clientIP(r), r.UserAgent()); err != nil {
+		app.Logger.Warnw("could not update session", "id", current.FamilyID, "error", err)
+	}
+
+	out := &TokenResponse{
+		Token:        accessToken,
+		RefreshToken: base64.URLEncoding.EncodeToString(next.Token),
+		ExpiresIn:    int64(app.Config.Authentication.Token.ExpirationTime.Seconds()),
+	}
+
+	app.respondWithJSON(w, r, http.StatusCreated, out)
+}
+
+// Creates a signed access token (JWT) for the user. `sessionID` is the family of the refresh token issued
+// along with it, so both can be revoked together.
+func (app *Application) createAccessToken(user *models.User, sessionID uuid.UUID) (string, error) {
+	currentTime := time.Now()
 	claims := jwt.MapClaims{
+		"jti": uuid.New().String(),
+		"sid": sessionID.String(),
 		"sub": user.Username,
-		"exp": time.Now().Add(app.Config.Authentication.Token.ExpirationTime).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
+		"exp": currentTime.Add(app.Config.Authentication.Token.ExpirationTime).Unix(),
+		"iat": currentTime.Unix(),
+		"nbf": currentTime.Unix(),
 		"iss": app.Config.Authentication.Token.Issuer,
 		"aud": app.Config.Authentication.Token.Issuer,
 	}
 
-	token, err := app.Authenticator.GenerateToken(claims)
+	return app.Authenticator.GenerateToken(claims)
+}
+
+// Creates a new, not yet stored, refresh token
+func (app *Application) newRefreshToken(userID, familyID uuid.UUID) (*models.RefreshToken, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("error creating refresh token: %v", err)
+	}
+
+	currentTime := time.Now().UTC()
+	return &models.RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		Token:     token,
+		CreatedAt: currentTime,
+		ExpiresAt: currentTime.Add(app.Config.Authentication.Token.RefreshExpirationTime),
+	}, nil
+}
+
+// Starts a session for the user: stores it along with its first refresh token, and creates an access token.
+// Logging in cancels the deletion of the user, if it was scheduled.
+func (app *Application) createTokens(ctx context.Context, r *http.Request, user *models.User) (*TokenResponse, error) {
+	if user.DeletionScheduledAt != nil {
+		if err := app.cancelDeletion(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	currentTime := time.Now().UTC()
+	session := &models.Session{
+		ID:         uuid.New(),
+		UserID:     user.ID,
+		IP:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		CreatedAt:  currentTime,
+		LastSeenAt: currentTime,
+	}
+
+	accessToken, err := app.createAccessToken(user, session.ID)
 	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := app.newRefreshToken(user.ID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := app.Storage.Sessions.Create(ctx, session, refreshToken); err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		Token:        accessToken,
+		RefreshToken: base64.URLEncoding.EncodeToString(refreshToken.Token),
+		ExpiresIn:    int64(app.Config.Authentication.Token.ExpirationTime.Seconds()),
+	}, nil
+}
+
+// Logout godoc
+//
+//	@Summary		Logs out the current session
+//	@Description	Revokes the token used on the request and the refresh token issued along with it
+//	@Tags			authorization
+//	@Produce		json
+//	@Success		204	"Session was closed"
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/logout [post]
+func (app *Application) handlerLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	claims := getTokenClaims(r)
+
+	jti, err := getStringClaim(claims, "jti")
+	if err != nil {
+		app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		err := fmt.Errorf("expiration time (exp) not found in token claims: %v", err)
+		app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+
+	if err := app.Cache.Revocations.Revoke(ctx, jti, time.Until(exp.Time)); err != nil {
+		err = fmt.Errorf("error revoking token: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if sid, err := getStringClaim(claims, "sid"); err == nil {
+		familyID, err := uuid.Parse(sid)
+		if err != nil {
+			err = fmt.Errorf("session ID (sid) in token claims is not a valid UUID: %v", err)
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+			return
+		}
+		if err := app.Storage.Tokens.RevokeFamily(ctx, familyID); err != nil {
+			err = fmt.Errorf("error revoking refresh tokens: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			return
+		}
+	}
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// Logout All godoc
+//
+//	@Summary		Logs out every session
+//	@Description	Revokes every token and refresh token issued to the logged user until now
+//	@Tags			authorization
+//	@Produce		json
+//	@Success		204	"Every session was closed"
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/logout/all [post]
+func (app *Application) handlerLogoutAll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	if err := app.revokeUserTokens(ctx, user); err != nil {
+		err = fmt.Errorf("error revoking tokens of user %s: %v", user.Username, err)
 		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
 		return
 	}
 
-	out := &TokenResponse{token}
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// Revokes every access token and refresh token issued to the user until now
+func (app *Application) revokeUserTokens(ctx context.Context, user *models.User) error {
+	// NOTE(maolivera): `iat` has a precision of seconds
+	until := time.Now().Truncate(time.Second)
+	if err := app.Cache.Revocations.RevokeUser(ctx, user.ID.String(), until, app.Config.Authentication.Token.ExpirationTime); err != nil {
+		return err
+	}
 
-	// Send response
-	app.respondWithJSON(w, r, http.StatusCreated, out)
+	return app.Storage.Tokens.RevokeByUser(ctx, user.ID)
 }
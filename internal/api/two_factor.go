@@ -0,0 +1,452 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/maxolivera/gophis-social-network/internal/auth"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+const recoveryCodesCount = 10
+
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	Challenge         string `json:"challenge"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
+// Either Code or RecoveryCode must be provided
+type TwoFactorLoginPayload struct {
+	Challenge    string `json:"challenge"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// Either Code or RecoveryCode must be provided. Only Code is accepted on confirmation.
+type TwoFactorCodePayload struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// Two-Factor Login godoc
+//
+//	@Summary		Completes a two-factor login
+//	@Description	Exchanges the challenge returned by /token, along with a TOTP code or a recovery code, for the tokens. Each challenge can only be used once.
+//	@Tags			authorization
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body		TwoFactorLoginPayload	true	"Challenge and code"
+//	@Success		201		{object}	TokenResponse			"Token"
+//	@Failure		500		{object}	error					"Something went wrong on the server"
+//	@Failure		401		{object}	error					"Challenge or code is invalid"
+//	@Failure		423		{object}	error					"Second factor locked after too many invalid codes"
+//	@Failure		429		{object}	error					"Too many failed logins from the IP"
+//	@Failure		400		{object}	error					"Some parameter was either not provided or invalid."
+//	@Router			/token/2fa [post]
+func (app *Application) handlerTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	in := TwoFactorLoginPayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when completing a two-factor login: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	{ // Validate input
+		if in.Challenge == "" {
+			err := errors.New("challenge is required")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if in.Code == "" && in.RecoveryCode == "" {
+			err := errors.New("code or recovery_code is required")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+	}
+
+	challenge, err := base64.URLEncoding.DecodeString(in.Challenge)
+	if err != nil {
+		err := fmt.Errorf("error decoding challenge: %v", err)
+		app.respondWithError(w, r, http.StatusBadRequest, err, "invalid challenge")
+		return
+	}
+
+	userID, err := app.Storage.TwoFactor.ConsumeChallenge(ctx, challenge)
+	if err != nil {
+		switch err {
+		case storage.ErrNoToken:
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		default:
+			err = fmt.Errorf("error consuming two-factor challenge: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	user, err := app.Storage.Users.GetByID(ctx, userID)
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		default:
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	// Brute-force protection, a challenge is single use but the password gives new ones
+	ip := clientIP(r)
+	if !app.checkTwoFactorLock(w, r, user, ip) {
+		return
+	}
+
+	ok, err := app.verifySecondFactor(ctx, user, in.Code, in.RecoveryCode)
+	if err != nil {
+		err = fmt.Errorf("error verifying second factor: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if !ok {
+		app.recordTwoFactorFailure(ctx, r, user, ip)
+		err := fmt.Errorf("invalid second factor for user %s", user.Username)
+		app.respondWithError(w, r, http.StatusUnauthorized, err, "Unauthorized")
+		return
+	}
+	app.recordTwoFactorSuccess(ctx, user)
+
+	out, err := app.createTokens(ctx, r, user)
+	if err != nil {
+		err = fmt.Errorf("error creating tokens: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	app.respondWithJSON(w, r, http.StatusCreated, out)
+}
+
+// Enroll Two-Factor godoc
+//
+//	@Summary		Starts the two-factor enrollment
+//	@Description	Creates a TOTP secret for the logged user. The provisioning URI can be rendered as a QR code for authenticator apps. Two-factor is not enabled until it is confirmed with a code.
+//	@Tags			authorization
+//	@Produce		json
+//	@Success		201	{object}	TwoFactorEnrollResponse	"Secret"
+//	@Failure		401	{object}	error					"Unauthorized"
+//	@Failure		409	{object}	error					"Two-factor is already enabled"
+//	@Failure		500	{object}	error					"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/2fa [post]
+func (app *Application) handlerEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	sealed, err := app.Secrets.Seal(secret)
+	if err != nil {
+		err = fmt.Errorf("error encrypting TOTP secret: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if err := app.Storage.TwoFactor.Enroll(ctx, user.ID, sealed); err != nil {
+		switch err {
+		case storage.ErrConflict:
+			app.respondWithError(w, r, http.StatusConflict, err, "two-factor authentication is already enabled")
+		default:
+			err = fmt.Errorf("error enrolling two-factor: %v", err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+
+	out := &TwoFactorEnrollResponse{
+		Secret:          auth.EncodeTOTPSecret(secret),
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, app.Config.Authentication.TwoFactor.Issuer, user.Email),
+	}
+
+	app.respondWithJSON(w, r, http.StatusCreated, out)
+}
+
+// Confirm Two-Factor godoc
+//
+//	@Summary		Enables two-factor
+//	@Description	Confirms the enrollment with a code of the authenticator app. The response holds the recovery codes, which are only shown once.
+//	@Tags			authorization
+//	@Accept			json
+//	@Produce		json
+//	@Param			Payload	body		TwoFactorCodePayload	true	"TOTP code"
+//	@Success		200		{object}	RecoveryCodesResponse	"Recovery codes"
+//	@Failure		400		{object}	error					"Code is missing or invalid"
+//	@Failure		401		{object}	error					"Unauthorized"
+//	@Failure		404		{object}	error					"Enrollment was not started"
+//	@Failure		409		{object}	error					"Two-factor is already enabled"
+//	@Failure		500		{object}	error					"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/2fa/confirm [post]
+func (app *Application) handlerConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	in := TwoFactorCodePayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when confirming two-factor: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if in.Code == "" {
+		err := errors.New("code is required")
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	totp, err := app.Storage.TwoFactor.Get(ctx, user.ID)
+	if err != nil {
+		switch err {
+		case storage.ErrNoRows:
+			app.respondWithError(w, r, http.StatusNotFound, err, "two-factor enrollment was not started")
+		default:
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		}
+		return
+	}
+	if totp.Enabled() {
+		err := fmt.Errorf("two-factor already enabled for user %s", user.Username)
+		app.respondWithError(w, r, http.StatusConflict, err, "two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := app.Secrets.Open(totp.Secret)
+	if err != nil {
+		err = fmt.Errorf("error decrypting TOTP secret of user %s: %v", user.Username, err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(secret, in.Code, time.Now())
+	if !ok {
+		err := errors.New("invalid code")
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	codes, hashedCodes, err := generateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if err := app.Storage.TwoFactor.Confirm(ctx, user.ID, step, hashedCodes); err != nil {
+		err = fmt.Errorf("error confirming two-factor: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if app.Config.Cache.Enabled {
+		app.Cache.Users.Delete(ctx, user.Username)
+	}
+	app.Logger.Infow("two-factor enabled", "username", user.Username)
+
+	app.respondWithJSON(w, r, http.StatusOK, &RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable Two-Factor godoc
+//
+//	@Summary		Disables two-factor
+//	@Description	Deletes the TOTP secret and the recovery codes of the logged user. It requires a valid code, and it is not allowed for roles which require two-factor.
+//	@Tags			authorization
+//	@Accept			json
+//	@Param			Payload	body	TwoFactorCodePayload	true	"TOTP code or recovery code"
+//	@Success		204		"Two-factor was disabled"
+//	@Failure		400		{object}	error	"Code is missing"
+//	@Failure		401		{object}	error	"Unauthorized or invalid code"
+//	@Failure		403		{object}	error	"Two-factor is required for the role"
+//	@Failure		404		{object}	error	"Two-factor is not enabled"
+//	@Failure		500		{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
+//	@Router			/me/2fa [delete]
+func (app *Application) handlerDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := getLoggedUser(r)
+
+	in := TwoFactorCodePayload{}
+	if err := readJSON(w, r, &in); err != nil {
+		err := fmt.Errorf("error reading JSON when disabling two-factor: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if in.Code == "" && in.RecoveryCode == "" {
+		err := errors.New("code or recovery_code is required")
+		app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	if app.twoFactorRequired(user) {
+		err := fmt.Errorf("two-factor is required for role %s", user.Role.Name)
+		app.respondWithError(w, r, http.StatusForbidden, err, "two-factor authentication is required for your role")
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		err := fmt.Errorf("two-factor is not enabled for user %s", user.Username)
+		app.respondWithError(w, r, http.StatusNotFound, err, "two-factor authentication is not enabled")
+		return
+	}
+
+	ok, err := app.verifySecondFactor(ctx, user, in.Code, in.RecoveryCode)
+	if err != nil {
+		err = fmt.Errorf("error verifying second factor: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+	if !ok {
+		err := fmt.Errorf("invalid second factor for user %s", user.Username)
+		app.respondWithError(w, r, http.StatusUnauthorized, err, "invalid code")
+		return
+	}
+
+	if err := app.Storage.TwoFactor.Disable(ctx, user.ID); err != nil {
+		err = fmt.Errorf("error disabling two-factor: %v", err)
+		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
+	}
+
+	if app.Config.Cache.Enabled {
+		app.Cache.Users.Delete(ctx, user.Username)
+	}
+	app.Logger.Infow("two-factor disabled", "username", user.Username)
+
+	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+}
+
+// Creates a challenge to be exchanged, along with a second factor, for the tokens of the user
+func (app *Application) createTwoFactorChallenge(ctx context.Context, user *models.User) (*TwoFactorChallengeResponse, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("error creating challenge: %v", err)
+	}
+
+	expiration := app.Config.Authentication.TwoFactor.ChallengeExpirationTime
+	if err := app.Storage.TwoFactor.CreateChallenge(ctx, user.ID, challenge, expiration); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		Challenge:         base64.URLEncoding.EncodeToString(challenge),
+		ExpiresIn:         int64(expiration.Seconds()),
+	}, nil
+}
+
+// Checks a TOTP code or, if it is empty, a recovery code. Both are single-use.
+func (app *Application) verifySecondFactor(ctx context.Context, user *models.User, code, recoveryCode string) (bool, error) {
+	if code == "" {
+		return app.Storage.TwoFactor.UseRecoveryCode(ctx, user.ID, normalizeRecoveryCode(recoveryCode))
+	}
+
+	totp, err := app.Storage.TwoFactor.Get(ctx, user.ID)
+	if err != nil {
+		if err == storage.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if !totp.Enabled() {
+		return false, nil
+	}
+
+	secret, err := app.Secrets.Open(totp.Secret)
+	if err != nil {
+		return false, fmt.Errorf("error decrypting TOTP secret: %v", err)
+	}
+
+	step, ok := auth.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	// NOTE(maolivera): A valid code is rejected if it (or a later one) was already used
+	return app.Storage.TwoFactor.UseStep(ctx, user.ID, step)
+}
+
+// Encrypts the TOTP secrets stored before they were encrypted. Secrets being replaced meanwhile are skipped, the
+// new ones are already encrypted.
+func (app *Application) EncryptTOTPSecrets(ctx context.Context) error {
+	totps, err := app.Storage.TwoFactor.ListBySecretSize(ctx, auth.TOTPSecretSize)
+	if err != nil {
+		return err
+	}
+
+	for _, totp := range totps {
+		sealed, err := app.Secrets.Seal(totp.Secret)
+		if err != nil {
+			return err
+		}
+		if _, err := app.Storage.TwoFactor.ReplaceSecret(ctx, totp.UserID, totp.Secret, sealed); err != nil {
+			return err
+		}
+	}
+	if len(totps) > 0 {
+		app.Logger.Infow("TOTP secrets encrypted", "secrets", len(totps))
+	}
+
+	return nil
+}
+
+// Reports if the role of the user requires two-factor authentication
+func (app *Application) twoFactorRequired(user *models.User) bool {
+	return slices.Contains(app.Config.Authentication.TwoFactor.RequiredRoles, user.Role.Name)
+}
+
+// Returns the codes to show to the user, formatted as "xxxxx-xxxxx", and their normalized values to be stored
+func generateRecoveryCodes(n int) ([]string, [][]byte, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, n)
+	normalized := make([][]byte, n)
+	for i := range n {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("error creating recovery code: %v", err)
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		normalized[i] = []byte(code)
+	}
+
+	return codes, normalized, nil
+}
+
+// Recovery codes are accepted regardless of case, dashes and spaces
+func normalizeRecoveryCode(code string) []byte {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return []byte(code)
+}
@@ -9,10 +9,13 @@ import (
 	"net/http"
 	"net/mail"
 	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/maxolivera/gophis-social-network/internal/mailer"
 	"github.com/maxolivera/gophis-social-network/internal/storage"
 	"github.com/maxolivera/gophis-social-network/internal/storage/models"
 )
@@ -23,20 +26,15 @@ type CreateUserPayload struct {
 	Password string `json:"password"`
 }
 
-type UserWithToken struct {
-	User            models.User `json:"user"`
-	ActivationToken string      `json:"activation_token"`
-}
-
 // Create User godoc
 //
 //	@Summary		Creates a User
-//	@Description	Creates a User
+//	@Description	Creates a User. The activation token is sent to the user email.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			Payload	body		CreateUserPayload	true	"User credentials"
-//	@Success		201		{object}	UserWithToken
+//	@Success		201		{object}	models.SelfUser
 //	@Failure		500		{object}	error	"Something went wrong on the server"
 //	@Failure		409		{object}	error	"Either email or username already taken"
 //	@Failure		400		{object}	error	"Some parameter was either not provided or invalid."
@@ -129,16 +127,17 @@ func (app *Application) handlerCreateUser(w http.ResponseWriter, r *http.Request
 	}
 
 	// 4. Send token
-	// TODO(maolivera): Send email instead of returning the user with the token
 	// TODO(maolivera): Check if this is the correct way of encoding and decoding token for URLs
 	encodedToken := base64.URLEncoding.EncodeToString(token)
-	out := UserWithToken{
-		User:            user.User,
-		ActivationToken: encodedToken,
-	}
+	app.sendEmail(user.User.Email, mailer.TemplateActivation, mailer.TokenData{
+		Username:  user.User.Username,
+		URL:       app.linkURL("/activate/%s", url.QueryEscape(encodedToken)),
+		Token:     encodedToken,
+		ExpiresIn: app.Config.ExpirationTime.String(),
+	})
 
 	// Send response
-	app.respondWithJSON(w, r, http.StatusCreated, out)
+	app.respondWithJSON(w, r, http.StatusCreated, user.User.SelfView())
 }
 
 // Activate User godoc
@@ -154,6 +153,7 @@ func (app *Application) handlerCreateUser(w http.ResponseWriter, r *http.Request
 //	@Failure		400	{object}	error	"Invalid token"
 //	@Failure		404	{object}	error	"Token not found"
 //	@Router			/activate/{token} [post]
+//	@Router			/activate/{token} [get]
 func (app *Application) handlerActivateUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
@@ -207,11 +207,11 @@ func (app *Application) handlerActivateUser(w http.ResponseWriter, r *http.Reque
 // Get User godoc
 //
 //	@Summary		Fetch a User
-//	@Description	Fetch a User
+//	@Description	Fetch a User. Other users only see its public profile, the user itself also sees its email and role, and staff with the users:read_private permission also see the permissions of its role.
 //	@Tags			users
 //	@Produce		json
 //	@Param			username	path		string	true	"Username"
-//	@Success		200			{object}	models.User
+//	@Success		200			{object}	models.AdminUser	"Fields depend on the view, see the description"
 //	@Failure		404			{object}	error	"User not found"
 //	@Failure		400			{object}	error	"Some parameter was either not provided or invalid."
 //	@Failure		500			{object}	error	"Something went wrong on the server"
@@ -219,52 +219,36 @@ func (app *Application) handlerActivateUser(w http.ResponseWriter, r *http.Reque
 //	@Security		ApiKeyAuth
 func (app *Application) handlerGetUser(w http.ResponseWriter, r *http.Request) {
 	user := getRouteUser(r)
-	app.respondWithJSON(w, r, http.StatusOK, user)
+	app.respondWithJSON(w, r, http.StatusOK, app.userView(r, user))
 }
 
-// Soft Delete User godoc
-//
-//	@Summary		Soft Deletes a User
-//	@Description	The user will be marked as "deleted" on the database, it will not appear nor it can be accessed, but it will not be deleted from the database
-//	@Tags			users
-//	@Produce		json
-//	@Param			username	path	string	true	"Username"
-//	@Success		204			"The user was deleted"
-//	@Failure		400			{object}	error	"Some parameter was either not provided or invalid."
-//	@Failure		404			{object}	error	"User not found"
-//	@Failure		500			{object}	error	"Something went wrong on the server"
-//	@Router			/users/{username} [delete]
-//	@Security		ApiKeyAuth
-func (app *Application) handlerSoftDeleteUser(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	user := getRouteUser(r)
+// Picks the representation of the user depending on who is asking: staff who can read private information get the
+// admin view, the user itself the self view and anyone else the public view
+func (app *Application) userView(r *http.Request, user *models.User) any {
+	viewer := getLoggedUser(r)
 
-	if err := app.Storage.Users.SoftDelete(ctx, user.ID); err != nil {
-		switch err {
-		case pgx.ErrNoRows:
-			err := fmt.Errorf("user was not deleted because was not found, user_id: %v", user.ID)
-			app.respondWithError(w, r, http.StatusNotFound, err, "user not found")
-		default:
-			err := fmt.Errorf("user could not deleted: %v", err)
-			app.respondWithError(w, r, http.StatusInternalServerError, err, "user could not be deleted")
-		}
-		return
+	if hasScope(r, models.ScopeAdmin) && app.hasPermission(viewer, models.PermissionUsersReadPrivate) {
+		return user.AdminView(app.rolePermissions(user.Role.Name))
 	}
-
-	app.respondWithJSON(w, r, http.StatusNoContent, nil)
+	if viewer.ID == user.ID {
+		return user.SelfView()
+	}
+	return user.PublicView()
 }
 
 // Hard Delete User godoc
 //
 //	@Summary		Hard Deletes a User
-//	@Description	The user will be deleted. Currently not used.
+//	@Description	The user will be deleted. Requires the users:hard_delete permission.
 //	@Tags			admin, users
 //	@Produce		json
 //	@Param			username	path	string	true	"Username"
 //	@Success		204			"The user was deleted"
 //	@Failure		400			{object}	error	"Some parameter was either not provided or invalid."
+//	@Failure		403			{object}	error	"Forbidden"
 //	@Failure		404			{object}	error	"User not found"
 //	@Failure		500			{object}	error	"Something went wrong on the server"
+//	@Security		ApiKeyAuth
 //	@Router			/users/{username}/hard [delete]
 func (app *Application) handlerHardDeleteUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -281,31 +265,51 @@ func (app *Application) handlerHardDeleteUser(w http.ResponseWriter, r *http.Req
 		}
 		return
 	}
+	app.Logger.Infow("user hard deleted", "username", user.Username, "by", getLoggedUser(r).Username)
+
+	if app.Config.Cache.Enabled {
+		app.Cache.Users.Delete(ctx, user.Username)
+	}
 
 	app.respondWithJSON(w, r, http.StatusNoContent, nil)
 }
 
 type UpdateUserPayload struct {
-	Email     string `json:"email,omitempty"`
-	Username  string `json:"username,omitempty"`
-	Password  string `json:"password,omitempty"`
-	FirstName string `json:"first_name,omitempty"`
-	LastName  string `json:"last_name,omitempty"`
+	// Not allowed, the email is changed with /me/email
+	Email    string `json:"email,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Required to change the password, which only the user itself can do
+	CurrentPassword string `json:"current_password,omitempty"`
+	FirstName       string `json:"first_name,omitempty"`
+	LastName        string `json:"last_name,omitempty"`
+	// Profile fields are cleared when empty, and do not change when missing
+	Bio      *string `json:"bio,omitempty"`
+	Website  *string `json:"website,omitempty"`
+	Location *string `json:"location,omitempty"`
+	Pronouns *string `json:"pronouns,omitempty"`
+	// URLs of the images
+	AvatarURL *string `json:"avatar_url,omitempty"`
+	BannerURL *string `json:"banner_url,omitempty"`
 }
 
 // Update User godoc
 //
 //	@Summary		Updates an User
-//	@Description	The user will be updated.
+//	@Description	The user will be updated. Only the user itself, or staff with the users:update_any permission, can update it. The email can not be changed here, it must be confirmed first (see /me/email). Changing the password requires the current one and closes every session.
 //	@Tags			users
 //	@Accept			json
 //	@Produce		json
 //	@Param			username	path		string				true	"Username of user to be modified"
 //	@Param			Payload		body		UpdateUserPayload	false	"New parameters for user"
-//	@Success		200			{object}	models.User			"New User"
+//	@Success		200			{object}	models.AdminUser	"New User, fields depend on the view like on GET"
 //	@Failure		404			{object}	error				"User not found"
 //	@Failure		400			{object}	error				"Some parameter was either not provided or invalid."
-//	@Failure		409			{object}	error				"Either email or username already taken"
+//	@Failure		401			{object}	error				"Invalid current password"
+//	@Failure		403			{object}	error				"Forbidden"
+//	@Failure		409			{object}	error				"Username already taken"
+//	@Failure		423			{object}	error				"Password checks locked after too many invalid passwords"
+//	@Failure		429			{object}	error				"Too many failed logins from the IP"
 //	@Failure		500			{object}	error				"Something went wrong on the server"
 //	@Router			/users/{username} [patch]
 //	@Security		ApiKeyAuth
@@ -317,6 +321,7 @@ func (app *Application) handlerUpdateUser(w http.ResponseWriter, r *http.Request
 	if err := readJSON(w, r, &in); err != nil {
 		err = fmt.Errorf("error reading input parameters: %v", err)
 		app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+		return
 	}
 
 	// TODO(maolivera): Split update password to its own endpoint
@@ -339,14 +344,9 @@ func (app *Application) handlerUpdateUser(w http.ResponseWriter, r *http.Request
 			return
 		}
 		// Email
-		if len(in.Email) > 255 {
-			err := errors.New("email is too long")
-			app.respondWithError(w, r, http.StatusBadRequest, err, "email is too long")
-			return
-		}
-		if _, err := mail.ParseAddress(in.Email); err != nil {
-			err := fmt.Errorf("email is invalid: %v", err)
-			app.respondWithError(w, r, http.StatusBadRequest, err, "email is invalid")
+		if in.Email != "" {
+			err := errors.New("email can not be updated directly")
+			app.respondWithError(w, r, http.StatusBadRequest, err, "email must be changed with /me/email")
 			return
 		}
 		// Password
@@ -355,16 +355,38 @@ func (app *Application) handlerUpdateUser(w http.ResponseWriter, r *http.Request
 			app.respondWithError(w, r, http.StatusBadRequest, err, "password is too long")
 			return
 		}
-		if len(in.Password) < 3 {
+		if in.Password != "" && len(in.Password) < 3 {
 			err := errors.New("password is too short")
 			app.respondWithError(w, r, http.StatusBadRequest, err, "password is too short")
 			return
 		}
+		// Profile
+		if err := validateProfile(&in); err != nil {
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+	}
+
+	// NOTE(maolivera): Staff can not set passwords, users who lost theirs reset them by email
+	if in.Password != "" {
+		if getLoggedUser(r).ID != user.ID {
+			err := fmt.Errorf("user %s tried to change the password of user %s", getLoggedUser(r).Username, user.Username)
+			app.respondWithError(w, r, http.StatusForbidden, err, "only the user can change its password")
+			return
+		}
+		if in.CurrentPassword == "" {
+			err := errors.New("current password is required to change the password")
+			app.respondWithError(w, r, http.StatusBadRequest, err, err.Error())
+			return
+		}
+		if !app.checkUserPassword(w, r, user, in.CurrentPassword, "invalid current password") {
+			return
+		}
 	}
 
 	newUser := &models.UserWithPassword{
 		User: models.User{
-			Email:     in.Email,
+			ID:        user.ID,
 			Username:  in.Username,
 			FirstName: in.FirstName,
 			LastName:  in.LastName,
@@ -372,17 +394,23 @@ func (app *Application) handlerUpdateUser(w http.ResponseWriter, r *http.Request
 		Password: in.Password,
 	}
 
-	changedUser, err := app.Storage.Users.Update(ctx, newUser)
+	profile := &models.ProfileChanges{
+		Bio:       in.Bio,
+		Website:   in.Website,
+		Location:  in.Location,
+		Pronouns:  in.Pronouns,
+		AvatarURL: in.AvatarURL,
+		BannerURL: in.BannerURL,
+	}
+
+	changedUser, err := app.Storage.Users.Update(ctx, newUser, profile)
 	if err != nil {
 		switch err {
 		case storage.ErrUsernameUnavailable:
 			err = fmt.Errorf("%v, username: %s", err, newUser.User.Username)
 			app.respondWithError(w, r, http.StatusConflict, err, "username is not available")
-		case storage.ErrEmailUnavailable:
-			err = fmt.Errorf("%v, email: %s", err, newUser.User.Email)
-			app.respondWithError(w, r, http.StatusConflict, err, "email is not available")
 		default:
-			err = fmt.Errorf("error during user creation: %v", err)
+			err = fmt.Errorf("error during user update: %v", err)
 			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
 		}
 		return
@@ -392,5 +420,56 @@ func (app *Application) handlerUpdateUser(w http.ResponseWriter, r *http.Request
 		app.Cache.Users.Delete(r.Context(), user.Username)
 	}
 
-	app.respondWithJSON(w, r, http.StatusOK, changedUser)
+	if in.Password != "" {
+		// Close every session, a stolen one must not outlive the password
+		if err := app.revokeUserTokens(ctx, user); err != nil {
+			err = fmt.Errorf("password was changed but tokens of user %s could not be revoked: %v", user.Username, err)
+			app.respondWithError(w, r, http.StatusInternalServerError, err, "")
+			return
+		}
+		app.Logger.Infow("password changed", "username", user.Username)
+
+		app.sendNotification(user.Email, user.Username,
+			"Your Gophis Social password was changed",
+			"The password of your account was changed, and every session was closed. If it was not you, reset your password.",
+		)
+	}
+
+	app.respondWithJSON(w, r, http.StatusOK, app.userView(r, changedUser))
+}
+
+// Trims the profile fields of the payload and checks them, returning an error which can be shown to the user
+func validateProfile(in *UpdateUserPayload) error {
+	fields := []struct {
+		name      string
+		value     *string
+		maxLength int
+		isURL     bool
+	}{
+		{"bio", in.Bio, 300, false},
+		{"website", in.Website, 255, true},
+		{"location", in.Location, 100, false},
+		{"pronouns", in.Pronouns, 40, false},
+		{"avatar_url", in.AvatarURL, 255, true},
+		{"banner_url", in.BannerURL, 255, true},
+	}
+
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		*field.value = strings.TrimSpace(*field.value)
+
+		if utf8.RuneCountInString(*field.value) > field.maxLength {
+			return fmt.Errorf("%s is too long, max %d characters", field.name, field.maxLength)
+		}
+		if field.isURL && *field.value != "" {
+			u, err := url.Parse(*field.value)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%s must be an http or https URL", field.name)
+			}
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestCreateUserSendsActivationEmail(t *testing.T) {
+	tests := []struct {
+		name        string
+		frontendUrl string
+		linkPrefix  string
+	}{
+		{"without frontend", "", testApiUrl + "/v1/activate/"},
+		{"with frontend", "http://app.test", "http://app.test/activate/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mail := newTestApplication(t)
+			app.Config.FrontendUrl = tt.frontendUrl
+			users := newFakeUserRepository()
+			app.Storage.Users = users
+
+			body := `{"username": "gopher", "email": "gopher@example.com", "password": "secret"}`
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/v1/register", strings.NewReader(body))
+			app.handlerCreateUser(w, r)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+			}
+
+			emails := sentEmails(app, mail)
+			if len(emails) != 1 {
+				t.Fatalf("expected 1 email, got %d", len(emails))
+			}
+			email := emails[0]
+			if email.To != "gopher@example.com" {
+				t.Errorf("expected email to gopher@example.com, got %s", email.To)
+			}
+
+			token := base64.URLEncoding.EncodeToString(users.invitations["gopher@example.com"])
+			link := tt.linkPrefix + url.QueryEscape(token)
+			if !strings.Contains(email.Text, link) {
+				t.Errorf("expected link %s in email, got:\n%s", link, email.Text)
+			}
+			if !strings.Contains(email.Text, token) {
+				t.Errorf("expected token %s in email, got:\n%s", token, email.Text)
+			}
+		})
+	}
+}
+
+func TestActivationLinkCanBeOpened(t *testing.T) {
+	app, mail := newTestApplication(t)
+	app.Storage.Users = newFakeUserRepository()
+
+	body := `{"username": "gopher", "email": "gopher@example.com", "password": "secret"}`
+	w := httptest.NewRecorder()
+	app.handlerCreateUser(w, httptest.NewRequest(http.MethodPost, "/v1/register", strings.NewReader(body)))
+
+	emails := sentEmails(app, mail)
+	if len(emails) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(emails))
+	}
+	link, err := url.Parse(emailLink(emails[0].Text, testApiUrl))
+	if err != nil || link.Path == "" {
+		t.Fatalf("could not parse link of email: %v", err)
+	}
+
+	// Opening the link sends a GET
+	routes := app.GetHandlers().(chi.Routes)
+	if !routes.Match(chi.NewRouteContext(), http.MethodGet, link.Path) {
+		t.Errorf("expected GET %s to be routed", link.Path)
+	}
+}
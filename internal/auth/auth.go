@@ -5,4 +5,6 @@ import "github.com/golang-jwt/jwt/v5"
 type Authenticator interface {
 	GenerateToken(claims jwt.Claims) (string, error)
 	ValidateToken(token string) (*jwt.Token, error)
+	// Public keys which can verify the generated tokens
+	JWKS() JWKSet
 }
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Encrypts secrets which must be read back, unlike passwords and tokens which are only compared against their
+// hash. It uses AES-256-GCM, so tampered values are rejected.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// Any key works, it is hashed to get the AES key. Values sealed with a key can only be opened with the same one.
+func NewSecretBox(key string) (*SecretBox, error) {
+	if key == "" {
+		return nil, errors.New("encryption key is empty")
+	}
+
+	hashed := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(hashed[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretBox{aead: aead}, nil
+}
+
+// Encrypts the value, prefixing it with a random nonce
+func (b *SecretBox) Seal(value []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(value)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error creating nonce: %v", err)
+	}
+	return b.aead.Seal(nonce, nonce, value, nil), nil
+}
+
+// Decrypts a value returned by Seal
+func (b *SecretBox) Open(sealed []byte) ([]byte, error) {
+	if len(sealed) < b.aead.NonceSize()+b.aead.Overhead() {
+		return nil, errors.New("sealed value is too short")
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	return b.aead.Open(nil, nonce, ciphertext, nil)
+}
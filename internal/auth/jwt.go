@@ -1,15 +1,23 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Signs tokens with HS256 and Secret, unless it is created with NewJWTAuthenticatorWithKeys, in that case
+// tokens are signed with the asymmetric signing key and verified with any of the verification keys.
 type JWTAuthenticator struct {
 	Secret   string
 	Audience string
 	Issuer   string
+
+	signingKey *Key
+	// Verification keys, by key ID and in the order they were provided
+	verificationKeys map[string]*Key
+	keyIDs           []string
 }
 
 func NewJWTAuthenticator(secret, audience, issuer string) *JWTAuthenticator {
@@ -20,7 +28,38 @@ func NewJWTAuthenticator(secret, audience, issuer string) *JWTAuthenticator {
 	}
 }
 
+// The signing key is always accepted for verification. Extra verification keys allow to keep validating
+// tokens signed with previous keys while rotating them.
+func NewJWTAuthenticatorWithKeys(signingKey *Key, verificationKeys []*Key, audience, issuer string) (*JWTAuthenticator, error) {
+	if signingKey == nil || signingKey.Private == nil {
+		return nil, errors.New("signing key must hold a private key")
+	}
+
+	a := &JWTAuthenticator{
+		Audience:         audience,
+		Issuer:           issuer,
+		signingKey:       signingKey,
+		verificationKeys: make(map[string]*Key, len(verificationKeys)+1),
+	}
+	for _, key := range append([]*Key{signingKey}, verificationKeys...) {
+		if _, ok := a.verificationKeys[key.ID]; ok {
+			continue
+		}
+		a.verificationKeys[key.ID] = key
+		a.keyIDs = append(a.keyIDs, key.ID)
+	}
+
+	return a, nil
+}
+
 func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
+	if a.signingKey != nil {
+		token := jwt.NewWithClaims(a.signingKey.Method, claims)
+		token.Header["kid"] = a.signingKey.ID
+
+		return token.SignedString(a.signingKey.Private)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	tokenString, err := token.SignedString([]byte(a.Secret))
@@ -32,6 +71,15 @@ func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
 }
 
 func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
+	if a.signingKey != nil {
+		return jwt.Parse(token, a.keyFunc,
+			jwt.WithExpirationRequired(),
+			jwt.WithAudience(a.Audience),
+			jwt.WithIssuer(a.Issuer),
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		)
+	}
+
 	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
@@ -45,3 +93,32 @@ func (a *JWTAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
 		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
 	)
 }
+
+// Picks the verification key from the `kid` header
+func (a *JWTAuthenticator) keyFunc(t *jwt.Token) (any, error) {
+	kid, ok := t.Header["kid"].(string)
+	if !ok {
+		return nil, errors.New("key ID (kid) not found in token header")
+	}
+
+	key, ok := a.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %s", kid)
+	}
+
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v for key %s", t.Header["alg"], kid)
+	}
+
+	return key.Public, nil
+}
+
+// Public keys which can verify tokens. Empty when signing with a shared secret.
+func (a *JWTAuthenticator) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(a.keyIDs))}
+	for _, id := range a.keyIDs {
+		set.Keys = append(set.Keys, a.verificationKeys[id].JWK())
+	}
+
+	return set
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Asymmetric key used to sign or verify tokens. Private is nil for verification-only keys.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// Public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Loads a RSA or Ed25519 key from a PEM file. The file may hold either a private key (PKCS #1 or PKCS #8)
+// or a public key (PKIX or PKCS #1). RSA keys are used with RS256 and Ed25519 keys with EdDSA.
+func LoadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file %s: %v", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found on %s", path)
+	}
+
+	var private crypto.PrivateKey
+	var public crypto.PublicKey
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		public, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q on %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse key %s: %v", path, err)
+	}
+
+	key := &Key{Private: private, Public: public}
+	switch k := private.(type) {
+	case nil:
+	case *rsa.PrivateKey:
+		key.Public = &k.PublicKey
+	case ed25519.PrivateKey:
+		key.Public = k.Public()
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T on %s", k, path)
+	}
+
+	switch key.Public.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T on %s", key.Public, path)
+	}
+
+	key.ID, err = key.thumbprint()
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// Returns the public part of the key as a JWK
+func (k *Key) JWK() JWK {
+	jwk := JWK{
+		Use: "sig",
+		Alg: k.Method.Alg(),
+		Kid: k.ID,
+	}
+
+	switch public := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
+
+// Key ID derived from the JWK Thumbprint (RFC 7638), so every instance loading the same key agrees on it
+func (k *Key) thumbprint() (string, error) {
+	jwk := k.JWK()
+
+	// NOTE(maolivera): Members must be in lexicographic order, which encoding/json does for maps
+	var members map[string]string
+	switch jwk.Kty {
+	case "RSA":
+		members = map[string]string{"e": jwk.E, "kty": jwk.Kty, "n": jwk.N}
+	case "OKP":
+		members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X}
+	default:
+		return "", errors.New("unsupported key type for thumbprint")
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
@@ -3,6 +3,8 @@ package cache
 import (
 	"context"
 	"errors"
+	"sync"
+	"time"
 
 	"github.com/maxolivera/gophis-social-network/internal/storage/models"
 	"github.com/maxolivera/gophis-social-network/pkg/lru"
@@ -10,7 +12,8 @@ import (
 
 func NewLRUStorage(c *lru.LRUCache) *Storage {
 	return &Storage{
-		Users: &UserLRUCache{c},
+		Users:       &UserLRUCache{c},
+		Revocations: NewRevocationMemoryStore(),
 	}
 }
 
@@ -19,7 +22,7 @@ type UserLRUCache struct {
 }
 
 func (u UserLRUCache) Get(ctx context.Context, username string) (*models.User, error) {
-	key := "user-" + username
+	key := userKey(username)
 	value, found := u.c.Get(ctx, key)
 	if !found {
 		return nil, nil
@@ -34,16 +37,96 @@ func (u UserLRUCache) Get(ctx context.Context, username string) (*models.User, e
 }
 
 func (u UserLRUCache) Set(ctx context.Context, user *models.User) error {
-	key := "user-" + user.Username
+	key := userKey(user.Username)
 	u.c.Set(ctx, key, user)
 	return nil
 }
 
 func (u UserLRUCache) Delete(ctx context.Context, username string) {
-	key := "user-" + username
+	key := userKey(username)
 	u.c.Delete(ctx, key)
 }
 
 func (u UserLRUCache) Len(ctx context.Context) int {
 	return u.c.Len(ctx)
 }
+
+// Revocations kept in memory. They are never evicted, otherwise a revoked token would be accepted again, and
+// are only dropped once they expire.
+// NOTE(maolivera): Revocations made on an instance are not seen by the others, use Redis with more than one
+type RevocationMemoryStore struct {
+	mu         sync.Mutex
+	tokens     map[string]time.Time // Expiration, by token ID or session ID
+	users      map[string]revokedUser
+	lastPruned time.Time
+}
+
+type revokedUser struct {
+	until      time.Time
+	expiration time.Time
+}
+
+func NewRevocationMemoryStore() *RevocationMemoryStore {
+	return &RevocationMemoryStore{
+		tokens:     make(map[string]time.Time),
+		users:      make(map[string]revokedUser),
+		lastPruned: time.Now(),
+	}
+}
+
+func (r *RevocationMemoryStore) Revoke(ctx context.Context, id string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune()
+	r.tokens[id] = time.Now().Add(ttl)
+	return nil
+}
+
+func (r *RevocationMemoryStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expiration, found := r.tokens[id]
+	return found && time.Now().Before(expiration), nil
+}
+
+func (r *RevocationMemoryStore) RevokeUser(ctx context.Context, userID string, until time.Time, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prune()
+	r.users[userID] = revokedUser{until: until, expiration: time.Now().Add(ttl)}
+	return nil
+}
+
+func (r *RevocationMemoryStore) RevokedUntil(ctx context.Context, userID string) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revoked, found := r.users[userID]
+	if !found || time.Now().After(revoked.expiration) {
+		return time.Time{}, nil
+	}
+	return revoked.until, nil
+}
+
+// Drops the expired revocations, at most once per UserTimeExpiration. Must be called holding the lock.
+func (r *RevocationMemoryStore) prune() {
+	now := time.Now()
+	if now.Sub(r.lastPruned) < UserTimeExpiration {
+		return
+	}
+	r.lastPruned = now
+
+	for id, expiration := range r.tokens {
+		if now.After(expiration) {
+			delete(r.tokens, id)
+		}
+	}
+	for userID, revoked := range r.users {
+		if now.After(revoked.expiration) {
+			delete(r.users, userID)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRevocationMemoryStoreKeepsEveryRevocation(t *testing.T) {
+	ctx := context.Background()
+	store := NewRevocationMemoryStore()
+
+	// More than any LRU capacity, none must be forgotten before it expires
+	for i := 0; i < 200_000; i++ {
+		store.Revoke(ctx, fmt.Sprint(i), time.Hour)
+	}
+
+	for _, id := range []string{"0", "100000", "199999"} {
+		if revoked, _ := store.IsRevoked(ctx, id); !revoked {
+			t.Errorf("expected %s to be revoked", id)
+		}
+	}
+	if revoked, _ := store.IsRevoked(ctx, "200000"); revoked {
+		t.Error("expected 200000 not to be revoked")
+	}
+}
+
+func TestRevocationMemoryStoreExpires(t *testing.T) {
+	ctx := context.Background()
+	store := NewRevocationMemoryStore()
+	until := time.Now().Truncate(time.Second)
+
+	store.Revoke(ctx, "expired", -time.Second)
+	store.RevokeUser(ctx, "expired", until, -time.Second)
+	store.RevokeUser(ctx, "user", until, time.Hour)
+
+	if revoked, _ := store.IsRevoked(ctx, "expired"); revoked {
+		t.Error("expected expired revocation to be ignored")
+	}
+	if revokedUntil, _ := store.RevokedUntil(ctx, "expired"); !revokedUntil.IsZero() {
+		t.Errorf("expected expired user revocation to be ignored, got %v", revokedUntil)
+	}
+	if revokedUntil, _ := store.RevokedUntil(ctx, "user"); !revokedUntil.Equal(until) {
+		t.Errorf("expected user revoked until %v, got %v", until, revokedUntil)
+	}
+
+	// Expired revocations are dropped on the next write once the prune interval elapsed
+	store.lastPruned = time.Now().Add(-2 * UserTimeExpiration)
+	store.Revoke(ctx, "other", time.Hour)
+	if _, found := store.tokens["expired"]; found {
+		t.Error("expected expired revocation to be pruned")
+	}
+	if _, found := store.users["expired"]; found {
+		t.Error("expected expired user revocation to be pruned")
+	}
+}
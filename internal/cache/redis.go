@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/maxolivera/gophis-social-network/internal/storage/models"
 	"github.com/redis/go-redis/v9"
@@ -20,7 +22,8 @@ func NewRedisClient(address, password string, db int) *redis.Client {
 
 func NewRedisStorage(r *redis.Client) *Storage {
 	return &Storage{
-		Users: &UserRedisStore{r},
+		Users:       &UserRedisStore{r},
+		Revocations: &RevocationRedisStore{r},
 	}
 }
 
@@ -29,7 +32,7 @@ type UserRedisStore struct {
 }
 
 func (s UserRedisStore) Get(ctx context.Context, username string) (*models.User, error) {
-	key := fmt.Sprintf("user-%s", username)
+	key := userKey(username)
 
 	data, err := s.r.Get(ctx, key).Result()
 	if err == redis.Nil {
@@ -54,7 +57,7 @@ func (s UserRedisStore) Set(ctx context.Context, user *models.User) error {
 	if user.Username == "" {
 		return errors.New("username is empty")
 	}
-	key := fmt.Sprintf("user-%s", user.Username)
+	key := userKey(user.Username)
 
 	json, err := json.Marshal(user)
 	if err != nil {
@@ -65,7 +68,7 @@ func (s UserRedisStore) Set(ctx context.Context, user *models.User) error {
 }
 
 func (s UserRedisStore) Delete(ctx context.Context, username string) {
-	key := fmt.Sprintf("user-%s", username)
+	key := userKey(username)
 	s.r.Del(ctx, key)
 }
 
@@ -76,3 +79,47 @@ func (s UserRedisStore) Len(ctx context.Context) int {
 	}
 	return int(count)
 }
+
+type RevocationRedisStore struct {
+	r *redis.Client
+}
+
+func (s RevocationRedisStore) Revoke(ctx context.Context, id string, ttl time.Duration) error {
+	key := fmt.Sprintf("revoked-token-%s", id)
+	return s.r.SetEx(ctx, key, 1, ttl).Err()
+}
+
+func (s RevocationRedisStore) IsRevoked(ctx context.Context, id string) (bool, error) {
+	key := fmt.Sprintf("revoked-token-%s", id)
+
+	count, err := s.r.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (s RevocationRedisStore) RevokeUser(ctx context.Context, userID string, until time.Time, ttl time.Duration) error {
+	key := fmt.Sprintf("revoked-user-%s", userID)
+	return s.r.SetEx(ctx, key, until.Unix(), ttl).Err()
+}
+
+func (s RevocationRedisStore) RevokedUntil(ctx context.Context, userID string) (time.Time, error) {
+	key := fmt.Sprintf("revoked-user-%s", userID)
+
+	data, err := s.r.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	unix, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(unix, 0), nil
+}
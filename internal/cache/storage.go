@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/maxolivera/gophis-social-network/internal/storage/models"
@@ -14,6 +15,24 @@ type Storage struct {
 		Delete(context.Context, string)
 		Len(context.Context) int
 	}
+	Revocations interface {
+		// Revokes a token ID (jti), or a session ID (sid), until `ttl` elapses, which should be the remaining
+		// lifetime of the tokens
+		Revoke(context.Context, string, time.Duration) error
+		// Reports if a token ID (jti), or a session ID (sid), was revoked
+		IsRevoked(context.Context, string) (bool, error)
+		// Revokes every token of a user issued until the given time
+		RevokeUser(context.Context, string, time.Time, time.Duration) error
+		// Returns the time until which the tokens of a user are revoked. Zero if none.
+		RevokedUntil(context.Context, string) (time.Time, error)
+	}
 }
 
 const UserTimeExpiration = time.Minute
+
+// Bumped whenever models.User changes, so users cached by older versions are not read as incomplete ones
+const userVersion = 3
+
+func userKey(username string) string {
+	return fmt.Sprintf("user-v%d-%s", userVersion, username)
+}
@@ -38,25 +38,33 @@ func (q *Queries) CreateCommentInPost(ctx context.Context, arg CreateCommentInPo
 }
 
 const getCommentsByPost = `-- name: GetCommentsByPost :many
-SELECT comments.post_id, comments.id, comments.content, users.username, users.email, users.first_name, comments.created_at
+SELECT comments.post_id, comments.id, comments.content, comments.user_id, users.username, comments.created_at
 FROM comments
 LEFT JOIN users ON comments.user_id = users.id
 WHERE comments.post_id = $1
+	AND NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.user_id = comments.user_id AND b.blocked_id = $2) OR (b.user_id = $2 AND b.blocked_id = comments.user_id)
+	)
 ORDER BY comments.created_at DESC
 `
 
+type GetCommentsByPostParams struct {
+	PostID   pgtype.UUID
+	ViewerID pgtype.UUID
+}
+
 type GetCommentsByPostRow struct {
 	PostID    pgtype.UUID
 	ID        pgtype.UUID
 	Content   string
+	UserID    pgtype.UUID
 	Username  pgtype.Text
-	Email     pgtype.Text
-	FirstName pgtype.Text
 	CreatedAt pgtype.Timestamp
 }
 
-func (q *Queries) GetCommentsByPost(ctx context.Context, postID pgtype.UUID) ([]GetCommentsByPostRow, error) {
-	rows, err := q.db.Query(ctx, getCommentsByPost, postID)
+func (q *Queries) GetCommentsByPost(ctx context.Context, arg GetCommentsByPostParams) ([]GetCommentsByPostRow, error) {
+	rows, err := q.db.Query(ctx, getCommentsByPost, arg.PostID, arg.ViewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -68,9 +76,8 @@ func (q *Queries) GetCommentsByPost(ctx context.Context, postID pgtype.UUID) ([]
 			&i.PostID,
 			&i.ID,
 			&i.Content,
+			&i.UserID,
 			&i.Username,
-			&i.Email,
-			&i.FirstName,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -82,3 +89,36 @@ func (q *Queries) GetCommentsByPost(ctx context.Context, postID pgtype.UUID) ([]
 	}
 	return items, nil
 }
+
+const getCommentsByUser = `-- name: GetCommentsByUser :many
+SELECT id, post_id, user_id, created_at, updated_at, content FROM comments
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetCommentsByUser(ctx context.Context, userID pgtype.UUID) ([]Comment, error) {
+	rows, err := q.db.Query(ctx, getCommentsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Comment
+	for rows.Next() {
+		var i Comment
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -13,19 +13,40 @@ import (
 
 const getUserFeed = `-- name: GetUserFeed :many
 SELECT
-	p.id, p.title, p.content, p.created_at, p.tags,
+	p.id, p.title, p.content, p.created_at, p.tags, p.edited_at,
 	author.id AS author_id, author.username, COUNT(c.id) AS comment_count
 FROM posts p
 LEFT JOIN comments c ON c.post_id = p.id
+	AND NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.user_id = c.user_id AND b.blocked_id = $1) OR (b.user_id = $1 AND b.blocked_id = c.user_id)
+	)
 LEFT JOIN users author ON p.user_id = author.id
-JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
-WHERE f.user_id = $1 OR p.user_id = $1
+WHERE (
+		p.user_id = $1
+		OR EXISTS (SELECT 1 FROM followers f WHERE f.user_id = p.user_id AND f.follower_id = $1)
+	)
+	AND p.deleted_at IS NULL
+	AND NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.user_id = p.user_id AND b.blocked_id = $1) OR (b.user_id = $1 AND b.blocked_id = p.user_id)
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM mutes m
+		WHERE m.user_id = $1
+			AND (m.expires_at IS NULL OR m.expires_at > $4)
+			AND (
+				(m.kind = 'user' AND m.muted_user_id = p.user_id)
+				OR (m.kind = 'tag' AND EXISTS (SELECT 1 FROM unnest(p.tags) t WHERE lower(t) = m.value))
+				OR (m.kind = 'keyword' AND (strpos(lower(p.title), m.value) > 0 OR strpos(lower(p.content), m.value) > 0))
+			)
+	)
 GROUP BY p.id, author.id, author.username
 ORDER BY
 	CASE
-		WHEN NOT $4::boolean THEN p.created_at END ASC,
+		WHEN NOT $5::boolean THEN p.created_at END ASC,
 	CASE
-		WHEN $4::boolean THEN p.created_at END DESC
+		WHEN $5::boolean THEN p.created_at END DESC
 LIMIT $2 OFFSET $3
 `
 
@@ -33,6 +54,7 @@ type GetUserFeedParams struct {
 	UserID pgtype.UUID
 	Limit  int32
 	Offset int32
+	Now    pgtype.Timestamp
 	Sort   bool
 }
 
@@ -42,6 +64,7 @@ type GetUserFeedRow struct {
 	Content      string
 	CreatedAt    pgtype.Timestamp
 	Tags         []string
+	EditedAt     pgtype.Timestamp
 	AuthorID     pgtype.UUID
 	Username     pgtype.Text
 	CommentCount int64
@@ -52,6 +75,7 @@ func (q *Queries) GetUserFeed(ctx context.Context, arg GetUserFeedParams) ([]Get
 		arg.UserID,
 		arg.Limit,
 		arg.Offset,
+		arg.Now,
 		arg.Sort,
 	)
 	if err != nil {
@@ -67,6 +91,7 @@ func (q *Queries) GetUserFeed(ctx context.Context, arg GetUserFeedParams) ([]Get
 			&i.Content,
 			&i.CreatedAt,
 			&i.Tags,
+			&i.EditedAt,
 			&i.AuthorID,
 			&i.Username,
 			&i.CommentCount,
@@ -11,9 +11,95 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const acceptFollowRequests = `-- name: AcceptFollowRequests :exec
+INSERT INTO followers(created_at, user_id, follower_id)
+SELECT created_at, user_id, requester_id
+FROM follow_requests
+WHERE user_id = $1
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) AcceptFollowRequests(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, acceptFollowRequests, userID)
+	return err
+}
+
+const canViewPosts = `-- name: CanViewPosts :one
+SELECT EXISTS (
+	SELECT 1 FROM users u
+	WHERE u.id = $1
+		AND (
+			NOT u.is_private
+			OR u.id = $2
+			OR EXISTS (SELECT 1 FROM followers f WHERE f.user_id = u.id AND f.follower_id = $2)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.user_id = u.id AND b.blocked_id = $2) OR (b.user_id = $2 AND b.blocked_id = u.id)
+		)
+)::boolean AS can_view
+`
+
+type CanViewPostsParams struct {
+	UserID   pgtype.UUID
+	ViewerID pgtype.UUID
+}
+
+// Public users, the viewer itself and the users followed by the viewer, unless any of them blocked the other
+func (q *Queries) CanViewPosts(ctx context.Context, arg CanViewPostsParams) (bool, error) {
+	row := q.db.QueryRow(ctx, canViewPosts, arg.UserID, arg.ViewerID)
+	var can_view bool
+	err := row.Scan(&can_view)
+	return can_view, err
+}
+
+const createFollowRequest = `-- name: CreateFollowRequest :exec
+INSERT INTO follow_requests(created_at, user_id, requester_id)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`
+
+type CreateFollowRequestParams struct {
+	CreatedAt   pgtype.Timestamp
+	UserID      pgtype.UUID
+	RequesterID pgtype.UUID
+}
+
+func (q *Queries) CreateFollowRequest(ctx context.Context, arg CreateFollowRequestParams) error {
+	_, err := q.db.Exec(ctx, createFollowRequest, arg.CreatedAt, arg.UserID, arg.RequesterID)
+	return err
+}
+
+const deleteFollowRequest = `-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests WHERE user_id = $1 AND requester_id = $2
+`
+
+type DeleteFollowRequestParams struct {
+	UserID      pgtype.UUID
+	RequesterID pgtype.UUID
+}
+
+func (q *Queries) DeleteFollowRequest(ctx context.Context, arg DeleteFollowRequestParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteFollowRequest, arg.UserID, arg.RequesterID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteFollowRequestsByUser = `-- name: DeleteFollowRequestsByUser :exec
+DELETE FROM follow_requests WHERE user_id = $1
+`
+
+func (q *Queries) DeleteFollowRequestsByUser(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteFollowRequestsByUser, userID)
+	return err
+}
+
 const followByID = `-- name: FollowByID :exec
 INSERT INTO followers(created_at, user_id, follower_id)
 VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
 `
 
 type FollowByIDParams struct {
@@ -27,6 +113,158 @@ func (q *Queries) FollowByID(ctx context.Context, arg FollowByIDParams) error {
 	return err
 }
 
+const isFollowing = `-- name: IsFollowing :one
+SELECT EXISTS (
+	SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2
+)::boolean AS following
+`
+
+type IsFollowingParams struct {
+	UserID     pgtype.UUID
+	FollowerID pgtype.UUID
+}
+
+func (q *Queries) IsFollowing(ctx context.Context, arg IsFollowingParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isFollowing, arg.UserID, arg.FollowerID)
+	var following bool
+	err := row.Scan(&following)
+	return following, err
+}
+
+const listFollowRequests = `-- name: ListFollowRequests :many
+SELECT u.id, u.username, r.created_at
+FROM follow_requests r
+JOIN users u ON r.requester_id = u.id
+WHERE r.user_id = $1 AND u.deleted_at IS NULL
+ORDER BY r.created_at DESC
+`
+
+type ListFollowRequestsRow struct {
+	ID        pgtype.UUID
+	Username  string
+	CreatedAt pgtype.Timestamp
+}
+
+func (q *Queries) ListFollowRequests(ctx context.Context, userID pgtype.UUID) ([]ListFollowRequestsRow, error) {
+	rows, err := q.db.Query(ctx, listFollowRequests, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFollowRequestsRow
+	for rows.Next() {
+		var i ListFollowRequestsRow
+		if err := rows.Scan(&i.ID, &i.Username, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowers = `-- name: ListFollowers :many
+SELECT u.id, u.username, f.created_at
+FROM followers f
+JOIN users u ON f.follower_id = u.id
+WHERE f.user_id = $2
+	AND u.deleted_at IS NULL
+	AND ($3::timestamp IS NULL OR (f.created_at, u.id) < ($3, $4::uuid))
+ORDER BY f.created_at DESC, u.id DESC
+LIMIT $1
+`
+
+type ListFollowersParams struct {
+	Limit    int32
+	UserID   pgtype.UUID
+	Before   pgtype.Timestamp
+	BeforeID pgtype.UUID
+}
+
+type ListFollowersRow struct {
+	ID        pgtype.UUID
+	Username  string
+	CreatedAt pgtype.Timestamp
+}
+
+// Keyset pagination, newest first. The cursor is the follow (and the ID of the user) where the previous page ended.
+func (q *Queries) ListFollowers(ctx context.Context, arg ListFollowersParams) ([]ListFollowersRow, error) {
+	rows, err := q.db.Query(ctx, listFollowers,
+		arg.Limit,
+		arg.UserID,
+		arg.Before,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFollowersRow
+	for rows.Next() {
+		var i ListFollowersRow
+		if err := rows.Scan(&i.ID, &i.Username, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFollowing = `-- name: ListFollowing :many
+SELECT u.id, u.username, f.created_at
+FROM followers f
+JOIN users u ON f.user_id = u.id
+WHERE f.follower_id = $2
+	AND u.deleted_at IS NULL
+	AND ($3::timestamp IS NULL OR (f.created_at, u.id) < ($3, $4::uuid))
+ORDER BY f.created_at DESC, u.id DESC
+LIMIT $1
+`
+
+type ListFollowingParams struct {
+	Limit    int32
+	UserID   pgtype.UUID
+	Before   pgtype.Timestamp
+	BeforeID pgtype.UUID
+}
+
+type ListFollowingRow struct {
+	ID        pgtype.UUID
+	Username  string
+	CreatedAt pgtype.Timestamp
+}
+
+// Keyset pagination, newest first. The cursor is the follow (and the ID of the user) where the previous page ended.
+func (q *Queries) ListFollowing(ctx context.Context, arg ListFollowingParams) ([]ListFollowingRow, error) {
+	rows, err := q.db.Query(ctx, listFollowing,
+		arg.Limit,
+		arg.UserID,
+		arg.Before,
+		arg.BeforeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFollowingRow
+	for rows.Next() {
+		var i ListFollowingRow
+		if err := rows.Scan(&i.ID, &i.Username, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const unfollowByID = `-- name: UnfollowByID :exec
 DELETE FROM followers WHERE user_id = $1 AND follower_id = $2
 `
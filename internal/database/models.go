@@ -8,6 +8,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AuditLog struct {
+	ID         pgtype.UUID
+	ActorID    pgtype.UUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Details    []byte
+	CreatedAt  pgtype.Timestamp
+}
+
+type Block struct {
+	UserID    pgtype.UUID
+	BlockedID pgtype.UUID
+	CreatedAt pgtype.Timestamp
+}
+
 type Comment struct {
 	ID        pgtype.UUID
 	PostID    pgtype.UUID
@@ -17,12 +33,81 @@ type Comment struct {
 	Content   string
 }
 
+type FollowRequest struct {
+	UserID      pgtype.UUID
+	RequesterID pgtype.UUID
+	CreatedAt   pgtype.Timestamp
+}
+
 type Follower struct {
 	UserID     pgtype.UUID
 	FollowerID pgtype.UUID
 	CreatedAt  pgtype.Timestamp
 }
 
+type LoginAttempt struct {
+	ID        pgtype.UUID
+	Email     string
+	Ip        string
+	UserAgent string
+	Outcome   string
+	CreatedAt pgtype.Timestamp
+}
+
+type LoginThrottle struct {
+	Key           string
+	Failures      int32
+	LastFailureAt pgtype.Timestamp
+	LockedUntil   pgtype.Timestamp
+}
+
+type Mute struct {
+	ID          pgtype.UUID
+	UserID      pgtype.UUID
+	Kind        string
+	MutedUserID pgtype.UUID
+	Value       string
+	CreatedAt   pgtype.Timestamp
+	ExpiresAt   pgtype.Timestamp
+}
+
+type OidcState struct {
+	StateHash    []byte
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    pgtype.Timestamp
+}
+
+type PasswordReset struct {
+	TokenHash []byte
+	UserID    pgtype.UUID
+	ExpiresAt pgtype.Timestamp
+}
+
+type PendingExport struct {
+	UserID    pgtype.UUID
+	ID        pgtype.UUID
+	StartedAt pgtype.Timestamp
+}
+
+type Permission struct {
+	Name        string
+	Description string
+}
+
+type PersonalAccessToken struct {
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	Name       string
+	TokenHash  []byte
+	Scopes     []string
+	CreatedAt  pgtype.Timestamp
+	ExpiresAt  pgtype.Timestamp
+	LastUsedAt pgtype.Timestamp
+	RevokedAt  pgtype.Timestamp
+}
+
 type Post struct {
 	ID        pgtype.UUID
 	CreatedAt pgtype.Timestamp
@@ -31,8 +116,29 @@ type Post struct {
 	Content   string
 	UserID    pgtype.UUID
 	Tags      []string
-	IsDeleted bool
 	Version   int32
+	DeletedAt pgtype.Timestamp
+	EditedAt  pgtype.Timestamp
+}
+
+type PostRevision struct {
+	PostID    pgtype.UUID
+	Version   int32
+	Title     string
+	Content   string
+	Tags      []string
+	CreatedAt pgtype.Timestamp
+}
+
+type RefreshToken struct {
+	ID        pgtype.UUID
+	FamilyID  pgtype.UUID
+	UserID    pgtype.UUID
+	TokenHash []byte
+	CreatedAt pgtype.Timestamp
+	ExpiresAt pgtype.Timestamp
+	UsedAt    pgtype.Timestamp
+	RevokedAt pgtype.Timestamp
 }
 
 type Role struct {
@@ -42,18 +148,67 @@ type Role struct {
 	Description string
 }
 
+type RolePermission struct {
+	RoleID     int32
+	Permission string
+}
+
+type Session struct {
+	ID         pgtype.UUID
+	UserID     pgtype.UUID
+	Ip         string
+	UserAgent  string
+	CreatedAt  pgtype.Timestamp
+	LastSeenAt pgtype.Timestamp
+	RevokedAt  pgtype.Timestamp
+}
+
+type TwoFactorChallenge struct {
+	TokenHash []byte
+	UserID    pgtype.UUID
+	ExpiresAt pgtype.Timestamp
+}
+
 type User struct {
-	ID        pgtype.UUID
-	CreatedAt pgtype.Timestamp
-	UpdatedAt pgtype.Timestamp
-	Username  string
-	Email     string
-	Password  []byte
-	FirstName pgtype.Text
-	LastName  pgtype.Text
-	IsDeleted bool
-	IsActive  bool
-	RoleID    int32
+	ID                  pgtype.UUID
+	CreatedAt           pgtype.Timestamp
+	UpdatedAt           pgtype.Timestamp
+	Username            string
+	Email               string
+	Password            []byte
+	FirstName           pgtype.Text
+	LastName            pgtype.Text
+	IsActive            bool
+	RoleID              int32
+	IsPrivate           bool
+	FollowerCount       int32
+	FollowingCount      int32
+	PostCount           int32
+	Bio                 pgtype.Text
+	Website             pgtype.Text
+	Location            pgtype.Text
+	Pronouns            pgtype.Text
+	AvatarUrl           pgtype.Text
+	BannerUrl           pgtype.Text
+	DeletionScheduledAt pgtype.Timestamp
+	DeletedAt           pgtype.Timestamp
+	PurgedAt            pgtype.Timestamp
+}
+
+type UserEmailChange struct {
+	TokenHash []byte
+	UserID    pgtype.UUID
+	NewEmail  string
+	ExpiresAt pgtype.Timestamp
+}
+
+type UserIdentity struct {
+	Provider    string
+	Subject     string
+	UserID      pgtype.UUID
+	Email       string
+	CreatedAt   pgtype.Timestamp
+	LastLoginAt pgtype.Timestamp
 }
 
 type UserInvitation struct {
@@ -61,3 +216,25 @@ type UserInvitation struct {
 	UserID    pgtype.UUID
 	ExpiresAt pgtype.Timestamp
 }
+
+type UserRecoveryCode struct {
+	CodeHash []byte
+	UserID   pgtype.UUID
+}
+
+type UserSuggestion struct {
+	UserID      pgtype.UUID
+	SuggestedID pgtype.UUID
+	Score       float64
+	MutualCount int32
+	SharedTags  int32
+	CreatedAt   pgtype.Timestamp
+}
+
+type UserTotp struct {
+	UserID       pgtype.UUID
+	Secret       []byte
+	CreatedAt    pgtype.Timestamp
+	ConfirmedAt  pgtype.Timestamp
+	LastUsedStep int64
+}
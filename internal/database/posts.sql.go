@@ -40,7 +40,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) error {
 }
 
 const getPostById = `-- name: GetPostById :one
-SELECT id, created_at, updated_at, title, content, user_id, tags, is_deleted, version FROM posts WHERE id = $1 AND is_deleted = false
+SELECT id, created_at, updated_at, title, content, user_id, tags, version, deleted_at, edited_at FROM posts WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetPostById(ctx context.Context, id pgtype.UUID) (Post, error) {
@@ -54,16 +54,18 @@ func (q *Queries) GetPostById(ctx context.Context, id pgtype.UUID) (Post, error)
 		&i.Content,
 		&i.UserID,
 		&i.Tags,
-		&i.IsDeleted,
 		&i.Version,
+		&i.DeletedAt,
+		&i.EditedAt,
 	)
 	return i, err
 }
 
 const getPostByUser = `-- name: GetPostByUser :many
-SELECT id, created_at, updated_at, title, content, user_id, tags, is_deleted, version FROM posts WHERE user_id = $1 AND is_deleted = false
+SELECT id, created_at, updated_at, title, content, user_id, tags, version, deleted_at, edited_at FROM posts WHERE user_id = $1 ORDER BY created_at
 `
 
+// Includes the soft deleted ones
 func (q *Queries) GetPostByUser(ctx context.Context, userID pgtype.UUID) ([]Post, error) {
 	rows, err := q.db.Query(ctx, getPostByUser, userID)
 	if err != nil {
@@ -81,8 +83,9 @@ func (q *Queries) GetPostByUser(ctx context.Context, userID pgtype.UUID) ([]Post
 			&i.Content,
 			&i.UserID,
 			&i.Tags,
-			&i.IsDeleted,
 			&i.Version,
+			&i.DeletedAt,
+			&i.EditedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -94,7 +97,7 @@ func (q *Queries) GetPostByUser(ctx context.Context, userID pgtype.UUID) ([]Post
 	return items, nil
 }
 
-const hardDeletePostByID = `-- name: HardDeletePostByID :exec
+const hardDeletePostByID = `-- name: HardDeletePostByID :execrows
 DELETE FROM posts WHERE id = $1 and version = $2
 `
 
@@ -103,39 +106,163 @@ type HardDeletePostByIDParams struct {
 	Version int32
 }
 
-func (q *Queries) HardDeletePostByID(ctx context.Context, arg HardDeletePostByIDParams) error {
-	_, err := q.db.Exec(ctx, hardDeletePostByID, arg.ID, arg.Version)
-	return err
+func (q *Queries) HardDeletePostByID(ctx context.Context, arg HardDeletePostByIDParams) (int64, error) {
+	result, err := q.db.Exec(ctx, hardDeletePostByID, arg.ID, arg.Version)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-const softDeletePostByID = `-- name: SoftDeletePostByID :one
-UPDATE posts
-SET is_deleted = true
-WHERE id = $1 and version = $2
-RETURNING is_deleted
+const listDeletedPosts = `-- name: ListDeletedPosts :many
+SELECT p.id, p.created_at, p.updated_at, p.title, p.content, p.user_id, p.tags, p.version, p.deleted_at, p.edited_at FROM posts p
+JOIN users u ON p.user_id = u.id
+WHERE p.deleted_at IS NOT NULL
+	AND ($3::text IS NULL OR u.username = $3)
+	AND ($4::timestamp IS NULL OR p.deleted_at >= $4)
+	AND ($5::timestamp IS NULL OR p.deleted_at < $5)
+ORDER BY p.deleted_at DESC
+LIMIT $1 OFFSET $2
 `
 
-type SoftDeletePostByIDParams struct {
+type ListDeletedPostsParams struct {
+	Limit    int32
+	Offset   int32
+	Username pgtype.Text
+	Since    pgtype.Timestamp
+	Until    pgtype.Timestamp
+}
+
+func (q *Queries) ListDeletedPosts(ctx context.Context, arg ListDeletedPostsParams) ([]Post, error) {
+	rows, err := q.db.Query(ctx, listDeletedPosts,
+		arg.Limit,
+		arg.Offset,
+		arg.Username,
+		arg.Since,
+		arg.Until,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Content,
+			&i.UserID,
+			&i.Tags,
+			&i.Version,
+			&i.DeletedAt,
+			&i.EditedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockPost = `-- name: LockPost :one
+SELECT id, created_at, updated_at, title, content, user_id, tags, version, deleted_at, edited_at FROM posts
+WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+type LockPostParams struct {
 	ID      pgtype.UUID
 	Version int32
 }
 
-func (q *Queries) SoftDeletePostByID(ctx context.Context, arg SoftDeletePostByIDParams) (bool, error) {
-	row := q.db.QueryRow(ctx, softDeletePostByID, arg.ID, arg.Version)
-	var is_deleted bool
-	err := row.Scan(&is_deleted)
-	return is_deleted, err
+// Returns no rows if the post was deleted or edited meanwhile
+func (q *Queries) LockPost(ctx context.Context, arg LockPostParams) (Post, error) {
+	row := q.db.QueryRow(ctx, lockPost, arg.ID, arg.Version)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Content,
+		&i.UserID,
+		&i.Tags,
+		&i.Version,
+		&i.DeletedAt,
+		&i.EditedAt,
+	)
+	return i, err
+}
+
+const restorePost = `-- name: RestorePost :one
+UPDATE posts
+SET
+	updated_at = $1,
+	deleted_at = NULL
+WHERE id = $2 AND deleted_at IS NOT NULL
+RETURNING id, created_at, updated_at, title, content, user_id, tags, version, deleted_at, edited_at
+`
+
+type RestorePostParams struct {
+	UpdatedAt pgtype.Timestamp
+	ID        pgtype.UUID
+}
+
+func (q *Queries) RestorePost(ctx context.Context, arg RestorePostParams) (Post, error) {
+	row := q.db.QueryRow(ctx, restorePost, arg.UpdatedAt, arg.ID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Content,
+		&i.UserID,
+		&i.Tags,
+		&i.Version,
+		&i.DeletedAt,
+		&i.EditedAt,
+	)
+	return i, err
+}
+
+const softDeletePostByID = `-- name: SoftDeletePostByID :execrows
+UPDATE posts
+SET deleted_at = $3
+WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+`
+
+type SoftDeletePostByIDParams struct {
+	ID        pgtype.UUID
+	Version   int32
+	DeletedAt pgtype.Timestamp
+}
+
+func (q *Queries) SoftDeletePostByID(ctx context.Context, arg SoftDeletePostByIDParams) (int64, error) {
+	result, err := q.db.Exec(ctx, softDeletePostByID, arg.ID, arg.Version, arg.DeletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const updatePost = `-- name: UpdatePost :one
 UPDATE posts
 SET
 	updated_at = $1,
+	edited_at = $1,
+	version = version + 1,
 	title = coalesce($4, title),
 	content = coalesce($5, content),
 	tags = coalesce($6, tags)
-WHERE id = $2 AND is_deleted = false AND version = $3
-RETURNING id, created_at, updated_at, title, content, user_id, tags, is_deleted, version
+WHERE id = $2 AND deleted_at IS NULL AND version = $3
+RETURNING id, created_at, updated_at, title, content, user_id, tags, version, deleted_at, edited_at
 `
 
 type UpdatePostParams struct {
@@ -165,8 +292,9 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		&i.Content,
 		&i.UserID,
 		&i.Tags,
-		&i.IsDeleted,
 		&i.Version,
+		&i.DeletedAt,
+		&i.EditedAt,
 	)
 	return i, err
 }
@@ -9,6 +9,30 @@ import (
 	"context"
 )
 
+const createRole = `-- name: CreateRole :one
+INSERT INTO roles (name, level, description)
+VALUES ($1, $2, $3)
+RETURNING id, name, level, description
+`
+
+type CreateRoleParams struct {
+	Name        string
+	Level       int32
+	Description string
+}
+
+func (q *Queries) CreateRole(ctx context.Context, arg CreateRoleParams) (Role, error) {
+	row := q.db.QueryRow(ctx, createRole, arg.Name, arg.Level, arg.Description)
+	var i Role
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Level,
+		&i.Description,
+	)
+	return i, err
+}
+
 const getRoleByName = `-- name: GetRoleByName :one
 SELECT id, name, level, description FROM roles WHERE name = $1
 `
@@ -27,6 +51,7 @@ func (q *Queries) GetRoleByName(ctx context.Context, name string) (Role, error)
 
 const getRoles = `-- name: GetRoles :many
 SELECT id, name, level, description FROM roles
+ORDER BY level, name
 `
 
 func (q *Queries) GetRoles(ctx context.Context) ([]Role, error) {
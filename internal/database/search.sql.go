@@ -13,32 +13,58 @@ import (
 
 const searchPosts = `-- name: SearchPosts :many
 SELECT
-    p.id, p.title, p.content, p.created_at, p.tags,
+    p.id, p.title, p.content, p.created_at, p.tags, p.edited_at,
     author.id AS author_id, author.username, COUNT(c.id) AS comment_count
 FROM posts p
 LEFT JOIN comments c ON c.post_id = p.id
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks b
+        WHERE (b.user_id = c.user_id AND b.blocked_id = $3) OR (b.user_id = $3 AND b.blocked_id = c.user_id)
+    )
 LEFT JOIN users author ON p.user_id = author.id
 WHERE
-    ($3::text IS NULL OR p.content ILIKE '%' || $3 || '%' OR p.title ILIKE '%' || $3 || '%')
-    AND ($4::text[] IS NULL OR p.tags && $4)
-    AND ($5::timestamp IS NULL OR p.created_at >= $5)
-    AND ($6::timestamp IS NULL OR p.created_at <= $6)
+    p.deleted_at IS NULL
+    AND ($4::text IS NULL OR p.content ILIKE '%' || $4 || '%' OR p.title ILIKE '%' || $4 || '%')
+    AND ($5::text[] IS NULL OR p.tags && $5)
+    AND ($6::timestamp IS NULL OR p.created_at >= $6)
+    AND ($7::timestamp IS NULL OR p.created_at <= $7)
+    AND (
+        NOT author.is_private
+        OR author.id = $3
+        OR EXISTS (SELECT 1 FROM followers f WHERE f.user_id = author.id AND f.follower_id = $3)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks b
+        WHERE (b.user_id = author.id AND b.blocked_id = $3) OR (b.user_id = $3 AND b.blocked_id = author.id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes m
+        WHERE m.user_id = $3
+            AND (m.expires_at IS NULL OR m.expires_at > $8)
+            AND (
+                (m.kind = 'user' AND m.muted_user_id = p.user_id)
+                OR (m.kind = 'tag' AND EXISTS (SELECT 1 FROM unnest(p.tags) t WHERE lower(t) = m.value))
+                OR (m.kind = 'keyword' AND (strpos(lower(p.title), m.value) > 0 OR strpos(lower(p.content), m.value) > 0))
+            )
+    )
 GROUP BY p.id, author.id, author.username
 ORDER BY
-	CASE WHEN $7::boolean THEN p.created_at END DESC,
-	CASE WHEN NOT $7::boolean THEN p.created_at END ASC,
+	CASE WHEN $9::boolean THEN p.created_at END DESC,
+	CASE WHEN NOT $9::boolean THEN p.created_at END ASC,
 	comment_count DESC
 LIMIT $1 OFFSET $2
 `
 
 type SearchPostsParams struct {
-	Limit  int32
-	Offset int32
-	Search string
-	Tags   []string
-	Since  pgtype.Timestamp
-	Until  pgtype.Timestamp
-	Sort   bool
+	Limit    int32
+	Offset   int32
+	ViewerID pgtype.UUID
+	Search   string
+	Tags     []string
+	Since    pgtype.Timestamp
+	Until    pgtype.Timestamp
+	Now      pgtype.Timestamp
+	Sort     bool
 }
 
 type SearchPostsRow struct {
@@ -47,6 +73,7 @@ type SearchPostsRow struct {
 	Content      string
 	CreatedAt    pgtype.Timestamp
 	Tags         []string
+	EditedAt     pgtype.Timestamp
 	AuthorID     pgtype.UUID
 	Username     pgtype.Text
 	CommentCount int64
@@ -56,10 +83,12 @@ func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]Sea
 	rows, err := q.db.Query(ctx, searchPosts,
 		arg.Limit,
 		arg.Offset,
+		arg.ViewerID,
 		arg.Search,
 		arg.Tags,
 		arg.Since,
 		arg.Until,
+		arg.Now,
 		arg.Sort,
 	)
 	if err != nil {
@@ -75,6 +104,7 @@ func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]Sea
 			&i.Content,
 			&i.CreatedAt,
 			&i.Tags,
+			&i.EditedAt,
 			&i.AuthorID,
 			&i.Username,
 			&i.CommentCount,
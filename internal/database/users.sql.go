@@ -15,7 +15,7 @@ const activateUser = `-- name: ActivateUser :one
 UPDATE users
 SET is_active = true
 WHERE id = $1
-RETURNING id, created_at, updated_at, username, email, password, first_name, last_name, is_deleted, is_active, role_id
+RETURNING id, created_at, updated_at, username, email, password, first_name, last_name, is_active, role_id, is_private, follower_count, following_count, post_count, bio, website, location, pronouns, avatar_url, banner_url, deletion_scheduled_at, deleted_at, purged_at
 `
 
 func (q *Queries) ActivateUser(ctx context.Context, id pgtype.UUID) (User, error) {
@@ -30,13 +30,86 @@ func (q *Queries) ActivateUser(ctx context.Context, id pgtype.UUID) (User, error
 		&i.Password,
 		&i.FirstName,
 		&i.LastName,
-		&i.IsDeleted,
 		&i.IsActive,
 		&i.RoleID,
+		&i.IsPrivate,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.PostCount,
+		&i.Bio,
+		&i.Website,
+		&i.Location,
+		&i.Pronouns,
+		&i.AvatarUrl,
+		&i.BannerUrl,
+		&i.DeletionScheduledAt,
+		&i.DeletedAt,
+		&i.PurgedAt,
 	)
 	return i, err
 }
 
+const cancelUserDeletion = `-- name: CancelUserDeletion :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	deletion_scheduled_at = NULL
+WHERE id = $2 AND deleted_at IS NULL
+`
+
+type CancelUserDeletionParams struct {
+	UpdatedAt pgtype.Timestamp
+	ID        pgtype.UUID
+}
+
+func (q *Queries) CancelUserDeletion(ctx context.Context, arg CancelUserDeletionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, cancelUserDeletion, arg.UpdatedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const consumePasswordReset = `-- name: ConsumePasswordReset :one
+DELETE FROM password_resets
+WHERE token_hash = $1 AND expires_at > $2
+RETURNING user_id
+`
+
+type ConsumePasswordResetParams struct {
+	TokenHash []byte
+	ExpiresAt pgtype.Timestamp
+}
+
+func (q *Queries) ConsumePasswordReset(ctx context.Context, arg ConsumePasswordResetParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, consumePasswordReset, arg.TokenHash, arg.ExpiresAt)
+	var user_id pgtype.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
+const createEmailChange = `-- name: CreateEmailChange :exec
+INSERT INTO user_email_changes (token_hash, user_id, new_email, expires_at)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateEmailChangeParams struct {
+	TokenHash []byte
+	UserID    pgtype.UUID
+	NewEmail  string
+	ExpiresAt pgtype.Timestamp
+}
+
+func (q *Queries) CreateEmailChange(ctx context.Context, arg CreateEmailChangeParams) error {
+	_, err := q.db.Exec(ctx, createEmailChange,
+		arg.TokenHash,
+		arg.UserID,
+		arg.NewEmail,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
 const createInvitation = `-- name: CreateInvitation :exec
 INSERT INTO user_invitations (token, user_id, expires_at)
 VALUES ($1, $2, $3)
@@ -53,6 +126,22 @@ func (q *Queries) CreateInvitation(ctx context.Context, arg CreateInvitationPara
 	return err
 }
 
+const createPasswordReset = `-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (token_hash, user_id, expires_at)
+VALUES ($1, $2, $3)
+`
+
+type CreatePasswordResetParams struct {
+	TokenHash []byte
+	UserID    pgtype.UUID
+	ExpiresAt pgtype.Timestamp
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) error {
+	_, err := q.db.Exec(ctx, createPasswordReset, arg.TokenHash, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
 const createUser = `-- name: CreateUser :exec
 INSERT INTO users (id, created_at, updated_at, username, email, password)
 VALUES ($1, $2, $3, $4, $5, $6)
@@ -79,6 +168,26 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) error {
 	return err
 }
 
+const deleteEmailChangesByUser = `-- name: DeleteEmailChangesByUser :exec
+DELETE FROM user_email_changes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteEmailChangesByUser(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteEmailChangesByUser, userID)
+	return err
+}
+
+const deletePasswordResetsByUser = `-- name: DeletePasswordResetsByUser :exec
+DELETE FROM password_resets
+WHERE user_id = $1
+`
+
+func (q *Queries) DeletePasswordResetsByUser(ctx context.Context, userID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deletePasswordResetsByUser, userID)
+	return err
+}
+
 const deleteToken = `-- name: DeleteToken :exec
 DELETE FROM user_invitations
 WHERE token = $1
@@ -89,6 +198,29 @@ func (q *Queries) DeleteToken(ctx context.Context, token []byte) error {
 	return err
 }
 
+const getEmailChange = `-- name: GetEmailChange :one
+SELECT user_id, new_email
+FROM user_email_changes
+WHERE token_hash = $1 AND expires_at > $2
+`
+
+type GetEmailChangeParams struct {
+	TokenHash []byte
+	ExpiresAt pgtype.Timestamp
+}
+
+type GetEmailChangeRow struct {
+	UserID   pgtype.UUID
+	NewEmail string
+}
+
+func (q *Queries) GetEmailChange(ctx context.Context, arg GetEmailChangeParams) (GetEmailChangeRow, error) {
+	row := q.db.QueryRow(ctx, getEmailChange, arg.TokenHash, arg.ExpiresAt)
+	var i GetEmailChangeRow
+	err := row.Scan(&i.UserID, &i.NewEmail)
+	return i, err
+}
+
 const getInvitation = `-- name: GetInvitation :one
 SELECT user_id
 FROM user_invitations
@@ -107,10 +239,42 @@ func (q *Queries) GetInvitation(ctx context.Context, arg GetInvitationParams) (p
 	return user_id, err
 }
 
+const getInvitationsByUser = `-- name: GetInvitationsByUser :many
+SELECT expires_at
+FROM user_invitations
+WHERE user_id = $1 AND expires_at > $2
+ORDER BY expires_at
+`
+
+type GetInvitationsByUserParams struct {
+	UserID    pgtype.UUID
+	ExpiresAt pgtype.Timestamp
+}
+
+func (q *Queries) GetInvitationsByUser(ctx context.Context, arg GetInvitationsByUserParams) ([]pgtype.Timestamp, error) {
+	rows, err := q.db.Query(ctx, getInvitationsByUser, arg.UserID, arg.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Timestamp
+	for rows.Next() {
+		var expires_at pgtype.Timestamp
+		if err := rows.Scan(&expires_at); err != nil {
+			return nil, err
+		}
+		items = append(items, expires_at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, username, email, password, first_name, last_name, is_deleted, is_active, role_id FROM users
+SELECT id, created_at, updated_at, username, email, password, first_name, last_name, is_active, role_id, is_private, follower_count, following_count, post_count, bio, website, location, pronouns, avatar_url, banner_url, deletion_scheduled_at, deleted_at, purged_at FROM users
 WHERE email = $1
-	AND is_deleted = false
+	AND deleted_at IS NULL
 	AND is_active = true
 `
 
@@ -126,37 +290,139 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Password,
 		&i.FirstName,
 		&i.LastName,
-		&i.IsDeleted,
 		&i.IsActive,
 		&i.RoleID,
+		&i.IsPrivate,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.PostCount,
+		&i.Bio,
+		&i.Website,
+		&i.Location,
+		&i.Pronouns,
+		&i.AvatarUrl,
+		&i.BannerUrl,
+		&i.DeletionScheduledAt,
+		&i.DeletedAt,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT
+	u.id, u.created_at, u.updated_at, u.username, u.email, u.password, u.first_name, u.last_name, u.is_active, u.role_id, u.is_private, u.follower_count, u.following_count, u.post_count, u.bio, u.website, u.location, u.pronouns, u.avatar_url, u.banner_url, u.deletion_scheduled_at, u.deleted_at, u.purged_at, r.level, r.name,
+	(t.confirmed_at IS NOT NULL)::boolean AS two_factor_enabled
+FROM users u
+JOIN roles r ON u.role_id = r.id
+LEFT JOIN user_totp t ON t.user_id = u.id
+WHERE u.id = $1
+	AND u.deleted_at IS NULL
+	AND u.is_active = true
+`
+
+type GetUserByIDRow struct {
+	ID                  pgtype.UUID
+	CreatedAt           pgtype.Timestamp
+	UpdatedAt           pgtype.Timestamp
+	Username            string
+	Email               string
+	Password            []byte
+	FirstName           pgtype.Text
+	LastName            pgtype.Text
+	IsActive            bool
+	RoleID              int32
+	IsPrivate           bool
+	FollowerCount       int32
+	FollowingCount      int32
+	PostCount           int32
+	Bio                 pgtype.Text
+	Website             pgtype.Text
+	Location            pgtype.Text
+	Pronouns            pgtype.Text
+	AvatarUrl           pgtype.Text
+	BannerUrl           pgtype.Text
+	DeletionScheduledAt pgtype.Timestamp
+	DeletedAt           pgtype.Timestamp
+	PurgedAt            pgtype.Timestamp
+	Level               int32
+	Name                string
+	TwoFactorEnabled    bool
+}
+
+func (q *Queries) GetUserByID(ctx context.Context, id pgtype.UUID) (GetUserByIDRow, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i GetUserByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.FirstName,
+		&i.LastName,
+		&i.IsActive,
+		&i.RoleID,
+		&i.IsPrivate,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.PostCount,
+		&i.Bio,
+		&i.Website,
+		&i.Location,
+		&i.Pronouns,
+		&i.AvatarUrl,
+		&i.BannerUrl,
+		&i.DeletionScheduledAt,
+		&i.DeletedAt,
+		&i.PurgedAt,
+		&i.Level,
+		&i.Name,
+		&i.TwoFactorEnabled,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
 SELECT
-	u.id, u.created_at, u.updated_at, u.username, u.email, u.password, u.first_name, u.last_name, u.is_deleted, u.is_active, u.role_id, r.level, r.name
+	u.id, u.created_at, u.updated_at, u.username, u.email, u.password, u.first_name, u.last_name, u.is_active, u.role_id, u.is_private, u.follower_count, u.following_count, u.post_count, u.bio, u.website, u.location, u.pronouns, u.avatar_url, u.banner_url, u.deletion_scheduled_at, u.deleted_at, u.purged_at, r.level, r.name,
+	(t.confirmed_at IS NOT NULL)::boolean AS two_factor_enabled
 FROM users u
 JOIN roles r ON u.role_id = r.id
+LEFT JOIN user_totp t ON t.user_id = u.id
 WHERE u.username = $1
-	AND is_deleted = false
-	AND is_active = true
+	AND u.deleted_at IS NULL
+	AND u.is_active = true
 `
 
 type GetUserByUsernameRow struct {
-	ID        pgtype.UUID
-	CreatedAt pgtype.Timestamp
-	UpdatedAt pgtype.Timestamp
-	Username  string
-	Email     string
-	Password  []byte
-	FirstName pgtype.Text
-	LastName  pgtype.Text
-	IsDeleted bool
-	IsActive  bool
-	RoleID    int32
-	Level     int32
-	Name      string
+	ID                  pgtype.UUID
+	CreatedAt           pgtype.Timestamp
+	UpdatedAt           pgtype.Timestamp
+	Username            string
+	Email               string
+	Password            []byte
+	FirstName           pgtype.Text
+	LastName            pgtype.Text
+	IsActive            bool
+	RoleID              int32
+	IsPrivate           bool
+	FollowerCount       int32
+	FollowingCount      int32
+	PostCount           int32
+	Bio                 pgtype.Text
+	Website             pgtype.Text
+	Location            pgtype.Text
+	Pronouns            pgtype.Text
+	AvatarUrl           pgtype.Text
+	BannerUrl           pgtype.Text
+	DeletionScheduledAt pgtype.Timestamp
+	DeletedAt           pgtype.Timestamp
+	PurgedAt            pgtype.Timestamp
+	Level               int32
+	Name                string
+	TwoFactorEnabled    bool
 }
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error) {
@@ -171,11 +437,24 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUs
 		&i.Password,
 		&i.FirstName,
 		&i.LastName,
-		&i.IsDeleted,
 		&i.IsActive,
 		&i.RoleID,
+		&i.IsPrivate,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.PostCount,
+		&i.Bio,
+		&i.Website,
+		&i.Location,
+		&i.Pronouns,
+		&i.AvatarUrl,
+		&i.BannerUrl,
+		&i.DeletionScheduledAt,
+		&i.DeletedAt,
+		&i.PurgedAt,
 		&i.Level,
 		&i.Name,
+		&i.TwoFactorEnabled,
 	)
 	return i, err
 }
@@ -190,17 +469,286 @@ func (q *Queries) HardDeleteUserByID(ctx context.Context, id pgtype.UUID) error
 	return err
 }
 
-const softDeleteUserByID = `-- name: SoftDeleteUserByID :exec
-UPDATE users
-SET is_deleted = true
-WHERE id = $1
+const listDeletedUsers = `-- name: ListDeletedUsers :many
+SELECT
+	u.id, u.created_at, u.updated_at, u.username, u.email, u.password, u.first_name, u.last_name, u.is_active, u.role_id, u.is_private, u.follower_count, u.following_count, u.post_count, u.bio, u.website, u.location, u.pronouns, u.avatar_url, u.banner_url, u.deletion_scheduled_at, u.deleted_at, u.purged_at, r.level, r.name,
+	(t.confirmed_at IS NOT NULL)::boolean AS two_factor_enabled
+FROM users u
+JOIN roles r ON u.role_id = r.id
+LEFT JOIN user_totp t ON t.user_id = u.id
+WHERE (u.deleted_at IS NOT NULL OR u.deletion_scheduled_at IS NOT NULL)
+	AND u.purged_at IS NULL
+	AND ($3::text IS NULL OR u.username = $3)
+	AND ($4::timestamp IS NULL OR coalesce(u.deleted_at, u.deletion_scheduled_at) >= $4)
+	AND ($5::timestamp IS NULL OR coalesce(u.deleted_at, u.deletion_scheduled_at) < $5)
+ORDER BY coalesce(u.deleted_at, u.deletion_scheduled_at) DESC
+LIMIT $1 OFFSET $2
+`
+
+type ListDeletedUsersParams struct {
+	Limit    int32
+	Offset   int32
+	Username pgtype.Text
+	Since    pgtype.Timestamp
+	Until    pgtype.Timestamp
+}
+
+type ListDeletedUsersRow struct {
+	ID                  pgtype.UUID
+	CreatedAt           pgtype.Timestamp
+	UpdatedAt           pgtype.Timestamp
+	Username            string
+	Email               string
+	Password            []byte
+	FirstName           pgtype.Text
+	LastName            pgtype.Text
+	IsActive            bool
+	RoleID              int32
+	IsPrivate           bool
+	FollowerCount       int32
+	FollowingCount      int32
+	PostCount           int32
+	Bio                 pgtype.Text
+	Website             pgtype.Text
+	Location            pgtype.Text
+	Pronouns            pgtype.Text
+	AvatarUrl           pgtype.Text
+	BannerUrl           pgtype.Text
+	DeletionScheduledAt pgtype.Timestamp
+	DeletedAt           pgtype.Timestamp
+	PurgedAt            pgtype.Timestamp
+	Level               int32
+	Name                string
+	TwoFactorEnabled    bool
+}
+
+// Users pending deletion and soft deleted ones. Purged users are not listed, they can not be restored.
+func (q *Queries) ListDeletedUsers(ctx context.Context, arg ListDeletedUsersParams) ([]ListDeletedUsersRow, error) {
+	rows, err := q.db.Query(ctx, listDeletedUsers,
+		arg.Limit,
+		arg.Offset,
+		arg.Username,
+		arg.Since,
+		arg.Until,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDeletedUsersRow
+	for rows.Next() {
+		var i ListDeletedUsersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Username,
+			&i.Email,
+			&i.Password,
+			&i.FirstName,
+			&i.LastName,
+			&i.IsActive,
+			&i.RoleID,
+			&i.IsPrivate,
+			&i.FollowerCount,
+			&i.FollowingCount,
+			&i.PostCount,
+			&i.Bio,
+			&i.Website,
+			&i.Location,
+			&i.Pronouns,
+			&i.AvatarUrl,
+			&i.BannerUrl,
+			&i.DeletionScheduledAt,
+			&i.DeletedAt,
+			&i.PurgedAt,
+			&i.Level,
+			&i.Name,
+			&i.TwoFactorEnabled,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsersToPurge = `-- name: ListUsersToPurge :many
+SELECT id, username
+FROM users
+WHERE deletion_scheduled_at <= $1
+ORDER BY deletion_scheduled_at
+LIMIT $2
 `
 
-func (q *Queries) SoftDeleteUserByID(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, softDeleteUserByID, id)
+type ListUsersToPurgeParams struct {
+	DeletionScheduledAt pgtype.Timestamp
+	Limit               int32
+}
+
+type ListUsersToPurgeRow struct {
+	ID       pgtype.UUID
+	Username string
+}
+
+func (q *Queries) ListUsersToPurge(ctx context.Context, arg ListUsersToPurgeParams) ([]ListUsersToPurgeRow, error) {
+	rows, err := q.db.Query(ctx, listUsersToPurge, arg.DeletionScheduledAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUsersToPurgeRow
+	for rows.Next() {
+		var i ListUsersToPurgeRow
+		if err := rows.Scan(&i.ID, &i.Username); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUserToPurge = `-- name: LockUserToPurge :one
+SELECT id
+FROM users
+WHERE id = $1 AND deletion_scheduled_at <= $2
+FOR UPDATE
+`
+
+type LockUserToPurgeParams struct {
+	ID                  pgtype.UUID
+	DeletionScheduledAt pgtype.Timestamp
+}
+
+// Returns no rows if the deletion was cancelled meanwhile
+func (q *Queries) LockUserToPurge(ctx context.Context, arg LockUserToPurgeParams) (pgtype.UUID, error) {
+	row := q.db.QueryRow(ctx, lockUserToPurge, arg.ID, arg.DeletionScheduledAt)
+	var id pgtype.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const purgeUser = `-- name: PurgeUser :exec
+WITH deleted_posts AS (
+	DELETE FROM posts WHERE user_id = $1
+), deleted_follows AS (
+	DELETE FROM followers WHERE user_id = $1 OR follower_id = $1
+), deleted_follow_requests AS (
+	DELETE FROM follow_requests WHERE user_id = $1 OR requester_id = $1
+), deleted_blocks AS (
+	DELETE FROM blocks WHERE user_id = $1 OR blocked_id = $1
+), deleted_mutes AS (
+	DELETE FROM mutes WHERE user_id = $1 OR muted_user_id = $1
+), deleted_suggestions AS (
+	DELETE FROM user_suggestions WHERE user_id = $1 OR suggested_id = $1
+), deleted_identities AS (
+	DELETE FROM user_identities WHERE user_id = $1
+), deleted_totp AS (
+	DELETE FROM user_totp WHERE user_id = $1
+), deleted_recovery_codes AS (
+	DELETE FROM user_recovery_codes WHERE user_id = $1
+), deleted_challenges AS (
+	DELETE FROM two_factor_challenges WHERE user_id = $1
+), deleted_personal_access_tokens AS (
+	DELETE FROM personal_access_tokens WHERE user_id = $1
+), deleted_refresh_tokens AS (
+	DELETE FROM refresh_tokens WHERE user_id = $1
+), deleted_sessions AS (
+	DELETE FROM sessions WHERE user_id = $1
+), deleted_invitations AS (
+	DELETE FROM user_invitations WHERE user_id = $1
+), deleted_password_resets AS (
+	DELETE FROM password_resets WHERE user_id = $1
+), deleted_email_changes AS (
+	DELETE FROM user_email_changes WHERE user_id = $1
+), deleted_login_attempts AS (
+	DELETE FROM login_attempts WHERE email = (SELECT u.email FROM users u WHERE u.id = $1)
+)
+UPDATE users
+SET
+	updated_at = $2,
+	username = 'deleted-' || id,
+	email = 'deleted-' || id || '@deleted.invalid',
+	password = ''::bytea,
+	first_name = NULL,
+	last_name = NULL,
+	bio = NULL,
+	website = NULL,
+	location = NULL,
+	pronouns = NULL,
+	avatar_url = NULL,
+	banner_url = NULL,
+	is_private = false,
+	deleted_at = coalesce(deleted_at, $2),
+	purged_at = $2,
+	deletion_scheduled_at = NULL
+WHERE id = $1`
+
+type PurgeUserParams struct {
+	ID  pgtype.UUID
+	Now pgtype.Timestamp
+}
+
+// Hard deletes the posts, the follow graph and the credentials of the user, and anonymizes it. Its comments are
+// kept, but nothing points to the user anymore, and its username and email are freed.
+func (q *Queries) PurgeUser(ctx context.Context, arg PurgeUserParams) error {
+	_, err := q.db.Exec(ctx, purgeUser, arg.ID, arg.Now)
 	return err
 }
 
+const restoreUser = `-- name: RestoreUser :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	deleted_at = NULL,
+	deletion_scheduled_at = NULL
+WHERE id = $2
+	AND (deleted_at IS NOT NULL OR deletion_scheduled_at IS NOT NULL)
+	AND purged_at IS NULL
+`
+
+type RestoreUserParams struct {
+	UpdatedAt pgtype.Timestamp
+	ID        pgtype.UUID
+}
+
+// Also cancels a pending deletion
+func (q *Queries) RestoreUser(ctx context.Context, arg RestoreUserParams) (int64, error) {
+	result, err := q.db.Exec(ctx, restoreUser, arg.UpdatedAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const scheduleUserDeletion = `-- name: ScheduleUserDeletion :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	deletion_scheduled_at = $2
+WHERE id = $3 AND deleted_at IS NULL
+`
+
+type ScheduleUserDeletionParams struct {
+	UpdatedAt           pgtype.Timestamp
+	DeletionScheduledAt pgtype.Timestamp
+	ID                  pgtype.UUID
+}
+
+func (q *Queries) ScheduleUserDeletion(ctx context.Context, arg ScheduleUserDeletionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, scheduleUserDeletion, arg.UpdatedAt, arg.DeletionScheduledAt, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET
@@ -209,9 +757,15 @@ SET
 	email = coalesce($4, email),
 	first_name = coalesce($5, first_name),
 	last_name = coalesce($6, last_name),
-	password = coalesce($7, password)
-WHERE id = $2 AND is_deleted = false
-RETURNING id, created_at, updated_at, username, email, password, first_name, last_name, is_deleted, is_active, role_id
+	password = coalesce($7, password),
+	bio = coalesce($8, bio),
+	website = coalesce($9, website),
+	location = coalesce($10, location),
+	pronouns = coalesce($11, pronouns),
+	avatar_url = coalesce($12, avatar_url),
+	banner_url = coalesce($13, banner_url)
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, username, email, password, first_name, last_name, is_active, role_id, is_private, follower_count, following_count, post_count, bio, website, location, pronouns, avatar_url, banner_url, deletion_scheduled_at, deleted_at, purged_at
 `
 
 type UpdateUserParams struct {
@@ -222,6 +776,12 @@ type UpdateUserParams struct {
 	FirstName pgtype.Text
 	LastName  pgtype.Text
 	Password  []byte
+	Bio       pgtype.Text
+	Website   pgtype.Text
+	Location  pgtype.Text
+	Pronouns  pgtype.Text
+	AvatarUrl pgtype.Text
+	BannerUrl pgtype.Text
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
@@ -233,7 +793,105 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		arg.FirstName,
 		arg.LastName,
 		arg.Password,
+		arg.Bio,
+		arg.Website,
+		arg.Location,
+		arg.Pronouns,
+		arg.AvatarUrl,
+		arg.BannerUrl,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.FirstName,
+		&i.LastName,
+		&i.IsActive,
+		&i.RoleID,
+		&i.IsPrivate,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.PostCount,
+		&i.Bio,
+		&i.Website,
+		&i.Location,
+		&i.Pronouns,
+		&i.AvatarUrl,
+		&i.BannerUrl,
+		&i.DeletionScheduledAt,
+		&i.DeletedAt,
+		&i.PurgedAt,
+	)
+	return i, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :one
+UPDATE users
+SET
+	updated_at = $1,
+	email = $2
+WHERE id = $3 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, username, email, password, first_name, last_name, is_active, role_id, is_private, follower_count, following_count, post_count, bio, website, location, pronouns, avatar_url, banner_url, deletion_scheduled_at, deleted_at, purged_at
+`
+
+type UpdateUserEmailParams struct {
+	UpdatedAt pgtype.Timestamp
+	Email     string
+	ID        pgtype.UUID
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserEmail, arg.UpdatedAt, arg.Email, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Username,
+		&i.Email,
+		&i.Password,
+		&i.FirstName,
+		&i.LastName,
+		&i.IsActive,
+		&i.RoleID,
+		&i.IsPrivate,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.PostCount,
+		&i.Bio,
+		&i.Website,
+		&i.Location,
+		&i.Pronouns,
+		&i.AvatarUrl,
+		&i.BannerUrl,
+		&i.DeletionScheduledAt,
+		&i.DeletedAt,
+		&i.PurgedAt,
 	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET
+	updated_at = $1,
+	password = $2
+WHERE id = $3 AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, username, email, password, first_name, last_name, is_active, role_id, is_private, follower_count, following_count, post_count, bio, website, location, pronouns, avatar_url, banner_url, deletion_scheduled_at, deleted_at, purged_at
+`
+
+type UpdateUserPasswordParams struct {
+	UpdatedAt pgtype.Timestamp
+	Password  []byte
+	ID        pgtype.UUID
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserPassword, arg.UpdatedAt, arg.Password, arg.ID)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -244,9 +902,65 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Password,
 		&i.FirstName,
 		&i.LastName,
-		&i.IsDeleted,
 		&i.IsActive,
 		&i.RoleID,
+		&i.IsPrivate,
+		&i.FollowerCount,
+		&i.FollowingCount,
+		&i.PostCount,
+		&i.Bio,
+		&i.Website,
+		&i.Location,
+		&i.Pronouns,
+		&i.AvatarUrl,
+		&i.BannerUrl,
+		&i.DeletionScheduledAt,
+		&i.DeletedAt,
+		&i.PurgedAt,
 	)
 	return i, err
 }
+
+const updateUserPrivacy = `-- name: UpdateUserPrivacy :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	is_private = $2
+WHERE id = $3 AND deleted_at IS NULL
+`
+
+type UpdateUserPrivacyParams struct {
+	UpdatedAt pgtype.Timestamp
+	IsPrivate bool
+	ID        pgtype.UUID
+}
+
+func (q *Queries) UpdateUserPrivacy(ctx context.Context, arg UpdateUserPrivacyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserPrivacy, arg.UpdatedAt, arg.IsPrivate, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateUserRole = `-- name: UpdateUserRole :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	role_id = $2
+WHERE id = $3 AND deleted_at IS NULL
+`
+
+type UpdateUserRoleParams struct {
+	UpdatedAt pgtype.Timestamp
+	RoleID    int32
+	ID        pgtype.UUID
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateUserRole, arg.UpdatedAt, arg.RoleID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
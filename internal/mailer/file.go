@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Development sink. Messages are stored as .eml files on Dir or, if Dir is empty, logged.
+type FileMailer struct {
+	Dir    string
+	From   string
+	Logger *zap.SugaredLogger
+}
+
+func NewFileMailer(dir, from string, logger *zap.SugaredLogger) *FileMailer {
+	return &FileMailer{
+		Dir:    dir,
+		From:   from,
+		Logger: logger,
+	}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	if m.Dir == "" {
+		m.Logger.Infow("email sent", "to", msg.To, "subject", msg.Subject, "text", msg.Text)
+		return nil
+	}
+
+	data, err := msg.Bytes(m.From)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New())
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+
+	m.Logger.Infow("email stored", "to", msg.To, "subject", msg.Subject, "path", path)
+	return nil
+}
@@ -0,0 +1,149 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"text/template"
+	"time"
+)
+
+const (
+	TemplateActivation    = "activation.tmpl"
+	TemplatePasswordReset = "password_reset.tmpl"
+	TemplateNotification  = "notification.tmpl"
+	TemplateEmailChange   = "email_change.tmpl"
+	TemplateExport        = "export.tmpl"
+)
+
+//go:embed templates
+var templates embed.FS
+
+type Mailer interface {
+	// Sends a message. It should respect the context deadline.
+	Send(context.Context, *Message) error
+}
+
+// Data for TemplateActivation, TemplatePasswordReset and TemplateEmailChange
+type TokenData struct {
+	Username  string
+	URL       string
+	Token     string
+	ExpiresIn string
+}
+
+// Data for TemplateExport
+type ExportData struct {
+	Username  string
+	URL       string
+	ExpiresIn string
+}
+
+// Data for TemplateNotification
+type NotificationData struct {
+	Username string
+	Subject  string
+	Message  string
+}
+
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Renders the template `name` with `data`. Every template defines a "subject", a "text" and a "html" block.
+func Render(name string, data any) (*Message, error) {
+	textTmpl, err := template.New("").ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %s: %v", name, err)
+	}
+	htmlTmpl, err := htmltemplate.New("").ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %s: %v", name, err)
+	}
+
+	subject := new(bytes.Buffer)
+	if err := textTmpl.ExecuteTemplate(subject, "subject", data); err != nil {
+		return nil, err
+	}
+	text := new(bytes.Buffer)
+	if err := textTmpl.ExecuteTemplate(text, "text", data); err != nil {
+		return nil, err
+	}
+	html := new(bytes.Buffer)
+	if err := htmlTmpl.ExecuteTemplate(html, "html", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: subject.String(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// Encodes the message as a multipart/alternative MIME message, ready to be sent or stored as .eml
+func (m *Message) Bytes(from string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	fmt.Fprintf(buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(buf, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	}
+	for _, part := range parts {
+		w, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// Sends the message, retrying up to `retries` times with an exponential backoff starting at `backoff`
+func SendWithRetry(ctx context.Context, m Mailer, msg *Message, retries int, backoff time.Duration) error {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if err = m.Send(ctx, msg); err == nil {
+			return nil
+		}
+
+		if attempt == retries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%v (last error: %v)", ctx.Err(), err)
+		case <-time.After(backoff << attempt):
+		}
+	}
+
+	return fmt.Errorf("could not send email after %d attempts: %v", retries+1, err)
+}
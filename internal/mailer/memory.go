@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Keeps every message in memory. Meant for tests.
+type MemoryMailer struct {
+	mu       sync.RWMutex
+	messages []*Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Returns a copy of the sent messages
+func (m *MemoryMailer) Messages() []*Message {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	messages := make([]*Message, len(m.messages))
+	copy(messages, m.messages)
+	return messages
+}
+
+// Removes every sent message
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
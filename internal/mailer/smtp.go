@@ -0,0 +1,90 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+)
+
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     *mail.Address
+}
+
+// `from` may have a display name, e.g. "Gophis Social <no-reply@example.com>"
+func NewSMTPMailer(host string, port int, username, password, from string) (*SMTPMailer, error) {
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %v", from, err)
+	}
+
+	return &SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     fromAddr,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := msg.Bytes(m.From.String())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("could not connect to SMTP server: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+	if m.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+
+	// NOTE(maolivera): The envelope only takes the address, the display name goes on the header
+	if err := c.Mail(m.From.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// SMTP server which accepts any message, without TLS nor authentication
+type fakeSMTPServer struct {
+	listener net.Listener
+	commands chan string
+	data     chan string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTPServer{listener: listener, commands: make(chan string, 100), data: make(chan string, 1)}
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.commands <- line
+
+		switch verb, _, _ := strings.Cut(strings.ToUpper(line), " "); verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			s.data <- data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestSMTPMailerSender(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	m, err := NewSMTPMailer("127.0.0.1", server.port(), "", "", "Gophis Social <no-reply@localhost>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	msg := &Message{To: "gopher@example.com", Subject: "Hi", Text: "Hi", HTML: "<p>Hi</p>"}
+	if err := m.Send(ctx, msg); err != nil {
+		t.Fatalf("could not send: %v", err)
+	}
+
+	close(server.commands)
+	var mailFrom string
+	for command := range server.commands {
+		if strings.HasPrefix(strings.ToUpper(command), "MAIL FROM:") {
+			mailFrom = command
+		}
+	}
+	// The envelope only takes the address
+	if expected := "MAIL FROM:<no-reply@localhost>"; !strings.HasPrefix(mailFrom, expected) {
+		t.Errorf("expected envelope sender %s, got %s", expected, mailFrom)
+	}
+
+	data := <-server.data
+	if expected := "From: \"Gophis Social\" <no-reply@localhost>\r\n"; !strings.Contains(data, expected) {
+		t.Errorf("expected header %q, got:\n%s", expected, data)
+	}
+}
+
+func TestNewSMTPMailerInvalidSender(t *testing.T) {
+	for _, from := range []string{"", "Gophis Social", "no-reply@"} {
+		if _, err := NewSMTPMailer("localhost", 25, "", "", from); err == nil {
+			t.Errorf("expected error for sender %q", from)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Minimum time between two fetches of the provider keys, triggered by unknown key IDs
+const keysRefreshInterval = time.Minute
+
+// Claims of the ID token which are used to find or provision users
+type Claims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	GivenName         string `json:"given_name"`
+	FamilyName        string `json:"family_name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// Verifies the signature, issuer, audience, expiration and nonce of an ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims,
+		func(t *jwt.Token) (any, error) {
+			return p.key(ctx, t)
+		},
+		jwt.WithIssuer(p.Metadata.Issuer),
+		jwt.WithAudience(p.Config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodES256.Alg(),
+			jwt.SigningMethodEdDSA.Alg(),
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %v", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid ID token: subject (sub) is missing")
+	}
+
+	return claims, nil
+}
+
+// Picks the key from the `kid` header, fetching the keys again if it is unknown (e.g. the provider rotated them)
+func (p *Provider) key(ctx context.Context, t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	if key, ok := p.cachedKey(kid); ok {
+		return key, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keys != nil && time.Since(p.keysFetchedAt) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+
+	set := jwkSet{}
+	if err := p.getJSON(ctx, p.Metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("error fetching keys of %s: %v", p.Config.Name, err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// NOTE(maolivera): Unsupported keys are skipped, the provider may publish keys for other algorithms
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	key, ok := p.lookupKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	return key, nil
+}
+
+func (p *Provider) cachedKey(kid string) (any, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.lookupKey(kid)
+}
+
+// Must be called holding the lock. Tokens without key ID are only accepted if the provider has a single key.
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(data), nil
+}
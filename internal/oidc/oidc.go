@@ -0,0 +1,196 @@
+// Package oidc implements the OpenID Connect authorization code flow with PKCE, as a relying party.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+type Config struct {
+	// Used on routes, e.g. /oidc/{name}/login
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Defaults to openid, email and profile
+	Scopes []string
+}
+
+// Provider metadata (OpenID Connect Discovery 1.0, section 3)
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	UserinfoEndpoint      string   `json:"userinfo_endpoint,omitempty"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+type Provider struct {
+	Config   Config
+	Metadata Metadata
+
+	client *http.Client
+
+	// Keys of the provider, by key ID
+	mu            sync.RWMutex
+	keys          map[string]any
+	keysFetchedAt time.Time
+}
+
+// Fetches the metadata of the provider from its discovery document
+func Discover(ctx context.Context, cfg Config) (*Provider, error) {
+	if cfg.Name == "" || cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("name, issuer, client ID and redirect URL are required")
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = defaultScopes
+	}
+
+	p := &Provider{
+		Config: cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	discoveryURL := strings.TrimSuffix(cfg.Issuer, "/") + discoveryPath
+	if err := p.getJSON(ctx, discoveryURL, &p.Metadata); err != nil {
+		return nil, fmt.Errorf("error fetching discovery document of %s: %v", cfg.Name, err)
+	}
+
+	// NOTE(maolivera): Required by the spec, otherwise a document could impersonate another issuer
+	if p.Metadata.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("issuer mismatch for %s: expected %s, got %s", cfg.Name, cfg.Issuer, p.Metadata.Issuer)
+	}
+	if p.Metadata.AuthorizationEndpoint == "" || p.Metadata.TokenEndpoint == "" || p.Metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s is missing endpoints", cfg.Name)
+	}
+
+	return p, nil
+}
+
+// URL to send the user to. `codeChallenge` is the S256 challenge of the PKCE verifier.
+func (p *Provider) AuthCodeURL(state, nonce, codeChallenge string) string {
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.Config.ClientID)
+	values.Set("redirect_uri", p.Config.RedirectURL)
+	values.Set("scope", strings.Join(p.Config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", codeChallenge)
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(p.Metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return p.Metadata.AuthorizationEndpoint + separator + values.Encode()
+}
+
+// Returns a PKCE code verifier and its S256 challenge (RFC 7636)
+func NewPKCE() (string, string, error) {
+	verifier, err := RandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	return verifier, S256Challenge(verifier), nil
+}
+
+func S256Challenge(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// URL safe random string of `size` bytes of entropy, used for states, nonces and verifiers
+func RandomString(size int) (string, error) {
+	data := make([]byte, size)
+	if _, err := rand.Read(data); err != nil {
+		return "", fmt.Errorf("error creating random string: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	TokenType        string `json:"token_type"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchanges the authorization code for the tokens of the provider, and returns the raw ID token
+func (p *Provider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", p.Config.RedirectURL)
+	values.Set("code_verifier", verifier)
+	values.Set("client_id", p.Config.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Metadata.TokenEndpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.Config.ClientSecret != "" {
+		// NOTE(maolivera): client_secret_basic, credentials must be form encoded first (RFC 6749, section 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error calling token endpoint: %v", err)
+	}
+	defer res.Body.Close()
+
+	out := tokenResponse{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&out); err != nil {
+		return "", fmt.Errorf("error decoding token response (status %d): %v", res.StatusCode, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint responded %d: %s %s", res.StatusCode, out.Error, out.ErrorDescription)
+	}
+	if out.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+
+	return out.IDToken, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded %d", url, res.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(out)
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+type AuditAction string
+
+const (
+	AuditRoleCreated       AuditAction = AuditAction("role.created")
+	AuditUserRoleChanged   AuditAction = AuditAction("user.role_changed")
+	AuditPermissionGranted AuditAction = AuditAction("role.permission_granted")
+	AuditPermissionRevoked AuditAction = AuditAction("role.permission_revoked")
+	AuditUserRestored      AuditAction = AuditAction("user.restored")
+	AuditPostRestored      AuditAction = AuditAction("post.restored")
+)
+
+// Record of an administrative change
+type AuditLogEntry struct {
+	ID uuid.UUID `json:"id"`
+	// Nil if the actor was deleted
+	ActorID    *uuid.UUID  `json:"actor_id"`
+	Action     AuditAction `json:"action"`
+	TargetType string      `json:"target_type"`
+	TargetID   string      `json:"target_id"`
+	// Action specific, e.g. the previous and the new role
+	Details   json.RawMessage `json:"details" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Creates an entry of `actor` doing `action` on the target. `details` is encoded as JSON.
+func NewAuditLogEntry(actor uuid.UUID, action AuditAction, targetType, targetID string, details any) (*AuditLogEntry, error) {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditLogEntry{
+		ID:         uuid.New(),
+		ActorID:    &actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    data,
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+func DBAuditLogToAuditLogEntry(dbEntry database.AuditLog) *AuditLogEntry {
+	e := &AuditLogEntry{
+		ID:         dbEntry.ID.Bytes,
+		Action:     AuditAction(dbEntry.Action),
+		TargetType: dbEntry.TargetType,
+		TargetID:   dbEntry.TargetID,
+		Details:    dbEntry.Details,
+		CreatedAt:  dbEntry.CreatedAt.Time,
+	}
+	if dbEntry.ActorID.Valid {
+		actorID := uuid.UUID(dbEntry.ActorID.Bytes)
+		e.ActorID = &actorID
+	}
+	return e
+}
+
+func DBAuditLogToAuditLogEntries(dbEntries []database.AuditLog) []*AuditLogEntry {
+	entries := make([]*AuditLogEntry, len(dbEntries))
+	for i, dbEntry := range dbEntries {
+		entries[i] = DBAuditLogToAuditLogEntry(dbEntry)
+	}
+	return entries
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+// A user blocked by the logged user
+type Block struct {
+	User      ReducedUser `json:"user"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func DBBlockToBlock(dbBlock database.ListBlockedUsersRow) *Block {
+	return &Block{
+		User: ReducedUser{
+			ID:       dbBlock.ID.Bytes,
+			Username: dbBlock.Username,
+		},
+		CreatedAt: dbBlock.CreatedAt.Time,
+	}
+}
+
+func DBBlocksToBlocks(dbBlocks []database.ListBlockedUsersRow) []*Block {
+	blocks := make([]*Block, len(dbBlocks))
+	for i, dbBlock := range dbBlocks {
+		blocks[i] = DBBlockToBlock(dbBlock)
+	}
+	return blocks
+}
@@ -8,12 +8,12 @@ import (
 )
 
 type Comment struct {
-	ID        uuid.UUID `json:"id"`
-	PostID    uuid.UUID `json:"post_id,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at,omitempty"`
-	Content   string    `json:"content"`
-	User      *User     `json:"user"`
+	ID        uuid.UUID   `json:"id"`
+	PostID    uuid.UUID   `json:"post_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at,omitempty"`
+	Content   string      `json:"content"`
+	User      ReducedUser `json:"user"`
 }
 
 func DBCommentToComment(dbComment database.Comment) *Comment {
@@ -23,7 +23,7 @@ func DBCommentToComment(dbComment database.Comment) *Comment {
 		CreatedAt: dbComment.CreatedAt.Time,
 		UpdatedAt: dbComment.UpdatedAt.Time,
 		Content:   dbComment.Content,
-		User:      &User{ID: dbComment.UserID.Bytes},
+		User:      ReducedUser{ID: dbComment.UserID.Bytes},
 	}
 }
 
@@ -40,10 +40,9 @@ func DBCommentWithUser(dbComment database.GetCommentsByPostRow) *Comment {
 		ID:        dbComment.ID.Bytes,
 		CreatedAt: dbComment.CreatedAt.Time,
 		Content:   dbComment.Content,
-		User: &User{
-			Username:  dbComment.Username.String,
-			FirstName: dbComment.Username.String,
-			Email:     dbComment.Email.String,
+		User: ReducedUser{
+			ID:       dbComment.UserID.Bytes,
+			Username: dbComment.Username.String,
 		},
 	}
 }
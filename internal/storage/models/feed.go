@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/maxolivera/gophis-social-network/internal/database"
 )
 
@@ -13,6 +14,7 @@ type Feed struct {
 	Title        string      `json:"title"`
 	Content      string      `json:"content"`
 	CreatedAt    time.Time   `json:"created_at"`
+	EditedAt     *time.Time  `json:"edited_at,omitempty"`
 	Tags         []string    `json:"tags"`
 	Author       ReducedUser `json:"author"`
 	CommentCount int64       `json:"comment_count"`
@@ -25,6 +27,7 @@ func DBFeedRowToFeed(row any) (*Feed, error) {
 			ID:           v.ID.Bytes,
 			Title:        v.Title,
 			CreatedAt:    v.CreatedAt.Time,
+			EditedAt:     optionalTime(v.EditedAt),
 			Content:      v.Content,
 			Tags:         v.Tags,
 			Author:       ReducedUser{ID: v.AuthorID.Bytes, Username: v.Username.String},
@@ -35,6 +38,7 @@ func DBFeedRowToFeed(row any) (*Feed, error) {
 			ID:           v.ID.Bytes,
 			Title:        v.Title,
 			CreatedAt:    v.CreatedAt.Time,
+			EditedAt:     optionalTime(v.EditedAt),
 			Content:      v.Content,
 			Tags:         v.Tags,
 			Author:       ReducedUser{ID: v.AuthorID.Bytes, Username: v.Username.String},
@@ -46,6 +50,14 @@ func DBFeedRowToFeed(row any) (*Feed, error) {
 
 }
 
+// Nil if the timestamp is NULL
+func optionalTime(t pgtype.Timestamp) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
 func DBFeedsToFeeds[S ~[]E, E any](s S) ([]*Feed, error) {
 	feeds := make([]*Feed, len(s))
 	for i, dbFeed := range s {
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+// A user in a followers or following list, and when the follow started
+type Follow struct {
+	User      ReducedUser `json:"user"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func DBFollowerToFollow(dbFollower database.ListFollowersRow) *Follow {
+	return &Follow{
+		User: ReducedUser{
+			ID:       dbFollower.ID.Bytes,
+			Username: dbFollower.Username,
+		},
+		CreatedAt: dbFollower.CreatedAt.Time,
+	}
+}
+
+func DBFollowersToFollows(dbFollowers []database.ListFollowersRow) []*Follow {
+	follows := make([]*Follow, len(dbFollowers))
+	for i, dbFollower := range dbFollowers {
+		follows[i] = DBFollowerToFollow(dbFollower)
+	}
+	return follows
+}
+
+func DBFollowingToFollows(dbFollowing []database.ListFollowingRow) []*Follow {
+	follows := make([]*Follow, len(dbFollowing))
+	for i, dbFollowed := range dbFollowing {
+		follows[i] = DBFollowerToFollow(database.ListFollowersRow(dbFollowed))
+	}
+	return follows
+}
+
+// A pending request to follow a private user
+type FollowRequest struct {
+	User      ReducedUser `json:"user"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+func DBFollowRequestToFollowRequest(dbRequest database.ListFollowRequestsRow) *FollowRequest {
+	return &FollowRequest{
+		User: ReducedUser{
+			ID:       dbRequest.ID.Bytes,
+			Username: dbRequest.Username,
+		},
+		CreatedAt: dbRequest.CreatedAt.Time,
+	}
+}
+
+func DBFollowRequestsToFollowRequests(dbRequests []database.ListFollowRequestsRow) []*FollowRequest {
+	requests := make([]*FollowRequest, len(dbRequests))
+	for i, dbRequest := range dbRequests {
+		requests[i] = DBFollowRequestToFollowRequest(dbRequest)
+	}
+	return requests
+}
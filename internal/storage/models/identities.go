@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Account of a user on an external identity provider
+type UserIdentity struct {
+	Provider  string
+	Subject   string
+	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+}
+
+// Pending login with an external identity provider. State holds the plain value, only its hash is stored.
+type OIDCState struct {
+	State        []byte
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+type LoginOutcome string
+
+const (
+	LoginSuccess             LoginOutcome = LoginOutcome("success")
+	LoginInvalidCredentials  LoginOutcome = LoginOutcome("invalid_credentials")
+	LoginInvalidSecondFactor LoginOutcome = LoginOutcome("invalid_second_factor")
+	LoginLocked              LoginOutcome = LoginOutcome("locked")
+	LoginThrottled           LoginOutcome = LoginOutcome("throttled")
+)
+
+type LoginAttempt struct {
+	ID        uuid.UUID    `json:"id"`
+	Email     string       `json:"email"`
+	IP        string       `json:"ip"`
+	UserAgent string       `json:"user_agent"`
+	Outcome   LoginOutcome `json:"outcome"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+func DBLoginAttemptToLoginAttempt(dbAttempt database.LoginAttempt) *LoginAttempt {
+	return &LoginAttempt{
+		ID:        dbAttempt.ID.Bytes,
+		Email:     dbAttempt.Email,
+		IP:        dbAttempt.Ip,
+		UserAgent: dbAttempt.UserAgent,
+		Outcome:   LoginOutcome(dbAttempt.Outcome),
+		CreatedAt: dbAttempt.CreatedAt.Time,
+	}
+}
+
+func DBLoginAttemptsToLoginAttempts(dbAttempts []database.LoginAttempt) []*LoginAttempt {
+	attempts := make([]*LoginAttempt, len(dbAttempts))
+	for i, dbAttempt := range dbAttempts {
+		attempts[i] = DBLoginAttemptToLoginAttempt(dbAttempt)
+	}
+	return attempts
+}
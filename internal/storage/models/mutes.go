@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+type MuteKind string
+
+const (
+	MuteKindUser    MuteKind = MuteKind("user")
+	MuteKindTag     MuteKind = MuteKind("tag")
+	MuteKindKeyword MuteKind = MuteKind("keyword")
+)
+
+// Hides posts from the feed and search of the user who muted them, without the other side knowing
+type Mute struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Kind   MuteKind  `json:"kind"`
+	// Only for muted users
+	MutedUser *ReducedUser `json:"muted_user,omitempty"`
+	// Lowercase tag or keyword, empty for muted users
+	Value     string     `json:"value,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func DBMuteToMute(dbMute database.ListMutesRow, userID uuid.UUID) *Mute {
+	m := &Mute{
+		ID:        dbMute.ID.Bytes,
+		UserID:    userID,
+		Kind:      MuteKind(dbMute.Kind),
+		Value:     dbMute.Value,
+		CreatedAt: dbMute.CreatedAt.Time,
+	}
+	if dbMute.MutedUserID.Valid {
+		m.MutedUser = &ReducedUser{
+			ID:       dbMute.MutedUserID.Bytes,
+			Username: dbMute.Username.String,
+		}
+	}
+	if dbMute.ExpiresAt.Valid {
+		m.ExpiresAt = &dbMute.ExpiresAt.Time
+	}
+	return m
+}
+
+func DBMutesToMutes(dbMutes []database.ListMutesRow, userID uuid.UUID) []*Mute {
+	mutes := make([]*Mute, len(dbMutes))
+	for i, dbMute := range dbMutes {
+		mutes[i] = DBMuteToMute(dbMute, userID)
+	}
+	return mutes
+}
@@ -0,0 +1,41 @@
+package models
+
+import "github.com/maxolivera/gophis-social-network/internal/database"
+
+// Permissions a role can be granted. Owners do not need them to act on their own resources.
+const (
+	PermissionPostsUpdateAny    = "posts:update_any"
+	PermissionPostsDeleteAny    = "posts:delete_any"
+	PermissionPostsHardDelete   = "posts:hard_delete"
+	PermissionUsersHardDelete   = "users:hard_delete"
+	PermissionUsersReadPrivate  = "users:read_private"
+	PermissionUsersDeleteAny    = "users:delete_any"
+	PermissionUsersUpdateAny    = "users:update_any"
+	PermissionUsersRestore      = "users:restore"
+	PermissionPostsRestore      = "posts:restore"
+	PermissionRolesManage       = "roles:manage"
+	PermissionSessionsManage    = "sessions:manage"
+	PermissionLoginAttemptsRead = "login_attempts:read"
+	PermissionAuditLogRead      = "audit_log:read"
+	PermissionTokensAdmin       = "tokens:admin"
+)
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func DBPermissionToPermission(dbPermission database.Permission) *Permission {
+	return &Permission{
+		Name:        dbPermission.Name,
+		Description: dbPermission.Description,
+	}
+}
+
+func DBPermissionsToPermissions(dbPermissions []database.Permission) []*Permission {
+	permissions := make([]*Permission, len(dbPermissions))
+	for i, dbPermission := range dbPermissions {
+		permissions[i] = DBPermissionToPermission(dbPermission)
+	}
+	return permissions
+}
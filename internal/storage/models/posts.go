@@ -17,10 +17,23 @@ type Post struct {
 	Tags      []string   `json:"tags"`
 	Comments  []*Comment `json:"comments"`
 	Version   int32      `json:"version"`
+	// Nil unless the post was edited
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// Nil unless the post was soft deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Content of a post before one of its edits
+type PostRevision struct {
+	Version   int32     `json:"version"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 func DBPostToPost(dbPost database.Post) *Post {
-	return &Post{
+	p := &Post{
 		ID:        dbPost.ID.Bytes,
 		UserID:    dbPost.UserID.Bytes,
 		CreatedAt: dbPost.CreatedAt.Time,
@@ -30,6 +43,13 @@ func DBPostToPost(dbPost database.Post) *Post {
 		Tags:      dbPost.Tags,
 		Version:   dbPost.Version,
 	}
+	if dbPost.EditedAt.Valid {
+		p.EditedAt = &dbPost.EditedAt.Time
+	}
+	if dbPost.DeletedAt.Valid {
+		p.DeletedAt = &dbPost.DeletedAt.Time
+	}
+	return p
 }
 
 func DBPostsToPost(dbPosts []database.Post) []*Post {
@@ -39,3 +59,21 @@ func DBPostsToPost(dbPosts []database.Post) []*Post {
 	}
 	return posts
 }
+
+func DBPostRevisionToPostRevision(dbRevision database.PostRevision) *PostRevision {
+	return &PostRevision{
+		Version:   dbRevision.Version,
+		Title:     dbRevision.Title,
+		Content:   dbRevision.Content,
+		Tags:      dbRevision.Tags,
+		CreatedAt: dbRevision.CreatedAt.Time,
+	}
+}
+
+func DBPostRevisionsToPostRevisions(dbRevisions []database.PostRevision) []*PostRevision {
+	revisions := make([]*PostRevision, len(dbRevisions))
+	for i, dbRevision := range dbRevisions {
+		revisions[i] = DBPostRevisionToPostRevision(dbRevision)
+	}
+	return revisions
+}
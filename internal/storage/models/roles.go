@@ -1,5 +1,7 @@
 package models
 
+import "github.com/maxolivera/gophis-social-network/internal/database"
+
 type RoleType string
 
 const (
@@ -13,3 +15,27 @@ type ReducedRole struct {
 	Name  RoleType `json:"name"`
 	Level int      `json:"level"`
 }
+
+type Role struct {
+	ID          int      `json:"id"`
+	Name        RoleType `json:"name"`
+	Level       int      `json:"level"`
+	Description string   `json:"description"`
+}
+
+func DBRoleToRole(dbRole database.Role) *Role {
+	return &Role{
+		ID:          int(dbRole.ID),
+		Name:        RoleType(dbRole.Name),
+		Level:       int(dbRole.Level),
+		Description: dbRole.Description,
+	}
+}
+
+func DBRolesToRoles(dbRoles []database.Role) []*Role {
+	roles := make([]*Role, len(dbRoles))
+	for i, dbRole := range dbRoles {
+		roles[i] = DBRoleToRole(dbRole)
+	}
+	return roles
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+// A login, which lasts as long as its refresh tokens can be used
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	// Whether it is the session of the request
+	Current bool `json:"current"`
+}
+
+func DBSessionToSession(dbSession database.Session) *Session {
+	return &Session{
+		ID:         dbSession.ID.Bytes,
+		UserID:     dbSession.UserID.Bytes,
+		IP:         dbSession.Ip,
+		UserAgent:  dbSession.UserAgent,
+		CreatedAt:  dbSession.CreatedAt.Time,
+		LastSeenAt: dbSession.LastSeenAt.Time,
+	}
+}
+
+func DBSessionsToSessions(dbSessions []database.Session) []*Session {
+	sessions := make([]*Session, len(dbSessions))
+	for i, dbSession := range dbSessions {
+		sessions[i] = DBSessionToSession(dbSession)
+	}
+	return sessions
+}
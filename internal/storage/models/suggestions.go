@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+// A user the logged user may want to follow, and why
+type Suggestion struct {
+	User  ReducedUser `json:"user"`
+	Score float64     `json:"score"`
+	// Users followed by the logged user who follow the suggested one
+	MutualFollowers int `json:"mutual_followers"`
+	// Tags used by both users on their recent posts
+	SharedTags int `json:"shared_tags"`
+}
+
+func DBSuggestionToSuggestion(dbSuggestion database.ListSuggestionsRow) *Suggestion {
+	return &Suggestion{
+		User: ReducedUser{
+			ID:       dbSuggestion.ID.Bytes,
+			Username: dbSuggestion.Username,
+		},
+		Score:           dbSuggestion.Score,
+		MutualFollowers: int(dbSuggestion.MutualCount),
+		SharedTags:      int(dbSuggestion.SharedTags),
+	}
+}
+
+func DBSuggestionsToSuggestions(dbSuggestions []database.ListSuggestionsRow) []*Suggestion {
+	suggestions := make([]*Suggestion, len(dbSuggestions))
+	for i, dbSuggestion := range dbSuggestions {
+		suggestions[i] = DBSuggestionToSuggestion(dbSuggestion)
+	}
+	return suggestions
+}
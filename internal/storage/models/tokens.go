@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+// Opaque refresh token. Token holds the plain value and is never stored, only its hash is.
+type RefreshToken struct {
+	ID        uuid.UUID
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	Token     []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func DBRefreshTokenToRefreshToken(dbToken database.RefreshToken) *RefreshToken {
+	return &RefreshToken{
+		ID:        dbToken.ID.Bytes,
+		FamilyID:  dbToken.FamilyID.Bytes,
+		UserID:    dbToken.UserID.Bytes,
+		CreatedAt: dbToken.CreatedAt.Time,
+		ExpiresAt: dbToken.ExpiresAt.Time,
+	}
+}
+
+// Prefix of every personal access token, so they can be told apart from JWTs (and found by secret scanners)
+const PersonalAccessTokenPrefix = "gph_"
+
+// Scopes a personal access token can be granted. Tokens obtained by logging in are not limited by scopes.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+	ScopeFeedRead   = "feed:read"
+	ScopeAdmin      = "admin"
+)
+
+var Scopes = []string{ScopeUsersRead, ScopeUsersWrite, ScopePostsRead, ScopePostsWrite, ScopeFeedRead, ScopeAdmin}
+
+// Long-lived token for bots and integrations. Token holds the plain value, it is only set on creation.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func (t *PersonalAccessToken) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().UTC().After(*t.ExpiresAt)
+}
+
+func DBPersonalAccessTokenToPersonalAccessToken(dbToken database.PersonalAccessToken) *PersonalAccessToken {
+	t := &PersonalAccessToken{
+		ID:        dbToken.ID.Bytes,
+		UserID:    dbToken.UserID.Bytes,
+		Name:      dbToken.Name,
+		Scopes:    dbToken.Scopes,
+		CreatedAt: dbToken.CreatedAt.Time,
+	}
+	if dbToken.ExpiresAt.Valid {
+		t.ExpiresAt = &dbToken.ExpiresAt.Time
+	}
+	if dbToken.LastUsedAt.Valid {
+		t.LastUsedAt = &dbToken.LastUsedAt.Time
+	}
+	return t
+}
+
+func DBPersonalAccessTokensToPersonalAccessTokens(dbTokens []database.PersonalAccessToken) []*PersonalAccessToken {
+	tokens := make([]*PersonalAccessToken, len(dbTokens))
+	for i, dbToken := range dbTokens {
+		tokens[i] = DBPersonalAccessTokenToPersonalAccessToken(dbToken)
+	}
+	return tokens
+}
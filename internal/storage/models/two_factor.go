@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+)
+
+// TOTP secret of a user. It is only enabled once the user confirms it with a valid code.
+type TOTP struct {
+	UserID       uuid.UUID
+	Secret       []byte
+	CreatedAt    time.Time
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+}
+
+func (t *TOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+func DBTOTPToTOTP(dbTOTP database.UserTotp) *TOTP {
+	t := &TOTP{
+		UserID:       dbTOTP.UserID.Bytes,
+		Secret:       dbTOTP.Secret,
+		CreatedAt:    dbTOTP.CreatedAt.Time,
+		LastUsedStep: dbTOTP.LastUsedStep,
+	}
+	if dbTOTP.ConfirmedAt.Valid {
+		t.ConfirmedAt = &dbTOTP.ConfirmedAt.Time
+	}
+	return t
+}
+
+func DBTOTPsToTOTPs(dbTOTPs []database.UserTotp) []*TOTP {
+	totps := make([]*TOTP, len(dbTOTPs))
+	for i, dbTOTP := range dbTOTPs {
+		totps[i] = DBTOTPToTOTP(dbTOTP)
+	}
+	return totps
+}
@@ -4,19 +4,127 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/maxolivera/gophis-social-network/internal/database"
 )
 
 // Do not hold the password
 type User struct {
-	ID        uuid.UUID   `json:"id"`
-	CreatedAt time.Time   `json:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at"`
-	Email     string      `json:"email"`
-	Username  string      `json:"username"`
-	FirstName string      `json:"first_name,omitempty"`
-	LastName  string      `json:"last_name,omitempty"`
-	Role      ReducedRole `json:"role"`
+	ID               uuid.UUID   `json:"id"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+	Email            string      `json:"email"`
+	Username         string      `json:"username"`
+	FirstName        string      `json:"first_name,omitempty"`
+	LastName         string      `json:"last_name,omitempty"`
+	Role             ReducedRole `json:"role"`
+	TwoFactorEnabled bool        `json:"two_factor_enabled"`
+	// Only followers can see the posts of private users, and following them requires their approval
+	IsPrivate bool `json:"is_private"`
+	// Kept by the database, they are not counted on each request.
+	// NOTE(maolivera): Cached users may show them up to cache.UserTimeExpiration late
+	FollowerCount  int `json:"follower_count"`
+	FollowingCount int `json:"following_count"`
+	PostCount      int `json:"post_count"`
+	Profile
+	// When the account will be purged, unless the user logs in before
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	// Nil unless the user was soft deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Public information the user chose to share
+type Profile struct {
+	Bio      string `json:"bio,omitempty"`
+	Website  string `json:"website,omitempty"`
+	Location string `json:"location,omitempty"`
+	Pronouns string `json:"pronouns,omitempty"`
+	// URLs of the images
+	AvatarURL string `json:"avatar_url,omitempty"`
+	BannerURL string `json:"banner_url,omitempty"`
+}
+
+// Changes to a profile. Nil fields will not change, and empty ones are cleared.
+type ProfileChanges struct {
+	Bio       *string
+	Website   *string
+	Location  *string
+	Pronouns  *string
+	AvatarURL *string
+	BannerURL *string
+}
+
+// What any user can see of another one
+type PublicUser struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	Username       string    `json:"username"`
+	FirstName      string    `json:"first_name,omitempty"`
+	LastName       string    `json:"last_name,omitempty"`
+	IsPrivate      bool      `json:"is_private"`
+	FollowerCount  int       `json:"follower_count"`
+	FollowingCount int       `json:"following_count"`
+	PostCount      int       `json:"post_count"`
+	Profile
+}
+
+// What users see of themselves
+type SelfUser struct {
+	PublicUser
+	UpdatedAt        time.Time   `json:"updated_at"`
+	Email            string      `json:"email"`
+	Role             ReducedRole `json:"role"`
+	TwoFactorEnabled bool        `json:"two_factor_enabled"`
+	// When the account will be purged, unless the user logs in before
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+}
+
+// What staff with the users:read_private permission see of any user
+type AdminUser struct {
+	SelfUser
+	// Permissions granted to the role of the user
+	Permissions []string `json:"permissions"`
+	// Nil unless the user was soft deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (u *User) PublicView() *PublicUser {
+	return &PublicUser{
+		ID:             u.ID,
+		CreatedAt:      u.CreatedAt,
+		Username:       u.Username,
+		FirstName:      u.FirstName,
+		LastName:       u.LastName,
+		IsPrivate:      u.IsPrivate,
+		FollowerCount:  u.FollowerCount,
+		FollowingCount: u.FollowingCount,
+		PostCount:      u.PostCount,
+		Profile:        u.Profile,
+	}
+}
+
+func (u *User) SelfView() *SelfUser {
+	return &SelfUser{
+		PublicUser:          *u.PublicView(),
+		UpdatedAt:           u.UpdatedAt,
+		Email:               u.Email,
+		Role:                u.Role,
+		TwoFactorEnabled:    u.TwoFactorEnabled,
+		DeletionScheduledAt: u.DeletionScheduledAt,
+	}
+}
+
+func (u *User) AdminView(permissions []string) *AdminUser {
+	return &AdminUser{
+		SelfUser:    *u.SelfView(),
+		Permissions: permissions,
+		DeletedAt:   u.DeletedAt,
+	}
+}
+
+// A pending activation of a user. The token is never exposed.
+type Invitation struct {
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // It has the real password. Should never be used besides on storage layers.
@@ -32,15 +140,34 @@ type ReducedUser struct {
 }
 
 func DBUserToUser(dbUser database.User) *User {
-	return &User{
-		ID:        dbUser.ID.Bytes,
-		CreatedAt: dbUser.CreatedAt.Time,
-		UpdatedAt: dbUser.UpdatedAt.Time,
-		Email:     dbUser.Email,
-		Username:  dbUser.Username,
-		FirstName: dbUser.FirstName.String,
-		LastName:  dbUser.LastName.String,
+	u := &User{
+		ID:             dbUser.ID.Bytes,
+		CreatedAt:      dbUser.CreatedAt.Time,
+		UpdatedAt:      dbUser.UpdatedAt.Time,
+		Email:          dbUser.Email,
+		Username:       dbUser.Username,
+		FirstName:      dbUser.FirstName.String,
+		LastName:       dbUser.LastName.String,
+		IsPrivate:      dbUser.IsPrivate,
+		FollowerCount:  int(dbUser.FollowerCount),
+		FollowingCount: int(dbUser.FollowingCount),
+		PostCount:      int(dbUser.PostCount),
+		Profile: dbProfileToProfile(
+			dbUser.Bio,
+			dbUser.Website,
+			dbUser.Location,
+			dbUser.Pronouns,
+			dbUser.AvatarUrl,
+			dbUser.BannerUrl,
+		),
+	}
+	if dbUser.DeletionScheduledAt.Valid {
+		u.DeletionScheduledAt = &dbUser.DeletionScheduledAt.Time
 	}
+	if dbUser.DeletedAt.Valid {
+		u.DeletedAt = &dbUser.DeletedAt.Time
+	}
+	return u
 }
 
 func DBUsersToUser(dbUsers []database.User) []*User {
@@ -52,7 +179,7 @@ func DBUsersToUser(dbUsers []database.User) []*User {
 }
 
 func DBUserWithRoleToUser(dbUser database.GetUserByUsernameRow) *User {
-	return &User{
+	u := &User{
 		ID:        dbUser.ID.Bytes,
 		CreatedAt: dbUser.CreatedAt.Time,
 		UpdatedAt: dbUser.UpdatedAt.Time,
@@ -64,5 +191,36 @@ func DBUserWithRoleToUser(dbUser database.GetUserByUsernameRow) *User {
 			Level: int(dbUser.Level),
 			Name:  RoleType(dbUser.Name),
 		},
+		TwoFactorEnabled: dbUser.TwoFactorEnabled,
+		IsPrivate:        dbUser.IsPrivate,
+		FollowerCount:    int(dbUser.FollowerCount),
+		FollowingCount:   int(dbUser.FollowingCount),
+		PostCount:        int(dbUser.PostCount),
+		Profile: dbProfileToProfile(
+			dbUser.Bio,
+			dbUser.Website,
+			dbUser.Location,
+			dbUser.Pronouns,
+			dbUser.AvatarUrl,
+			dbUser.BannerUrl,
+		),
+	}
+	if dbUser.DeletionScheduledAt.Valid {
+		u.DeletionScheduledAt = &dbUser.DeletionScheduledAt.Time
+	}
+	if dbUser.DeletedAt.Valid {
+		u.DeletedAt = &dbUser.DeletedAt.Time
+	}
+	return u
+}
+
+func dbProfileToProfile(bio, website, location, pronouns, avatarURL, bannerURL pgtype.Text) Profile {
+	return Profile{
+		Bio:       bio.String,
+		Website:   website.String,
+		Location:  location.String,
+		Pronouns:  pronouns.String,
+		AvatarURL: avatarURL.String,
+		BannerURL: bannerURL.String,
 	}
 }
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresAuditLogRepository struct {
+	p *pgxpool.Pool
+}
+
+// Stores an audit entry (no transaction)
+func (r PostgresAuditLogRepository) Record(ctx context.Context, e *models.AuditLogEntry) error {
+	q := database.New(r.p)
+
+	return createAuditLogEntry(ctx, q, e)
+}
+
+func createAuditLogEntry(ctx context.Context, q *database.Queries, e *models.AuditLogEntry) error {
+	actorID := pgtype.UUID{}
+	if e.ActorID != nil {
+		actorID = pgtype.UUID{Bytes: *e.ActorID, Valid: true}
+	}
+	details := []byte(e.Details)
+	if len(details) == 0 {
+		details = []byte("{}")
+	}
+
+	return q.CreateAuditLogEntry(ctx, database.CreateAuditLogEntryParams{
+		ID:         pgtype.UUID{Bytes: e.ID, Valid: true},
+		ActorID:    actorID,
+		Action:     string(e.Action),
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Details:    details,
+		CreatedAt:  pgtype.Timestamp{Time: e.CreatedAt, Valid: true},
+	})
+}
+
+// Retrieve audit entries, newest first
+func (r PostgresAuditLogRepository) List(ctx context.Context, limit, offset int32) ([]*models.AuditLogEntry, error) {
+	q := database.New(r.p)
+
+	dbEntries, err := q.ListAuditLog(ctx, database.ListAuditLogParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBAuditLogToAuditLogEntries(dbEntries), nil
+}
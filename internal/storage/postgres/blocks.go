@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresBlockRepository struct {
+	p *pgxpool.Pool
+}
+
+func (r PostgresBlockRepository) Block(ctx context.Context, user, blocked uuid.UUID) error {
+	pgUser := pgtype.UUID{Bytes: user, Valid: true}
+	pgBlocked := pgtype.UUID{Bytes: blocked, Valid: true}
+
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(tx)
+
+		// 1. Block
+		if err := q.BlockByID(ctx, database.BlockByIDParams{
+			CreatedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+			UserID:    pgUser,
+			BlockedID: pgBlocked,
+		}); err != nil {
+			return err
+		}
+
+		// 2. Remove follows in both directions
+		if err := q.DeleteFollowsBetween(ctx, database.DeleteFollowsBetweenParams{
+			UserID:     pgUser,
+			FollowerID: pgBlocked,
+		}); err != nil {
+			return err
+		}
+
+		// 3. Remove pending follow requests in both directions
+		return q.DeleteFollowRequestsBetween(ctx, database.DeleteFollowRequestsBetweenParams{
+			UserID:      pgUser,
+			RequesterID: pgBlocked,
+		})
+	})
+}
+
+func (r PostgresBlockRepository) Unblock(ctx context.Context, user, blocked uuid.UUID) error {
+	q := database.New(r.p)
+
+	return q.UnblockByID(ctx, database.UnblockByIDParams{
+		UserID:    pgtype.UUID{Bytes: user, Valid: true},
+		BlockedID: pgtype.UUID{Bytes: blocked, Valid: true},
+	})
+}
+
+func (r PostgresBlockRepository) IsBlocked(ctx context.Context, user, other uuid.UUID) (bool, error) {
+	q := database.New(r.p)
+
+	return q.IsBlocked(ctx, database.IsBlockedParams{
+		UserID:    pgtype.UUID{Bytes: user, Valid: true},
+		BlockedID: pgtype.UUID{Bytes: other, Valid: true},
+	})
+}
+
+func (r PostgresBlockRepository) GetByUser(ctx context.Context, user uuid.UUID) ([]*models.Block, error) {
+	q := database.New(r.p)
+
+	dbBlocks, err := q.ListBlockedUsers(ctx, pgtype.UUID{Bytes: user, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBBlocksToBlocks(dbBlocks), nil
+}
@@ -16,9 +16,12 @@ type PostgresCommentRepository struct {
 	p *pgxpool.Pool
 }
 
-func (r *PostgresCommentRepository) GetByPostID(ctx context.Context, id uuid.UUID) ([]*models.Comment, error) {
+func (r *PostgresCommentRepository) GetByPostID(ctx context.Context, id, viewer uuid.UUID) ([]*models.Comment, error) {
 	q := database.New(r.p)
-	dbComments, err := q.GetCommentsByPost(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	dbComments, err := q.GetCommentsByPost(ctx, database.GetCommentsByPostParams{
+		PostID:   pgtype.UUID{Bytes: id, Valid: true},
+		ViewerID: pgtype.UUID{Bytes: viewer, Valid: true},
+	})
 	if err != nil {
 		switch err {
 		case pgx.ErrNoRows:
@@ -44,3 +47,13 @@ func (r *PostgresCommentRepository) Create(ctx context.Context, comment *models.
 		Content:   comment.Content,
 	})
 }
+
+func (r *PostgresCommentRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Comment, error) {
+	q := database.New(r.p)
+	dbComments, err := q.GetCommentsByUser(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBCommentsToComments(dbComments), nil
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+)
+
+type PostgresExportRepository struct {
+	p *pgxpool.Pool
+}
+
+// NOTE(maolivera): Pending exports started before staleBefore are taken over, otherwise one whose instance
+// stopped while generating it would block the exports of the user forever
+func (r PostgresExportRepository) Start(ctx context.Context, userID, id uuid.UUID, staleBefore time.Time) error {
+	q := database.New(r.p)
+
+	_, err := q.StartExport(ctx, database.StartExportParams{
+		UserID:      pgtype.UUID{Bytes: userID, Valid: true},
+		ID:          pgtype.UUID{Bytes: id, Valid: true},
+		Now:         pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+		StaleBefore: pgtype.Timestamp{Time: staleBefore.UTC(), Valid: true},
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return storage.ErrInProgress
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r PostgresExportRepository) Finish(ctx context.Context, userID, id uuid.UUID) error {
+	q := database.New(r.p)
+
+	return q.FinishExport(ctx, database.FinishExportParams{
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+	})
+}
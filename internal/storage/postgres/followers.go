@@ -5,9 +5,12 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
 )
 
 type PostgresFollowerRepository struct {
@@ -26,10 +29,143 @@ func (r PostgresFollowerRepository) Follow(ctx context.Context, user, follower u
 }
 
 func (r PostgresFollowerRepository) Unfollow(ctx context.Context, user, follower uuid.UUID) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(tx)
+
+		// 1. Stop following
+		if err := q.UnfollowByID(ctx, database.UnfollowByIDParams{
+			UserID:     pgtype.UUID{Bytes: user, Valid: true},
+			FollowerID: pgtype.UUID{Bytes: follower, Valid: true},
+		}); err != nil {
+			return err
+		}
+
+		// 2. Cancel the pending request, if any
+		_, err := q.DeleteFollowRequest(ctx, database.DeleteFollowRequestParams{
+			UserID:      pgtype.UUID{Bytes: user, Valid: true},
+			RequesterID: pgtype.UUID{Bytes: follower, Valid: true},
+		})
+		return err
+	})
+}
+
+func (r PostgresFollowerRepository) IsFollowing(ctx context.Context, user, follower uuid.UUID) (bool, error) {
 	q := database.New(r.p)
 
-	return q.UnfollowByID(ctx, database.UnfollowByIDParams{
+	return q.IsFollowing(ctx, database.IsFollowingParams{
 		UserID:     pgtype.UUID{Bytes: user, Valid: true},
 		FollowerID: pgtype.UUID{Bytes: follower, Valid: true},
 	})
 }
+
+func (r PostgresFollowerRepository) CanViewPosts(ctx context.Context, user, viewer uuid.UUID) (bool, error) {
+	q := database.New(r.p)
+
+	return q.CanViewPosts(ctx, database.CanViewPostsParams{
+		UserID:   pgtype.UUID{Bytes: user, Valid: true},
+		ViewerID: pgtype.UUID{Bytes: viewer, Valid: true},
+	})
+}
+
+func (r PostgresFollowerRepository) Request(ctx context.Context, user, requester uuid.UUID) error {
+	q := database.New(r.p)
+
+	return q.CreateFollowRequest(ctx, database.CreateFollowRequestParams{
+		CreatedAt:   pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+		UserID:      pgtype.UUID{Bytes: user, Valid: true},
+		RequesterID: pgtype.UUID{Bytes: requester, Valid: true},
+	})
+}
+
+func (r PostgresFollowerRepository) GetRequests(ctx context.Context, user uuid.UUID) ([]*models.FollowRequest, error) {
+	q := database.New(r.p)
+
+	dbRequests, err := q.ListFollowRequests(ctx, pgtype.UUID{Bytes: user, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBFollowRequestsToFollowRequests(dbRequests), nil
+}
+
+func (r PostgresFollowerRepository) AcceptRequest(ctx context.Context, user, requester uuid.UUID) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(tx)
+
+		// 1. Consume the request
+		deleted, err := q.DeleteFollowRequest(ctx, database.DeleteFollowRequestParams{
+			UserID:      pgtype.UUID{Bytes: user, Valid: true},
+			RequesterID: pgtype.UUID{Bytes: requester, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return storage.ErrNoRows
+		}
+
+		// 2. Follow
+		return q.FollowByID(ctx, database.FollowByIDParams{
+			CreatedAt:  pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+			UserID:     pgtype.UUID{Bytes: user, Valid: true},
+			FollowerID: pgtype.UUID{Bytes: requester, Valid: true},
+		})
+	})
+}
+
+func (r PostgresFollowerRepository) RejectRequest(ctx context.Context, user, requester uuid.UUID) error {
+	q := database.New(r.p)
+
+	deleted, err := q.DeleteFollowRequest(ctx, database.DeleteFollowRequestParams{
+		UserID:      pgtype.UUID{Bytes: user, Valid: true},
+		RequesterID: pgtype.UUID{Bytes: requester, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return storage.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r PostgresFollowerRepository) GetFollowers(ctx context.Context, user uuid.UUID, limit int32, after *models.Follow) ([]*models.Follow, error) {
+	q := database.New(r.p)
+
+	params := database.ListFollowersParams{
+		Limit:  limit,
+		UserID: pgtype.UUID{Bytes: user, Valid: true},
+	}
+	if after != nil {
+		params.Before = pgtype.Timestamp{Time: after.CreatedAt, Valid: true}
+		params.BeforeID = pgtype.UUID{Bytes: after.User.ID, Valid: true}
+	}
+
+	dbFollowers, err := q.ListFollowers(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBFollowersToFollows(dbFollowers), nil
+}
+
+func (r PostgresFollowerRepository) GetFollowing(ctx context.Context, user uuid.UUID, limit int32, after *models.Follow) ([]*models.Follow, error) {
+	q := database.New(r.p)
+
+	params := database.ListFollowingParams{
+		Limit:  limit,
+		UserID: pgtype.UUID{Bytes: user, Valid: true},
+	}
+	if after != nil {
+		params.Before = pgtype.Timestamp{Time: after.CreatedAt, Valid: true}
+		params.BeforeID = pgtype.UUID{Bytes: after.User.ID, Valid: true}
+	}
+
+	dbFollowing, err := q.ListFollowing(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBFollowingToFollows(dbFollowing), nil
+}
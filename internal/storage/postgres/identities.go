@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresIdentityRepository struct {
+	p *pgxpool.Pool
+}
+
+// Fetch the ID of the user linked to an external identity
+func (r PostgresIdentityRepository) GetUserID(ctx context.Context, provider, subject string) (uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
+	defer cancel()
+
+	q := database.New(r.p)
+	identity, err := q.GetUserIdentity(ctx, database.GetUserIdentityParams{
+		Provider: provider,
+		Subject:  subject,
+	})
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return uuid.Nil, storage.ErrNoRows
+		default:
+			return uuid.Nil, err
+		}
+	}
+
+	return identity.UserID.Bytes, nil
+}
+
+// Updates the last login with an external identity
+func (r PostgresIdentityRepository) Touch(ctx context.Context, provider, subject string) error {
+	q := database.New(r.p)
+
+	return q.TouchUserIdentity(ctx, database.TouchUserIdentityParams{
+		Provider:    provider,
+		Subject:     subject,
+		LastLoginAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+}
+
+// Links an external identity to the active user with the same email
+func (r PostgresIdentityRepository) Link(ctx context.Context, identity *models.UserIdentity) (uuid.UUID, error) {
+	var userID uuid.UUID
+
+	if err := withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+
+		// 1. Find user
+		dbUser, err := qtx.GetUserByEmail(ctx, identity.Email)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoUser
+			}
+			return err
+		}
+
+		// 2. Link identity
+		identity.UserID = dbUser.ID.Bytes
+		if err := createUserIdentity(ctx, qtx, identity); err != nil {
+			return err
+		}
+
+		userID = identity.UserID
+		return nil
+	}); err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// Stores an already active user and links the external identity to it
+func (r PostgresIdentityRepository) CreateWithUser(ctx context.Context, user *models.UserWithPassword, identity *models.UserIdentity) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+
+		// 1. Create user
+		if err := (PostgresUserRepository{r.p}).createWithTx(ctx, user, tx); err != nil {
+			return err
+		}
+
+		// 2. Activate it, the provider already verified the email
+		if _, err := qtx.ActivateUser(ctx, pgtype.UUID{Bytes: user.User.ID, Valid: true}); err != nil {
+			return err
+		}
+
+		// 3. Link identity
+		identity.UserID = user.User.ID
+		return createUserIdentity(ctx, qtx, identity)
+	})
+}
+
+func createUserIdentity(ctx context.Context, q *database.Queries, identity *models.UserIdentity) error {
+	if err := q.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		UserID:      pgtype.UUID{Bytes: identity.UserID, Valid: true},
+		Email:       identity.Email,
+		CreatedAt:   pgtype.Timestamp{Time: identity.CreatedAt, Valid: true},
+		LastLoginAt: pgtype.Timestamp{Time: identity.CreatedAt, Valid: true},
+	}); err != nil {
+		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "user_identities_pkey" {
+			return storage.ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Stores a pending login (no transaction)
+func (r PostgresIdentityRepository) CreateState(ctx context.Context, state *models.OIDCState, exp time.Duration) error {
+	q := database.New(r.p)
+
+	return q.CreateOIDCState(ctx, database.CreateOIDCStateParams{
+		StateHash:    hashToken(state.State),
+		Provider:     state.Provider,
+		Nonce:        state.Nonce,
+		CodeVerifier: state.CodeVerifier,
+		ExpiresAt:    pgtype.Timestamp{Time: time.Now().UTC().Add(exp), Valid: true},
+	})
+}
+
+// Consumes a pending login. Each state can only be used once.
+func (r PostgresIdentityRepository) ConsumeState(ctx context.Context, state []byte) (*models.OIDCState, error) {
+	q := database.New(r.p)
+
+	row, err := q.ConsumeOIDCState(ctx, database.ConsumeOIDCStateParams{
+		StateHash: hashToken(state),
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, storage.ErrNoToken
+		default:
+			return nil, err
+		}
+	}
+
+	return &models.OIDCState{
+		State:        state,
+		Provider:     row.Provider,
+		Nonce:        row.Nonce,
+		CodeVerifier: row.CodeVerifier,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresLoginAttemptRepository struct {
+	p *pgxpool.Pool
+}
+
+// Stores a login attempt
+func (r PostgresLoginAttemptRepository) Record(ctx context.Context, a *models.LoginAttempt) error {
+	q := database.New(r.p)
+
+	return q.CreateLoginAttempt(ctx, database.CreateLoginAttemptParams{
+		ID:        pgtype.UUID{Bytes: a.ID, Valid: true},
+		Email:     a.Email,
+		Ip:        a.IP,
+		UserAgent: a.UserAgent,
+		Outcome:   string(a.Outcome),
+		CreatedAt: pgtype.Timestamp{Time: a.CreatedAt, Valid: true},
+	})
+}
+
+// Retrieve login attempts. Empty email or IP means no filter.
+func (r PostgresLoginAttemptRepository) List(ctx context.Context, email, ip string, failedOnly bool, limit, offset int32) ([]*models.LoginAttempt, error) {
+	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
+	defer cancel()
+
+	q := database.New(r.p)
+	dbAttempts, err := q.ListLoginAttempts(ctx, database.ListLoginAttemptsParams{
+		Limit:      limit,
+		Offset:     offset,
+		Email:      pgtype.Text{String: email, Valid: email != ""},
+		Ip:         pgtype.Text{String: ip, Valid: ip != ""},
+		FailedOnly: failedOnly,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBLoginAttemptsToLoginAttempts(dbAttempts), nil
+}
+
+// Returns the time until which logins for the key are locked
+func (r PostgresLoginAttemptRepository) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	q := database.New(r.p)
+
+	lockedUntil, err := q.GetLoginLock(ctx, database.GetLoginLockParams{
+		Key:         key,
+		LockedUntil: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+
+	return lockedUntil.Time, nil
+}
+
+// Counts a failed login for the key
+func (r PostgresLoginAttemptRepository) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	q := database.New(r.p)
+	currentTime := time.Now().UTC()
+
+	failures, err := q.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key:         key,
+		Now:         pgtype.Timestamp{Time: currentTime, Valid: true},
+		WindowStart: pgtype.Timestamp{Time: currentTime.Add(-window), Valid: true},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(failures), nil
+}
+
+// Locks logins for the key
+func (r PostgresLoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	q := database.New(r.p)
+
+	return q.LockLogin(ctx, database.LockLoginParams{
+		Key:         key,
+		LockedUntil: pgtype.Timestamp{Time: until.UTC(), Valid: true},
+	})
+}
+
+// Forgets the failed logins of the key
+func (r PostgresLoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	q := database.New(r.p)
+
+	return q.DeleteLoginThrottle(ctx, key)
+}
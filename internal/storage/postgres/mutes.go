@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresMuteRepository struct {
+	p *pgxpool.Pool
+}
+
+func (r *PostgresMuteRepository) Create(ctx context.Context, m *models.Mute) error {
+	q := database.New(r.p)
+
+	params := database.CreateMuteParams{
+		ID:        pgtype.UUID{Bytes: m.ID, Valid: true},
+		UserID:    pgtype.UUID{Bytes: m.UserID, Valid: true},
+		Kind:      string(m.Kind),
+		Value:     m.Value,
+		CreatedAt: pgtype.Timestamp{Time: m.CreatedAt, Valid: true},
+	}
+	if m.MutedUser != nil {
+		params.MutedUserID = pgtype.UUID{Bytes: m.MutedUser.ID, Valid: true}
+	}
+	if m.ExpiresAt != nil {
+		params.ExpiresAt = pgtype.Timestamp{Time: *m.ExpiresAt, Valid: true}
+	}
+
+	// If it was already muted, the existing ID is returned
+	id, err := q.CreateMute(ctx, params)
+	if err != nil {
+		return err
+	}
+	m.ID = id.Bytes
+
+	return nil
+}
+
+func (r *PostgresMuteRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Mute, error) {
+	q := database.New(r.p)
+
+	dbMutes, err := q.ListMutes(ctx, database.ListMutesParams{
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBMutesToMutes(dbMutes, userID), nil
+}
+
+func (r *PostgresMuteRepository) UpdateExpiration(ctx context.Context, userID, id uuid.UUID, expiresAt *time.Time) error {
+	q := database.New(r.p)
+
+	params := database.UpdateMuteExpirationParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+	}
+	if expiresAt != nil {
+		params.ExpiresAt = pgtype.Timestamp{Time: *expiresAt, Valid: true}
+	}
+
+	updated, err := q.UpdateMuteExpiration(ctx, params)
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return storage.ErrNoRows
+	}
+
+	return nil
+}
+
+func (r *PostgresMuteRepository) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	q := database.New(r.p)
+
+	deleted, err := q.DeleteMute(ctx, database.DeleteMuteParams{
+		ID:     pgtype.UUID{Bytes: id, Valid: true},
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return storage.ErrNoRows
+	}
+
+	return nil
+}
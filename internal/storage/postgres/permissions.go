@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresPermissionRepository struct {
+	p *pgxpool.Pool
+}
+
+// Fetch every permission
+func (r PostgresPermissionRepository) GetAll(ctx context.Context) ([]*models.Permission, error) {
+	q := database.New(r.p)
+
+	dbPermissions, err := q.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBPermissionsToPermissions(dbPermissions), nil
+}
+
+// Fetch the permissions granted to each role
+func (r PostgresPermissionRepository) GetByRole(ctx context.Context) (map[models.RoleType][]string, error) {
+	q := database.New(r.p)
+
+	rows, err := q.ListRolePermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[models.RoleType][]string)
+	for _, row := range rows {
+		role := models.RoleType(row.Role)
+		permissions[role] = append(permissions[role], row.Permission)
+	}
+
+	return permissions, nil
+}
+
+// Grants a permission to a role and stores the audit entry of the change. Granting it twice does nothing.
+func (r PostgresPermissionRepository) Grant(ctx context.Context, role models.RoleType, permission string, entry *models.AuditLogEntry) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+
+		// 1. Find role
+		dbRole, err := qtx.GetRoleByName(ctx, string(role))
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoRows
+			}
+			return err
+		}
+
+		// 2. Grant permission, which must exist
+		if err := qtx.GrantPermission(ctx, database.GrantPermissionParams{
+			RoleID:     dbRole.ID,
+			Permission: permission,
+		}); err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "role_permissions_permission_fkey" {
+				return storage.ErrNoRows
+			}
+			return err
+		}
+
+		// 3. Audit
+		return createAuditLogEntry(ctx, qtx, entry)
+	})
+}
+
+// Revokes a permission of a role and stores the audit entry of the change
+func (r PostgresPermissionRepository) Revoke(ctx context.Context, role models.RoleType, permission string, entry *models.AuditLogEntry) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+
+		// 1. Find role
+		dbRole, err := qtx.GetRoleByName(ctx, string(role))
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoRows
+			}
+			return err
+		}
+
+		// 2. Revoke permission
+		rows, err := qtx.RevokePermission(ctx, database.RevokePermissionParams{
+			RoleID:     dbRole.ID,
+			Permission: permission,
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return storage.ErrNoRows
+		}
+
+		// 3. Audit
+		return createAuditLogEntry(ctx, qtx, entry)
+	})
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresPersonalAccessTokenRepository struct {
+	p *pgxpool.Pool
+}
+
+// Stores a personal access token (no transaction)
+func (r PostgresPersonalAccessTokenRepository) Create(ctx context.Context, t *models.PersonalAccessToken) error {
+	q := database.New(r.p)
+
+	expiresAt := pgtype.Timestamp{}
+	if t.ExpiresAt != nil {
+		expiresAt = pgtype.Timestamp{Time: *t.ExpiresAt, Valid: true}
+	}
+
+	return q.CreatePersonalAccessToken(ctx, database.CreatePersonalAccessTokenParams{
+		ID:        pgtype.UUID{Bytes: t.ID, Valid: true},
+		UserID:    pgtype.UUID{Bytes: t.UserID, Valid: true},
+		Name:      t.Name,
+		TokenHash: hashToken([]byte(t.Token)),
+		Scopes:    t.Scopes,
+		CreatedAt: pgtype.Timestamp{Time: t.CreatedAt, Valid: true},
+		ExpiresAt: expiresAt,
+	})
+}
+
+// Fetch a non-revoked token by its plain value. Expiration is left to the caller.
+func (r PostgresPersonalAccessTokenRepository) GetByToken(ctx context.Context, token string) (*models.PersonalAccessToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
+	defer cancel()
+
+	q := database.New(r.p)
+	dbToken, err := q.GetPersonalAccessTokenByHash(ctx, hashToken([]byte(token)))
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, storage.ErrNoToken
+		default:
+			return nil, err
+		}
+	}
+
+	return models.DBPersonalAccessTokenToPersonalAccessToken(dbToken), nil
+}
+
+// Fetch the non-revoked tokens of a user, newest first
+func (r PostgresPersonalAccessTokenRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.PersonalAccessToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
+	defer cancel()
+
+	q := database.New(r.p)
+	dbTokens, err := q.ListPersonalAccessTokensByUser(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBPersonalAccessTokensToPersonalAccessTokens(dbTokens), nil
+}
+
+// Updates the last time the token was used
+func (r PostgresPersonalAccessTokenRepository) Touch(ctx context.Context, id uuid.UUID) error {
+	q := database.New(r.p)
+
+	return q.TouchPersonalAccessToken(ctx, database.TouchPersonalAccessTokenParams{
+		ID:         pgtype.UUID{Bytes: id, Valid: true},
+		LastUsedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+}
+
+// Revokes a token of a user
+func (r PostgresPersonalAccessTokenRepository) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	q := database.New(r.p)
+
+	rows, err := q.RevokePersonalAccessToken(ctx, database.RevokePersonalAccessTokenParams{
+		ID:        pgtype.UUID{Bytes: id, Valid: true},
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		RevokedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return storage.ErrNoRows
+	}
+
+	return nil
+}
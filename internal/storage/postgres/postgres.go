@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/maxolivera/gophis-social-network/internal/storage"
 )
@@ -14,7 +15,20 @@ func NewPostgresStorage(p *pgxpool.Pool) *storage.Storage {
 		Users:     &PostgresUserRepository{p},
 		Comments:  &PostgresCommentRepository{p},
 		Followers: &PostgresFollowerRepository{p},
+		Blocks:    &PostgresBlockRepository{p},
+		Mutes:     &PostgresMuteRepository{p},
 		Roles:     &PostgresRoleRepository{p},
+		Tokens:    &PostgresTokenRepository{p},
+		TwoFactor: &PostgresTwoFactorRepository{p},
+
+		PersonalAccessTokens: &PostgresPersonalAccessTokenRepository{p},
+		LoginAttempts:        &PostgresLoginAttemptRepository{p},
+		Identities:           &PostgresIdentityRepository{p},
+		Sessions:             &PostgresSessionRepository{p},
+		AuditLog:             &PostgresAuditLogRepository{p},
+		Permissions:          &PostgresPermissionRepository{p},
+		Suggestions:          &PostgresSuggestionRepository{p},
+		Exports:              &PostgresExportRepository{p},
 	}
 }
 
@@ -31,3 +45,11 @@ func withTx(pool *pgxpool.Pool, ctx context.Context, fn func(pgx.Tx) error) erro
 
 	return tx.Commit(ctx)
 }
+
+// Nil is not set, so the column does not change
+func optionalText(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
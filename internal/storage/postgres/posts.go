@@ -34,6 +34,16 @@ func (r *PostgresPostRepository) GetByID(ctx context.Context, id uuid.UUID) (*mo
 	return post, nil
 }
 
+func (r *PostgresPostRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Post, error) {
+	q := database.New(r.p)
+	dbPosts, err := q.GetPostByUser(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBPostsToPost(dbPosts), nil
+}
+
 func (r *PostgresPostRepository) Create(ctx context.Context, p *models.Post) error {
 	q := database.New(r.p)
 
@@ -52,46 +62,162 @@ func (r *PostgresPostRepository) SoftDelete(ctx context.Context, p *models.Post)
 	q := database.New(r.p)
 
 	deleted, err := q.SoftDeletePostByID(ctx, database.SoftDeletePostByIDParams{
-		ID:      pgtype.UUID{Bytes: p.ID, Valid: true},
-		Version: p.Version,
+		ID:        pgtype.UUID{Bytes: p.ID, Valid: true},
+		Version:   p.Version,
+		DeletedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
 	})
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return fmt.Errorf("post was not deleted because was not found, post_id: %v", p.ID)
-		}
 		return fmt.Errorf("post could not deleted: %v", err)
 	}
-	if !deleted {
-		return fmt.Errorf("post was not deleted, post_id: %v", p.ID)
+	if deleted == 0 {
+		return storage.ErrNoRows
 	}
 
 	return nil
 }
 
+func (r *PostgresPostRepository) GetDeleted(ctx context.Context, username string, since, until *time.Time, limit, offset int32) ([]*models.Post, error) {
+	q := database.New(r.p)
+	params := database.ListDeletedPostsParams{
+		Limit:    limit,
+		Offset:   offset,
+		Username: pgtype.Text{String: username, Valid: username != ""},
+	}
+	if since != nil {
+		params.Since = pgtype.Timestamp{Time: *since, Valid: true}
+	}
+	if until != nil {
+		params.Until = pgtype.Timestamp{Time: *until, Valid: true}
+	}
+
+	dbPosts, err := q.ListDeletedPosts(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBPostsToPost(dbPosts), nil
+}
+
+func (r *PostgresPostRepository) Restore(ctx context.Context, id uuid.UUID, entry *models.AuditLogEntry) (*models.Post, error) {
+	var post *models.Post
+
+	if err := withTx(r.p, ctx, func(tx pgx.Tx) error {
+		qtx := database.New(tx)
+
+		// 1. Restore post
+		dbPost, err := qtx.RestorePost(ctx, database.RestorePostParams{
+			UpdatedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+			ID:        pgtype.UUID{Bytes: id, Valid: true},
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoRows
+			}
+			return err
+		}
+		post = models.DBPostToPost(dbPost)
+
+		// 2. Audit
+		return createAuditLogEntry(ctx, qtx, entry)
+	}); err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
 func (r *PostgresPostRepository) HardDelete(ctx context.Context, p *models.Post) error {
 	q := database.New(r.p)
-	if err := q.HardDeletePostByID(ctx, database.HardDeletePostByIDParams{
+	deleted, err := q.HardDeletePostByID(ctx, database.HardDeletePostByIDParams{
 		ID:      pgtype.UUID{Bytes: p.ID, Valid: true},
 		Version: p.Version,
-	}); err != nil {
-		if err == pgx.ErrNoRows {
-			return storage.ErrNoRows
-		}
+	})
+	if err != nil {
 		return err
 	}
+	if deleted == 0 {
+		return storage.ErrNoRows
+	}
 
 	return nil
 }
 
 func (r *PostgresPostRepository) Update(ctx context.Context, p *models.Post) (*models.Post, error) {
+	var post *models.Post
+	id := pgtype.UUID{Bytes: p.ID, Valid: true}
+
+	if err := withTx(r.p, ctx, func(tx pgx.Tx) error {
+		qtx := database.New(tx)
+		currentTime := pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+
+		// 1. Lock post, so concurrent edits can not keep the same version
+		old, err := qtx.LockPost(ctx, database.LockPostParams{
+			ID:      id,
+			Version: p.Version,
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoRows
+			}
+			return err
+		}
+
+		// 2. Keep the current content as a revision
+		revisionTime := old.CreatedAt
+		if old.EditedAt.Valid {
+			revisionTime = old.EditedAt
+		}
+		if err := qtx.CreatePostRevision(ctx, database.CreatePostRevisionParams{
+			PostID:    id,
+			Version:   old.Version,
+			Title:     old.Title,
+			Content:   old.Content,
+			Tags:      old.Tags,
+			CreatedAt: revisionTime,
+		}); err != nil {
+			return err
+		}
+
+		// 3. Update post
+		dbPost, err := qtx.UpdatePost(ctx, database.UpdatePostParams{
+			UpdatedAt: currentTime,
+			ID:        id,
+			Content:   pgtype.Text{String: p.Content, Valid: len(p.Content) > 0},
+			Title:     pgtype.Text{String: p.Title, Valid: len(p.Title) > 0},
+			Tags:      p.Tags,
+			Version:   p.Version,
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoRows
+			}
+			return err
+		}
+		post = models.DBPostToPost(dbPost)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return post, nil
+}
+
+func (r *PostgresPostRepository) GetRevisions(ctx context.Context, id uuid.UUID) ([]*models.PostRevision, error) {
 	q := database.New(r.p)
-	dbPost, err := q.UpdatePost(ctx, database.UpdatePostParams{
-		UpdatedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
-		ID:        pgtype.UUID{Bytes: p.ID, Valid: true},
-		Content:   pgtype.Text{String: p.Content, Valid: len(p.Content) > 0},
-		Title:     pgtype.Text{String: p.Title, Valid: len(p.Title) > 0},
-		Tags:      p.Tags,
-		Version:   p.Version,
+	dbRevisions, err := q.ListPostRevisions(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBPostRevisionsToPostRevisions(dbRevisions), nil
+}
+
+func (r *PostgresPostRepository) GetRevision(ctx context.Context, id uuid.UUID, version int32) (*models.PostRevision, error) {
+	q := database.New(r.p)
+	dbRevision, err := q.GetPostRevision(ctx, database.GetPostRevisionParams{
+		PostID:  pgtype.UUID{Bytes: id, Valid: true},
+		Version: version,
 	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -100,15 +226,14 @@ func (r *PostgresPostRepository) Update(ctx context.Context, p *models.Post) (*m
 		return nil, err
 	}
 
-	post := models.DBPostToPost(dbPost)
-
-	return post, nil
+	return models.DBPostRevisionToPostRevision(dbRevision), nil
 }
 
 func (r *PostgresPostRepository) GetFeed(ctx context.Context, u *models.User, sort bool, limit, offset int32) ([]*models.Feed, error) {
 	q := database.New(r.p)
 	dbFeed, err := q.GetUserFeed(ctx, database.GetUserFeedParams{
 		UserID: pgtype.UUID{Bytes: u.ID, Valid: true},
+		Now:    pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
 		Sort:   sort,
 		Limit:  int32(limit),
 		Offset: int32(offset),
@@ -128,7 +253,7 @@ func (r *PostgresPostRepository) GetFeed(ctx context.Context, u *models.User, so
 	return feed, nil
 }
 
-func (r *PostgresPostRepository) Search(ctx context.Context, word string, tags []string, limit, offset int32, sort bool, since, until *time.Time) ([]*models.Feed, error) {
+func (r *PostgresPostRepository) Search(ctx context.Context, u *models.User, word string, tags []string, limit, offset int32, sort bool, since, until *time.Time) ([]*models.Feed, error) {
 	q := database.New(r.p)
 	params := database.SearchPostsParams{
 		Search: "",
@@ -136,6 +261,9 @@ func (r *PostgresPostRepository) Search(ctx context.Context, word string, tags [
 		Limit:  10,    // Default limit
 		Offset: 0,     // Default offset
 		Sort:   false, // Default sort order
+		// Posts of private users are only visible to their followers
+		ViewerID: pgtype.UUID{Bytes: u.ID, Valid: true},
+		Now:      pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
 	}
 
 	if tags != nil {
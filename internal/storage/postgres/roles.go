@@ -2,8 +2,12 @@ package postgres
 
 import (
 	"context"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/maxolivera/gophis-social-network/internal/database"
 	"github.com/maxolivera/gophis-social-network/internal/storage"
@@ -30,3 +34,73 @@ func (r PostgresRoleRepository) GetByName(ctx context.Context, name string) (*mo
 		Level: int(dbRole.Level),
 	}, nil
 }
+
+// Fetch every role, by level
+func (r PostgresRoleRepository) GetAll(ctx context.Context) ([]*models.Role, error) {
+	q := database.New(r.p)
+
+	dbRoles, err := q.GetRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBRolesToRoles(dbRoles), nil
+}
+
+// Stores a role, filling its ID, and the audit entry of the change
+func (r PostgresRoleRepository) Create(ctx context.Context, role *models.Role, entry *models.AuditLogEntry) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+
+		// 1. Store role
+		dbRole, err := qtx.CreateRole(ctx, database.CreateRoleParams{
+			Name:        string(role.Name),
+			Level:       int32(role.Level),
+			Description: role.Description,
+		})
+		if err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "roles_name_key" {
+				return storage.ErrConflict
+			}
+			return err
+		}
+		role.ID = int(dbRole.ID)
+
+		// 2. Audit
+		return createAuditLogEntry(ctx, qtx, entry)
+	})
+}
+
+// Changes the role of a user and stores the audit entry of the change
+func (r PostgresRoleRepository) AssignToUser(ctx context.Context, userID uuid.UUID, name models.RoleType, entry *models.AuditLogEntry) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+
+		// 1. Find role
+		dbRole, err := qtx.GetRoleByName(ctx, string(name))
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoRows
+			}
+			return err
+		}
+
+		// 2. Change role
+		rows, err := qtx.UpdateUserRole(ctx, database.UpdateUserRoleParams{
+			UpdatedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+			RoleID:    dbRole.ID,
+			ID:        pgtype.UUID{Bytes: userID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return storage.ErrNoUser
+		}
+
+		// 3. Audit
+		return createAuditLogEntry(ctx, qtx, entry)
+	})
+}
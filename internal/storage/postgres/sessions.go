@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresSessionRepository struct {
+	p *pgxpool.Pool
+}
+
+// Stores a session and the first refresh token of its family
+func (r PostgresSessionRepository) Create(ctx context.Context, s *models.Session, t *models.RefreshToken) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+
+		// 1. Store session
+		if err := qtx.CreateSession(ctx, database.CreateSessionParams{
+			ID:         pgtype.UUID{Bytes: s.ID, Valid: true},
+			UserID:     pgtype.UUID{Bytes: s.UserID, Valid: true},
+			Ip:         s.IP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  pgtype.Timestamp{Time: s.CreatedAt, Valid: true},
+			LastSeenAt: pgtype.Timestamp{Time: s.LastSeenAt, Valid: true},
+		}); err != nil {
+			return err
+		}
+
+		// 2. Store refresh token
+		t.FamilyID = s.ID
+		t.UserID = s.UserID
+		return createRefreshToken(ctx, qtx, t)
+	})
+}
+
+// Updates the last time the session was seen, and from where
+func (r PostgresSessionRepository) Touch(ctx context.Context, id uuid.UUID, ip, userAgent string) error {
+	q := database.New(r.p)
+
+	return q.TouchSession(ctx, database.TouchSessionParams{
+		ID:         pgtype.UUID{Bytes: id, Valid: true},
+		LastSeenAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+		Ip:         ip,
+		UserAgent:  userAgent,
+	})
+}
+
+// Fetch the active sessions of a user, most recently seen first
+func (r PostgresSessionRepository) GetByUser(ctx context.Context, userID uuid.UUID) ([]*models.Session, error) {
+	q := database.New(r.p)
+
+	dbSessions, err := q.ListActiveSessionsByUser(ctx, database.ListActiveSessionsByUserParams{
+		UserID: pgtype.UUID{Bytes: userID, Valid: true},
+		Now:    pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBSessionsToSessions(dbSessions), nil
+}
+
+// Revokes a session of a user and every refresh token of its family
+func (r PostgresSessionRepository) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+		currentTime := time.Now().UTC()
+
+		// 1. Revoke session, which must belong to the user
+		rows, err := qtx.RevokeSession(ctx, database.RevokeSessionParams{
+			ID:        pgtype.UUID{Bytes: id, Valid: true},
+			UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+			RevokedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return storage.ErrNoRows
+		}
+
+		// 2. Revoke refresh tokens
+		return qtx.RevokeRefreshTokenFamily(ctx, database.RevokeRefreshTokenFamilyParams{
+			FamilyID:  pgtype.UUID{Bytes: id, Valid: true},
+			RevokedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+		})
+	})
+}
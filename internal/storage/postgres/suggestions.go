@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresSuggestionRepository struct {
+	p *pgxpool.Pool
+}
+
+func (r PostgresSuggestionRepository) Refresh(ctx context.Context, since time.Time, authorsPerTag, maxPerUser int32) (int64, error) {
+	var refreshed int64
+
+	err := withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(tx)
+
+		// 1. Make sure no one else is refreshing them
+		locked, err := q.TryLockSuggestions(ctx)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			return storage.ErrInProgress
+		}
+
+		// 2. Forget the previous suggestions
+		if err := q.DeleteSuggestions(ctx); err != nil {
+			return err
+		}
+
+		// 3. Compute them again
+		refreshed, err = q.RefreshSuggestions(ctx, database.RefreshSuggestionsParams{
+			Since:         pgtype.Timestamp{Time: since, Valid: true},
+			AuthorsPerTag: authorsPerTag,
+			Now:           pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+			MaxPerUser:    maxPerUser,
+		})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return refreshed, nil
+}
+
+func (r PostgresSuggestionRepository) GetByUser(ctx context.Context, user uuid.UUID, limit int32) ([]*models.Suggestion, error) {
+	q := database.New(r.p)
+
+	dbSuggestions, err := q.ListSuggestions(ctx, database.ListSuggestionsParams{
+		Limit:  limit,
+		UserID: pgtype.UUID{Bytes: user, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBSuggestionsToSuggestions(dbSuggestions), nil
+}
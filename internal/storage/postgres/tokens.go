@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresTokenRepository struct {
+	p *pgxpool.Pool
+}
+
+func hashToken(token []byte) []byte {
+	hash := sha256.Sum256(token)
+	return hash[:]
+}
+
+// Stores a refresh token (no transaction)
+func (r PostgresTokenRepository) Create(ctx context.Context, t *models.RefreshToken) error {
+	q := database.New(r.p)
+
+	return createRefreshToken(ctx, q, t)
+}
+
+func createRefreshToken(ctx context.Context, q *database.Queries, t *models.RefreshToken) error {
+	return q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		ID:        pgtype.UUID{Bytes: t.ID, Valid: true},
+		FamilyID:  pgtype.UUID{Bytes: t.FamilyID, Valid: true},
+		UserID:    pgtype.UUID{Bytes: t.UserID, Valid: true},
+		TokenHash: hashToken(t.Token),
+		CreatedAt: pgtype.Timestamp{Time: t.CreatedAt, Valid: true},
+		ExpiresAt: pgtype.Timestamp{Time: t.ExpiresAt, Valid: true},
+	})
+}
+
+// Consumes a refresh token and stores its replacement. The replacement inherits the family and user of
+// the consumed token. Presenting a token that was already used (or revoked) revokes the whole family.
+func (r PostgresTokenRepository) Rotate(ctx context.Context, token []byte, next *models.RefreshToken) (*models.RefreshToken, error) {
+	var current *models.RefreshToken
+	reused := false
+
+	if err := withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+		currentTime := time.Now().UTC()
+
+		// 1. Find token
+		dbToken, err := qtx.GetRefreshTokenByHash(ctx, hashToken(token))
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoToken
+			}
+			return err
+		}
+
+		// 2. Reuse detection: revoke every token of the family.
+		// NOTE(maolivera): The transaction must be commited, so the error is returned after it.
+		if dbToken.UsedAt.Valid || dbToken.RevokedAt.Valid {
+			reused = true
+			return qtx.RevokeRefreshTokenFamily(ctx, database.RevokeRefreshTokenFamilyParams{
+				FamilyID:  dbToken.FamilyID,
+				RevokedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+			})
+		}
+
+		if currentTime.After(dbToken.ExpiresAt.Time) {
+			return storage.ErrNoToken
+		}
+
+		// 3. Mark as used
+		if err := qtx.MarkRefreshTokenUsed(ctx, database.MarkRefreshTokenUsedParams{
+			ID:     dbToken.ID,
+			UsedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+		}); err != nil {
+			return err
+		}
+
+		// 4. Store replacement
+		current = models.DBRefreshTokenToRefreshToken(dbToken)
+		next.FamilyID = current.FamilyID
+		next.UserID = current.UserID
+
+		return createRefreshToken(ctx, qtx, next)
+	}); err != nil {
+		return nil, err
+	}
+
+	if reused {
+		return nil, storage.ErrTokenReused
+	}
+
+	return current, nil
+}
+
+// Revokes every refresh token of a family
+func (r PostgresTokenRepository) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	q := database.New(r.p)
+
+	return q.RevokeRefreshTokenFamily(ctx, database.RevokeRefreshTokenFamilyParams{
+		FamilyID:  pgtype.UUID{Bytes: familyID, Valid: true},
+		RevokedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+}
+
+// Revokes every refresh token of a user
+func (r PostgresTokenRepository) RevokeByUser(ctx context.Context, userID uuid.UUID) error {
+	q := database.New(r.p)
+
+	return q.RevokeRefreshTokensByUser(ctx, database.RevokeRefreshTokensByUserParams{
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		RevokedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+}
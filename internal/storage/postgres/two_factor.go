@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/maxolivera/gophis-social-network/internal/database"
+	"github.com/maxolivera/gophis-social-network/internal/storage"
+	"github.com/maxolivera/gophis-social-network/internal/storage/models"
+)
+
+type PostgresTwoFactorRepository struct {
+	p *pgxpool.Pool
+}
+
+// Fetch the TOTP secret of a user
+func (r PostgresTwoFactorRepository) Get(ctx context.Context, userID uuid.UUID) (*models.TOTP, error) {
+	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
+	defer cancel()
+
+	q := database.New(r.p)
+	dbTOTP, err := q.GetTOTPByUser(ctx, pgtype.UUID{Bytes: userID, Valid: true})
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, storage.ErrNoRows
+		default:
+			return nil, err
+		}
+	}
+
+	return models.DBTOTPToTOTP(dbTOTP), nil
+}
+
+// Fetch the TOTP secrets of the given size, in bytes
+func (r PostgresTwoFactorRepository) ListBySecretSize(ctx context.Context, size int32) ([]*models.TOTP, error) {
+	q := database.New(r.p)
+
+	dbTOTPs, err := q.ListTOTPsBySecretSize(ctx, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.DBTOTPsToTOTPs(dbTOTPs), nil
+}
+
+// Replaces the TOTP secret of a user, only if it is still the old one
+func (r PostgresTwoFactorRepository) ReplaceSecret(ctx context.Context, userID uuid.UUID, oldSecret, newSecret []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
+	defer cancel()
+
+	q := database.New(r.p)
+	replaced, err := q.ReplaceTOTPSecret(ctx, database.ReplaceTOTPSecretParams{
+		Secret:    newSecret,
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		OldSecret: oldSecret,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return replaced > 0, nil
+}
+
+// Stores a new TOTP secret, replacing any unconfirmed one
+func (r PostgresTwoFactorRepository) Enroll(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+		id := pgtype.UUID{Bytes: userID, Valid: true}
+
+		// 1. Check it is not enabled
+		dbTOTP, err := qtx.GetTOTPByUser(ctx, id)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+		if err == nil && dbTOTP.ConfirmedAt.Valid {
+			return storage.ErrConflict
+		}
+
+		// 2. Store secret
+		return qtx.UpsertTOTP(ctx, database.UpsertTOTPParams{
+			UserID:    id,
+			Secret:    secret,
+			CreatedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+		})
+	})
+}
+
+// Enables two-factor and replaces the recovery codes. Only the hashes of the codes are stored.
+func (r PostgresTwoFactorRepository) Confirm(ctx context.Context, userID uuid.UUID, step int64, recoveryCodes [][]byte) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+		id := pgtype.UUID{Bytes: userID, Valid: true}
+
+		// 1. Confirm secret
+		if err := qtx.ConfirmTOTP(ctx, database.ConfirmTOTPParams{
+			UserID:       id,
+			ConfirmedAt:  pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+			LastUsedStep: step,
+		}); err != nil {
+			return err
+		}
+
+		// 2. Replace recovery codes
+		if err := qtx.DeleteRecoveryCodesByUser(ctx, id); err != nil {
+			return err
+		}
+		for _, code := range recoveryCodes {
+			if err := qtx.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+				CodeHash: hashToken(code),
+				UserID:   id,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Marks a time step as used, so a code cannot be replayed while it is still valid
+func (r PostgresTwoFactorRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	q := database.New(r.p)
+
+	rows, err := q.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		UserID:       pgtype.UUID{Bytes: userID, Valid: true},
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Consumes a recovery code
+func (r PostgresTwoFactorRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, code []byte) (bool, error) {
+	q := database.New(r.p)
+
+	rows, err := q.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		CodeHash: hashToken(code),
+		UserID:   pgtype.UUID{Bytes: userID, Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Deletes the TOTP secret and the recovery codes
+func (r PostgresTwoFactorRepository) Disable(ctx context.Context, userID uuid.UUID) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+		id := pgtype.UUID{Bytes: userID, Valid: true}
+
+		if err := qtx.DeleteRecoveryCodesByUser(ctx, id); err != nil {
+			return err
+		}
+
+		return qtx.DeleteTOTP(ctx, id)
+	})
+}
+
+// Stores a login challenge (no transaction)
+func (r PostgresTwoFactorRepository) CreateChallenge(ctx context.Context, userID uuid.UUID, token []byte, exp time.Duration) error {
+	q := database.New(r.p)
+
+	return q.CreateTwoFactorChallenge(ctx, database.CreateTwoFactorChallengeParams{
+		TokenHash: hashToken(token),
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(exp), Valid: true},
+	})
+}
+
+// Consumes a login challenge. Each challenge can only be used once, whether the code is right or not.
+func (r PostgresTwoFactorRepository) ConsumeChallenge(ctx context.Context, token []byte) (uuid.UUID, error) {
+	q := database.New(r.p)
+
+	userID, err := q.ConsumeTwoFactorChallenge(ctx, database.ConsumeTwoFactorChallengeParams{
+		TokenHash: hashToken(token),
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return uuid.Nil, storage.ErrNoToken
+		default:
+			return uuid.Nil, err
+		}
+	}
+
+	return userID.Bytes, nil
+}
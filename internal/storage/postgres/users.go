@@ -40,6 +40,26 @@ func (r PostgresUserRepository) GetByUsername(ctx context.Context, username stri
 	return user, nil
 }
 
+// Fetch a user by ID
+func (r PostgresUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
+	defer cancel()
+
+	q := database.New(r.p)
+	dbUser, err := q.GetUserByID(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		switch err {
+		case pgx.ErrNoRows:
+			return nil, storage.ErrNoRows
+		default:
+			return nil, err
+		}
+	}
+
+	user := models.DBUserWithRoleToUser(database.GetUserByUsernameRow(dbUser))
+	return user, nil
+}
+
 // Fetch a user by email
 func (r PostgresUserRepository) GetByEmailAndPassword(ctx context.Context, email, pass string) (*models.User, error) {
 	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
@@ -59,6 +79,10 @@ func (r PostgresUserRepository) GetByEmailAndPassword(ctx context.Context, email
 	// compare password
 	err = bcrypt.CompareHashAndPassword(dbUser.Password, []byte(pass))
 	if err != nil {
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			// NOTE(maolivera): Same error as an unknown email, so callers can not tell them apart
+			return nil, storage.ErrNoRows
+		}
 		err := fmt.Errorf("error when comparing passwords: %v", err)
 		return nil, err
 	}
@@ -191,11 +215,375 @@ func (r PostgresUserRepository) Activate(ctx context.Context, token []byte) (*mo
 	return user, nil
 }
 
-// Mark a user as deleted
-func (r PostgresUserRepository) SoftDelete(ctx context.Context, id uuid.UUID) error {
+// Fetch the invitations of a user which did not expire
+func (r PostgresUserRepository) GetInvitations(ctx context.Context, userID uuid.UUID) ([]*models.Invitation, error) {
+	q := database.New(r.p)
+	dbExpirations, err := q.GetInvitationsByUser(ctx, database.GetInvitationsByUserParams{
+		UserID:    pgtype.UUID{Bytes: userID, Valid: true},
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := make([]*models.Invitation, len(dbExpirations))
+	for i, expiresAt := range dbExpirations {
+		invitations[i] = &models.Invitation{ExpiresAt: expiresAt.Time}
+	}
+
+	return invitations, nil
+}
+
+// Stores a password reset token for the active user with the given email. Only the hash of the token is stored.
+func (r PostgresUserRepository) CreatePasswordReset(ctx context.Context, email string, token []byte, resetExp time.Duration) (*models.User, error) {
+	q := database.New(r.p)
+
+	dbUser, err := q.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, storage.ErrNoUser
+		}
+		return nil, err
+	}
+
+	if err := q.CreatePasswordReset(ctx, database.CreatePasswordResetParams{
+		TokenHash: hashToken(token),
+		UserID:    dbUser.ID,
+		ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(resetExp), Valid: true},
+	}); err != nil {
+		return nil, err
+	}
+
+	return models.DBUserToUser(dbUser), nil
+}
+
+// Changes the password of the user who owns the reset token. Every reset token of the user and every
+// refresh token are invalidated.
+func (r PostgresUserRepository) ResetPassword(ctx context.Context, token []byte, password string) (*models.User, error) {
+	var user *models.User
+
+	// hash password
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+		currentTime := time.Now().UTC()
+
+		// 1. Consume token, in the same statement so concurrent requests can not both use it
+		id, err := qtx.ConsumePasswordReset(ctx, database.ConsumePasswordResetParams{
+			TokenHash: hashToken(token),
+			ExpiresAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoToken
+			} else {
+				return err
+			}
+		}
+
+		// 2. Change password
+		dbUser, err := qtx.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+			UpdatedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+			Password:  hashed,
+			ID:        id,
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoUser
+			} else {
+				return err
+			}
+		}
+
+		// 3. Delete the other tokens of the user
+		if err = qtx.DeletePasswordResetsByUser(ctx, id); err != nil {
+			return err
+		}
+
+		// 4. Invalidate sessions
+		if err = qtx.RevokeRefreshTokensByUser(ctx, database.RevokeRefreshTokensByUserParams{
+			UserID:    id,
+			RevokedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+		}); err != nil {
+			return err
+		}
+
+		// 5. Invalidate personal access tokens, they could have been created by whoever knew the old password
+		if err = qtx.RevokePersonalAccessTokensByUser(ctx, database.RevokePersonalAccessTokensByUserParams{
+			UserID:    id,
+			RevokedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+		}); err != nil {
+			return err
+		}
+
+		user = models.DBUserToUser(dbUser)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Stores a pending email change for the user, replacing any previous one. Only the hash of the token is stored.
+func (r PostgresUserRepository) CreateEmailChange(ctx context.Context, userID uuid.UUID, email string, token []byte, changeExp time.Duration) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+		id := pgtype.UUID{Bytes: userID, Valid: true}
+
+		// 1. Forget previous changes, only the last requested address can be confirmed
+		if err := qtx.DeleteEmailChangesByUser(ctx, id); err != nil {
+			return err
+		}
+
+		// 2. Store change
+		return qtx.CreateEmailChange(ctx, database.CreateEmailChangeParams{
+			TokenHash: hashToken(token),
+			UserID:    id,
+			NewEmail:  email,
+			ExpiresAt: pgtype.Timestamp{Time: time.Now().UTC().Add(changeExp), Valid: true},
+		})
+	})
+}
+
+// Changes the email of the user who owns the change token, and deletes the pending changes of the user
+func (r PostgresUserRepository) ConfirmEmailChange(ctx context.Context, token []byte) (*models.User, error) {
+	var user *models.User
+
+	if err := withTx(r.p, ctx, func(tx pgx.Tx) error {
+		q := database.New(r.p)
+		qtx := q.WithTx(tx)
+		currentTime := time.Now().UTC()
+
+		// 1. Validate token
+		change, err := qtx.GetEmailChange(ctx, database.GetEmailChangeParams{
+			TokenHash: hashToken(token),
+			ExpiresAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+		})
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoToken
+			} else {
+				return err
+			}
+		}
+
+		// 2. Change email
+		dbUser, err := qtx.UpdateUserEmail(ctx, database.UpdateUserEmailParams{
+			UpdatedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
+			Email:     change.NewEmail,
+			ID:        change.UserID,
+		})
+		if err != nil {
+			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.ConstraintName == "users_email_key" {
+				return storage.ErrEmailUnavailable
+			}
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoUser
+			}
+			return err
+		}
+
+		// 3. Delete tokens, they are single-use
+		if err = qtx.DeleteEmailChangesByUser(ctx, change.UserID); err != nil {
+			return err
+		}
+
+		user = models.DBUserToUser(dbUser)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Makes a user private or public. Pending follow requests are accepted when the user becomes public.
+func (r PostgresUserRepository) SetPrivate(ctx context.Context, id uuid.UUID, private bool) error {
+	pgID := pgtype.UUID{Bytes: id, Valid: true}
+
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		qtx := database.New(tx)
+
+		// 1. Update user
+		updated, err := qtx.UpdateUserPrivacy(ctx, database.UpdateUserPrivacyParams{
+			UpdatedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+			IsPrivate: private,
+			ID:        pgID,
+		})
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return storage.ErrNoUser
+		}
+		if private {
+			return nil
+		}
+
+		// 2. Accept pending requests, there is nothing left to approve
+		if err := qtx.AcceptFollowRequests(ctx, pgID); err != nil {
+			return err
+		}
+
+		return qtx.DeleteFollowRequestsByUser(ctx, pgID)
+	})
+}
+
+// Schedules the purge of a user, who can not use its personal access tokens meanwhile
+func (r PostgresUserRepository) ScheduleDeletion(ctx context.Context, id uuid.UUID, at time.Time) error {
+	pgID := pgtype.UUID{Bytes: id, Valid: true}
+
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		qtx := database.New(tx)
+		currentTime := pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+
+		// 1. Schedule deletion
+		scheduled, err := qtx.ScheduleUserDeletion(ctx, database.ScheduleUserDeletionParams{
+			UpdatedAt:           currentTime,
+			DeletionScheduledAt: pgtype.Timestamp{Time: at, Valid: true},
+			ID:                  pgID,
+		})
+		if err != nil {
+			return err
+		}
+		if scheduled == 0 {
+			return storage.ErrNoUser
+		}
+
+		// 2. Invalidate personal access tokens, logging in is the only way to cancel the deletion
+		return qtx.RevokePersonalAccessTokensByUser(ctx, database.RevokePersonalAccessTokensByUserParams{
+			UserID:    pgID,
+			RevokedAt: currentTime,
+		})
+	})
+}
+
+// Cancels the scheduled purge of a user
+func (r PostgresUserRepository) CancelDeletion(ctx context.Context, id uuid.UUID) error {
 	q := database.New(r.p)
 
-	return q.SoftDeleteUserByID(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	cancelled, err := q.CancelUserDeletion(ctx, database.CancelUserDeletionParams{
+		UpdatedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+		ID:        pgtype.UUID{Bytes: id, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if cancelled == 0 {
+		return storage.ErrNoUser
+	}
+
+	return nil
+}
+
+// Fetch the soft deleted users, which can still be restored
+func (r PostgresUserRepository) GetDeleted(ctx context.Context, username string, since, until *time.Time, limit, offset int32) ([]*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, storage.QueryTimeDuration)
+	defer cancel()
+
+	q := database.New(r.p)
+	params := database.ListDeletedUsersParams{
+		Limit:    limit,
+		Offset:   offset,
+		Username: pgtype.Text{String: username, Valid: username != ""},
+	}
+	if since != nil {
+		params.Since = pgtype.Timestamp{Time: *since, Valid: true}
+	}
+	if until != nil {
+		params.Until = pgtype.Timestamp{Time: *until, Valid: true}
+	}
+
+	dbUsers, err := q.ListDeletedUsers(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*models.User, len(dbUsers))
+	for i, dbUser := range dbUsers {
+		users[i] = models.DBUserWithRoleToUser(database.GetUserByUsernameRow(dbUser))
+	}
+	return users, nil
+}
+
+// Undoes the soft deletion of a user
+func (r PostgresUserRepository) Restore(ctx context.Context, id uuid.UUID, entry *models.AuditLogEntry) error {
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		qtx := database.New(tx)
+
+		// 1. Restore user
+		restored, err := qtx.RestoreUser(ctx, database.RestoreUserParams{
+			UpdatedAt: pgtype.Timestamp{Time: time.Now().UTC(), Valid: true},
+			ID:        pgtype.UUID{Bytes: id, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+		if restored == 0 {
+			return storage.ErrNoUser
+		}
+
+		// 2. Audit
+		return createAuditLogEntry(ctx, qtx, entry)
+	})
+}
+
+// Fetch the users whose purge is due
+func (r PostgresUserRepository) GetToPurge(ctx context.Context, before time.Time, limit int32) ([]*models.ReducedUser, error) {
+	q := database.New(r.p)
+
+	dbUsers, err := q.ListUsersToPurge(ctx, database.ListUsersToPurgeParams{
+		DeletionScheduledAt: pgtype.Timestamp{Time: before, Valid: true},
+		Limit:               limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]*models.ReducedUser, len(dbUsers))
+	for i, dbUser := range dbUsers {
+		users[i] = &models.ReducedUser{
+			ID:       dbUser.ID.Bytes,
+			Username: dbUser.Username,
+		}
+	}
+	return users, nil
+}
+
+// Purges a user whose deletion is due
+func (r PostgresUserRepository) Purge(ctx context.Context, id uuid.UUID) error {
+	pgID := pgtype.UUID{Bytes: id, Valid: true}
+
+	return withTx(r.p, ctx, func(tx pgx.Tx) error {
+		qtx := database.New(tx)
+		currentTime := pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+
+		// 1. Lock user, so logging in meanwhile either cancels the deletion before or fails after
+		if _, err := qtx.LockUserToPurge(ctx, database.LockUserToPurgeParams{
+			ID:                  pgID,
+			DeletionScheduledAt: currentTime,
+		}); err != nil {
+			if err == pgx.ErrNoRows {
+				return storage.ErrNoUser
+			}
+			return err
+		}
+
+		// 2. Purge
+		return qtx.PurgeUser(ctx, database.PurgeUserParams{
+			ID:  pgID,
+			Now: currentTime,
+		})
+	})
 }
 
 // Deletes a user
@@ -206,7 +594,7 @@ func (r PostgresUserRepository) HardDelete(ctx context.Context, id uuid.UUID) er
 }
 
 // Updates a user. The `u` parameter may contain empty fields, which mean they will not change.
-func (r PostgresUserRepository) Update(ctx context.Context, u *models.UserWithPassword) (*models.User, error) {
+func (r PostgresUserRepository) Update(ctx context.Context, u *models.UserWithPassword, profile *models.ProfileChanges) (*models.User, error) {
 	q := database.New(r.p)
 	currentTime := time.Now().UTC()
 	var pgPassword []byte
@@ -220,14 +608,20 @@ func (r PostgresUserRepository) Update(ctx context.Context, u *models.UserWithPa
 		pgPassword = nil
 	}
 
+	// NOTE(maolivera): Email is never set here, it must be confirmed first (see ConfirmEmailChange)
 	dbUser, err := q.UpdateUser(ctx, database.UpdateUserParams{
 		UpdatedAt: pgtype.Timestamp{Time: currentTime, Valid: true},
 		ID:        pgtype.UUID{Bytes: u.User.ID, Valid: true},
-		Username:  pgtype.Text{String: u.User.Username, Valid: len(u.User.Username) > 0 || len(u.User.Username) <= 100},
-		Email:     pgtype.Text{String: u.User.Email, Valid: len(u.User.Email) > 0 || len(u.User.Email) <= 255},
-		FirstName: pgtype.Text{String: u.User.FirstName, Valid: len(u.User.FirstName) > 0 || len(u.User.FirstName) <= 100},
-		LastName:  pgtype.Text{String: u.User.LastName, Valid: len(u.User.LastName) > 0 || len(u.User.LastName) <= 100},
+		Username:  pgtype.Text{String: u.User.Username, Valid: len(u.User.Username) > 0},
+		FirstName: pgtype.Text{String: u.User.FirstName, Valid: len(u.User.FirstName) > 0},
+		LastName:  pgtype.Text{String: u.User.LastName, Valid: len(u.User.LastName) > 0},
 		Password:  pgPassword,
+		Bio:       optionalText(profile.Bio),
+		Website:   optionalText(profile.Website),
+		Location:  optionalText(profile.Location),
+		Pronouns:  optionalText(profile.Pronouns),
+		AvatarUrl: optionalText(profile.AvatarURL),
+		BannerUrl: optionalText(profile.BannerURL),
 	})
 	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok {
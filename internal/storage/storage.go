@@ -16,6 +16,8 @@ var (
 	ErrEmailUnavailable    = errors.New("email is unavailable")
 	ErrNoUser              = errors.New("user not found")
 	ErrNoToken             = errors.New("token not found")
+	ErrTokenReused         = errors.New("token was already used")
+	ErrInProgress          = errors.New("already in progress")
 	QueryTimeDuration      = time.Second * 5
 )
 
@@ -24,30 +26,57 @@ type Storage struct {
 	Users     UserRepository
 	Comments  CommentRepository
 	Followers FollowerRepository
+	Blocks    BlockRepository
+	Mutes     MuteRepository
 	Roles     RoleRepository
+	Tokens    TokenRepository
+	TwoFactor TwoFactorRepository
+
+	PersonalAccessTokens PersonalAccessTokenRepository
+	LoginAttempts        LoginAttemptRepository
+	Identities           IdentityRepository
+	Sessions             SessionRepository
+	AuditLog             AuditLogRepository
+	Permissions          PermissionRepository
+	Suggestions          SuggestionRepository
+	Exports              ExportRepository
 }
 
 type PostRepository interface {
 	// Fetch a post by ID
 	GetByID(context.Context, uuid.UUID) (*models.Post, error)
+	// Fetch every post of a user, including the soft deleted ones, oldest first
+	GetByUser(context.Context, uuid.UUID) ([]*models.Post, error)
 	// Stores a post
 	Create(context.Context, *models.Post) error
-	// Mark a post as deleted
+	// Mark a post as deleted. Returns ErrNoRows if it was already deleted or its version changed.
 	SoftDelete(context.Context, *models.Post) error
-	// Deletes a post
+	// Fetch soft deleted posts, newest deletion first. Filters by the username of the author and by when they
+	// were deleted, if given. It requires a limit and an offset.
+	GetDeleted(context.Context, string, *time.Time, *time.Time, int32, int32) ([]*models.Post, error)
+	// Undoes the soft deletion of a post and records the entry. Returns ErrNoRows if there is no such deleted post.
+	Restore(context.Context, uuid.UUID, *models.AuditLogEntry) (*models.Post, error)
+	// Deletes a post. Returns ErrNoRows if it was already deleted or its version changed.
 	HardDelete(context.Context, *models.Post) error
-	// Updates a post
+	// Updates a post, keeping its previous content as a revision. Returns ErrNoRows if it was deleted or its
+	// version changed.
 	Update(context.Context, *models.Post) (*models.Post, error)
-	// Retrieve feed for user. It requires sort (bool), a limit and an offset
+	// Fetch the revisions of a post, newest first
+	GetRevisions(context.Context, uuid.UUID) ([]*models.PostRevision, error)
+	// Fetch the revision of a post with the given version. Returns ErrNoRows if there is no such revision.
+	GetRevision(context.Context, uuid.UUID, int32) (*models.PostRevision, error)
+	// Retrieve feed for user, without what it muted. It requires sort (bool), a limit and an offset
 	GetFeed(context.Context, *models.User, bool, int32, int32) ([]*models.Feed, error)
-	// Search posts.
-	Search(context.Context, string, []string, int32, int32, bool, *time.Time, *time.Time) ([]*models.Feed, error)
+	// Search posts visible to the user, without what it muted.
+	Search(context.Context, *models.User, string, []string, int32, int32, bool, *time.Time, *time.Time) ([]*models.Feed, error)
 }
 
 type UserRepository interface {
 	// Fetch a user by username
 	GetByUsername(context.Context, string) (*models.User, error)
-	// Fetch a user by email and password. Used for log in.
+	// Fetch a user by ID
+	GetByID(context.Context, uuid.UUID) (*models.User, error)
+	// Fetch a user by email and password. Used for log in. Returns ErrNoRows if either of them is wrong.
 	GetByEmailAndPassword(context.Context, string, string) (*models.User, error)
 	// Stores a user
 	Create(context.Context, *models.UserWithPassword) error
@@ -55,29 +84,239 @@ type UserRepository interface {
 	CreateAndInvite(context.Context, *models.UserWithPassword, []byte, time.Duration) error
 	// Activates a user and deletes the invitation
 	Activate(context.Context, []byte) (*models.User, error)
-	// Mark a user as deleted
-	SoftDelete(context.Context, uuid.UUID) error
+	// Fetch the invitations of a user which did not expire
+	GetInvitations(context.Context, uuid.UUID) ([]*models.Invitation, error)
+	// Stores a password reset token, which expires after the duration, for the user with the given email
+	CreatePasswordReset(context.Context, string, []byte, time.Duration) (*models.User, error)
+	// Changes the password of the owner of the reset token, consuming it and revoking every refresh and personal access token
+	ResetPassword(context.Context, []byte, string) (*models.User, error)
+	// Stores a pending change of the email of a user to the given address, which expires after the duration
+	CreateEmailChange(context.Context, uuid.UUID, string, []byte, time.Duration) error
+	// Changes the email of the owner of the change token, consuming it. Returns ErrEmailUnavailable if the
+	// address was taken meanwhile.
+	ConfirmEmailChange(context.Context, []byte) (*models.User, error)
+	// Makes a user private or public. Pending follow requests are accepted when the user becomes public.
+	// Returns ErrNoUser if there is no such user.
+	SetPrivate(context.Context, uuid.UUID, bool) error
+	// Schedules the purge of a user at the given time and revokes its personal access tokens. Returns ErrNoUser
+	// if there is no such user.
+	ScheduleDeletion(context.Context, uuid.UUID, time.Time) error
+	// Cancels the scheduled purge of a user. Returns ErrNoUser if there is no such user.
+	CancelDeletion(context.Context, uuid.UUID) error
+	// Fetch users pending deletion and soft deleted users which were not purged, newest deletion first. Filters by
+	// username and by when they were or will be deleted, if given. It requires a limit and an offset.
+	GetDeleted(context.Context, string, *time.Time, *time.Time, int32, int32) ([]*models.User, error)
+	// Undoes the soft deletion, or cancels the pending deletion, of a user and records the entry. Returns ErrNoUser
+	// if there is no such deleted user, or if it was purged.
+	Restore(context.Context, uuid.UUID, *models.AuditLogEntry) error
+	// Fetch up to limit users whose purge was scheduled before the given time, oldest first
+	GetToPurge(context.Context, time.Time, int32) ([]*models.ReducedUser, error)
+	// Hard deletes the posts, follows and credentials of a user, and anonymizes it so its comments are kept.
+	// Returns ErrNoUser if the deletion was cancelled meanwhile.
+	Purge(context.Context, uuid.UUID) error
 	// Deletes a user
 	HardDelete(context.Context, uuid.UUID) error
-	// Updates a user. The user parameter may contain empty fields, which mean they will not change.
-	Update(context.Context, *models.UserWithPassword) (*models.User, error)
+	// Updates a user. The user parameter may contain empty fields, which mean they will not change. The email
+	// is changed with CreateEmailChange and ConfirmEmailChange instead.
+	Update(context.Context, *models.UserWithPassword, *models.ProfileChanges) (*models.User, error)
+}
+
+type TokenRepository interface {
+	// Stores a refresh token. Only the hash of the token is persisted.
+	Create(context.Context, *models.RefreshToken) error
+	// Consumes the refresh token with the given value and stores its replacement in the same family.
+	// If the token was already used, the whole family is revoked and ErrTokenReused is returned.
+	Rotate(context.Context, []byte, *models.RefreshToken) (*models.RefreshToken, error)
+	// Revokes every refresh token of a family (a single login)
+	RevokeFamily(context.Context, uuid.UUID) error
+	// Revokes every refresh token of a user
+	RevokeByUser(context.Context, uuid.UUID) error
+}
+
+type SessionRepository interface {
+	// Stores a session and the first refresh token of its family. Only the hash of the token is persisted.
+	Create(context.Context, *models.Session, *models.RefreshToken) error
+	// Updates the last time a session was seen. It requires the IP and the user agent.
+	Touch(context.Context, uuid.UUID, string, string) error
+	// Fetch the active sessions of a user, most recently seen first
+	GetByUser(context.Context, uuid.UUID) ([]*models.Session, error)
+	// Revokes a session of a user, along with its refresh tokens. Returns ErrNoRows if the user has no such session.
+	Revoke(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+type PersonalAccessTokenRepository interface {
+	// Stores a personal access token. Only the hash of the token is persisted.
+	Create(context.Context, *models.PersonalAccessToken) error
+	// Fetch a non-revoked token by its plain value
+	GetByToken(context.Context, string) (*models.PersonalAccessToken, error)
+	// Fetch the non-revoked tokens of a user
+	GetByUser(context.Context, uuid.UUID) ([]*models.PersonalAccessToken, error)
+	// Updates the last time the token was used
+	Touch(context.Context, uuid.UUID) error
+	// Revokes a token of a user. Returns ErrNoRows if the user has no such token.
+	Revoke(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+type LoginAttemptRepository interface {
+	// Stores a login attempt, so admins can review them
+	Record(context.Context, *models.LoginAttempt) error
+	// Retrieve login attempts, newest first. It requires an email and an IP (both optional), if only failed
+	// attempts are wanted, a limit and an offset
+	List(context.Context, string, string, bool, int32, int32) ([]*models.LoginAttempt, error)
+	// Returns the time until which logins for the key (an account or an IP) are locked. Zero if they are not.
+	LockedUntil(context.Context, string) (time.Time, error)
+	// Counts a failed login for the key and returns the failures in a row. Failures older than the duration
+	// are forgotten.
+	Fail(context.Context, string, time.Duration) (int, error)
+	// Locks logins for the key until the given time
+	Lock(context.Context, string, time.Time) error
+	// Forgets the failed logins of the key
+	Reset(context.Context, string) error
+}
+
+type IdentityRepository interface {
+	// Fetch the ID of the user linked to an external identity, by provider and subject
+	GetUserID(context.Context, string, string) (uuid.UUID, error)
+	// Updates the last login with an external identity, by provider and subject
+	Touch(context.Context, string, string) error
+	// Links an external identity to the active user with the same email, and returns the ID of the user.
+	// Returns ErrNoUser if there is no such user.
+	Link(context.Context, *models.UserIdentity) (uuid.UUID, error)
+	// Stores an already active user and links the external identity to it
+	CreateWithUser(context.Context, *models.UserWithPassword, *models.UserIdentity) error
+	// Stores a pending login, which expires after the duration
+	CreateState(context.Context, *models.OIDCState, time.Duration) error
+	// Consumes a pending login by its state. Returns ErrNoToken if not found or expired.
+	ConsumeState(context.Context, []byte) (*models.OIDCState, error)
+}
+
+type TwoFactorRepository interface {
+	// Fetch the TOTP secret of a user
+	Get(context.Context, uuid.UUID) (*models.TOTP, error)
+	// Fetch the TOTP secrets of the given size, in bytes
+	ListBySecretSize(context.Context, int32) ([]*models.TOTP, error)
+	// Replaces a TOTP secret with the same one encrypted. Returns false if it changed in the meantime.
+	ReplaceSecret(context.Context, uuid.UUID, []byte, []byte) (bool, error)
+	// Stores a new, not yet confirmed, TOTP secret. Returns ErrConflict if two-factor is already enabled.
+	Enroll(context.Context, uuid.UUID, []byte) error
+	// Enables two-factor for the user, marking the time step as used and replacing the recovery codes
+	Confirm(context.Context, uuid.UUID, int64, [][]byte) error
+	// Marks a time step as used. Returns false if it (or a later one) was already used.
+	UseStep(context.Context, uuid.UUID, int64) (bool, error)
+	// Consumes a recovery code. Returns false if it does not exist.
+	UseRecoveryCode(context.Context, uuid.UUID, []byte) (bool, error)
+	// Disables two-factor, deleting the secret and the recovery codes
+	Disable(context.Context, uuid.UUID) error
+	// Stores a login challenge, issued after the password was verified, which expires after the duration
+	CreateChallenge(context.Context, uuid.UUID, []byte, time.Duration) error
+	// Consumes a login challenge and returns the ID of the user. Returns ErrNoToken if not found or expired.
+	ConsumeChallenge(context.Context, []byte) (uuid.UUID, error)
 }
 
 type CommentRepository interface {
 	// Create a comment on a post
 	Create(context.Context, *models.Comment) error
-	// Get comments from a post
-	GetByPostID(context.Context, uuid.UUID) ([]*models.Comment, error)
+	// Get comments from a post visible to the user, i.e. not written by someone who blocked it or it blocked.
+	// It requires the ID of the post and the one of the user.
+	GetByPostID(context.Context, uuid.UUID, uuid.UUID) ([]*models.Comment, error)
+	// Fetch every comment written by a user, oldest first
+	GetByUser(context.Context, uuid.UUID) ([]*models.Comment, error)
 }
 
 type FollowerRepository interface {
 	// Follows a user
 	Follow(context.Context, uuid.UUID, uuid.UUID) error
-	// Unfollows a user
+	// Unfollows a user, or cancels the pending request to follow it
 	Unfollow(context.Context, uuid.UUID, uuid.UUID) error
+	// Reports if the second user follows the first one
+	IsFollowing(context.Context, uuid.UUID, uuid.UUID) (bool, error)
+	// Reports if the second user can see the posts of the first one: it is public, the same user, or followed,
+	// and none of them blocked the other
+	CanViewPosts(context.Context, uuid.UUID, uuid.UUID) (bool, error)
+	// Fetch the followers of a user, newest first. It requires a limit and the last follow of the previous
+	// page, nil for the first one.
+	GetFollowers(context.Context, uuid.UUID, int32, *models.Follow) ([]*models.Follow, error)
+	// Fetch the users followed by a user, newest first. It requires a limit and the last follow of the previous
+	// page, nil for the first one.
+	GetFollowing(context.Context, uuid.UUID, int32, *models.Follow) ([]*models.Follow, error)
+	// Stores a request of the second user to follow the first one
+	Request(context.Context, uuid.UUID, uuid.UUID) error
+	// Fetch the pending requests to follow a user, newest first
+	GetRequests(context.Context, uuid.UUID) ([]*models.FollowRequest, error)
+	// Accepts the request of the second user to follow the first one. Returns ErrNoRows if there is no such request.
+	AcceptRequest(context.Context, uuid.UUID, uuid.UUID) error
+	// Rejects the request of the second user to follow the first one. Returns ErrNoRows if there is no such request.
+	RejectRequest(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+type BlockRepository interface {
+	// Blocks a user, removing the follows and follow requests between both users
+	Block(context.Context, uuid.UUID, uuid.UUID) error
+	// Unblocks a user
+	Unblock(context.Context, uuid.UUID, uuid.UUID) error
+	// Reports if any of the users blocked the other
+	IsBlocked(context.Context, uuid.UUID, uuid.UUID) (bool, error)
+	// Fetch the users blocked by a user, newest first
+	GetByUser(context.Context, uuid.UUID) ([]*models.Block, error)
+}
+
+type MuteRepository interface {
+	// Stores a mute. If it was already muted, only its expiration changes and the ID of the mute is replaced
+	// by the existing one.
+	Create(context.Context, *models.Mute) error
+	// Fetch the mutes of a user which did not expire, newest first
+	GetByUser(context.Context, uuid.UUID) ([]*models.Mute, error)
+	// Changes when a mute of a user expires, nil meaning never. Returns ErrNoRows if the user has no such mute.
+	UpdateExpiration(context.Context, uuid.UUID, uuid.UUID, *time.Time) error
+	// Deletes a mute of a user. Returns ErrNoRows if the user has no such mute.
+	Delete(context.Context, uuid.UUID, uuid.UUID) error
+}
+
+type SuggestionRepository interface {
+	// Computes again the suggestions of every user, keeping the best ones of each. Only posts created after the
+	// given time count as recent activity, and only the given number of authors per tag are suggested for it.
+	// Returns how many suggestions were stored, or ErrInProgress if someone else is refreshing them.
+	Refresh(context.Context, time.Time, int32, int32) (int64, error)
+	// Fetch the suggestions of a user, best first. It requires a limit.
+	GetByUser(context.Context, uuid.UUID, int32) ([]*models.Suggestion, error)
+}
+
+type ExportRepository interface {
+	// Marks an export of a user as being generated. Returns ErrInProgress if another export of the user started
+	// after the given time, and so it may still be generated.
+	Start(context.Context, uuid.UUID, uuid.UUID, time.Time) error
+	// Marks an export of a user as done, so another one can be requested
+	Finish(context.Context, uuid.UUID, uuid.UUID) error
 }
 
 type RoleRepository interface {
 	// Get role without description nor ID.
 	GetByName(context.Context, string) (*models.ReducedRole, error)
+	// Fetch every role, by level
+	GetAll(context.Context) ([]*models.Role, error)
+	// Stores a role and the audit entry of the change. Returns ErrConflict if the name is taken.
+	Create(context.Context, *models.Role, *models.AuditLogEntry) error
+	// Changes the role of a user and stores the audit entry of the change. Returns ErrNoRows if the role does
+	// not exist and ErrNoUser if the user does not.
+	AssignToUser(context.Context, uuid.UUID, models.RoleType, *models.AuditLogEntry) error
+}
+
+type PermissionRepository interface {
+	// Fetch every permission
+	GetAll(context.Context) ([]*models.Permission, error)
+	// Fetch the permissions granted to each role
+	GetByRole(context.Context) (map[models.RoleType][]string, error)
+	// Grants a permission to a role and stores the audit entry of the change. Returns ErrNoRows if either of
+	// them does not exist.
+	Grant(context.Context, models.RoleType, string, *models.AuditLogEntry) error
+	// Revokes a permission of a role and stores the audit entry of the change. Returns ErrNoRows if the role
+	// does not have it.
+	Revoke(context.Context, models.RoleType, string, *models.AuditLogEntry) error
+}
+
+type AuditLogRepository interface {
+	// Stores an audit entry
+	Record(context.Context, *models.AuditLogEntry) error
+	// Retrieve audit entries, newest first. It requires a limit and an offset
+	List(context.Context, int32, int32) ([]*models.AuditLogEntry, error)
 }
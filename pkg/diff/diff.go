@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+)
+
+type Edit struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diffs two texts word by word. Joining the equal and deleted edits gives `a`, and joining the equal and inserted
+// ones gives `b`, whitespace included.
+func Words(a, b string) []Edit {
+	return Tokens(split(a), split(b))
+}
+
+// Cells of the longest common subsequence table above which the middle of the texts, past their common prefix and
+// suffix, is diffed as a whole replacement. Bounds the memory to a few megabytes whatever the texts are.
+const maxCells = 1 << 20
+
+// Diffs two sequences of tokens using their longest common subsequence. Consecutive edits of the same kind are merged.
+// Sequences too long to compare token by token are reported as deleted and inserted as a whole, past their common
+// prefix and suffix.
+func Tokens(a, b []string) []Edit {
+	edits := &builder{edits: []Edit{}}
+
+	// Common prefix and suffix, which are most of the text on usual edits
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		edits.add(OpEqual, a[prefix])
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if (len(midA)+1)*(len(midB)+1) > maxCells {
+		for _, token := range midA {
+			edits.add(OpDelete, token)
+		}
+		for _, token := range midB {
+			edits.add(OpInsert, token)
+		}
+	} else {
+		lcsEdits(edits, midA, midB)
+	}
+
+	for _, token := range a[len(a)-suffix:] {
+		edits.add(OpEqual, token)
+	}
+
+	return edits.result()
+}
+
+func lcsEdits(edits *builder, a, b []string) {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits.add(OpEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits.add(OpDelete, a[i])
+			i++
+		default:
+			edits.add(OpInsert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits.add(OpDelete, a[i])
+	}
+	for ; j < len(b); j++ {
+		edits.add(OpInsert, b[j])
+	}
+}
+
+// Merges consecutive tokens of the same kind into edits, without copying the text on every token
+type builder struct {
+	edits []Edit
+	op    Op
+	text  strings.Builder
+}
+
+func (b *builder) add(op Op, token string) {
+	if b.text.Len() > 0 && op != b.op {
+		b.flush()
+	}
+	b.op = op
+	b.text.WriteString(token)
+}
+
+func (b *builder) flush() {
+	if b.text.Len() > 0 {
+		b.edits = append(b.edits, Edit{Op: b.op, Text: b.text.String()})
+		b.text.Reset()
+	}
+}
+
+func (b *builder) result() []Edit {
+	b.flush()
+	return b.edits
+}
+
+// Splits a text into words and the whitespace between them
+func split(s string) []string {
+	tokens := []string{}
+	var token strings.Builder
+	inSpace := false
+	for _, r := range s {
+		if token.Len() > 0 && unicode.IsSpace(r) != inSpace {
+			tokens = append(tokens, token.String())
+			token.Reset()
+		}
+		inSpace = unicode.IsSpace(r)
+		token.WriteRune(r)
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens
+}
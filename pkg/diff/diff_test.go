@@ -0,0 +1,100 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Joins the edits which make up the old and the new text
+func join(edits []Edit) (string, string) {
+	var a, b strings.Builder
+	for _, edit := range edits {
+		if edit.Op != OpInsert {
+			a.WriteString(edit.Text)
+		}
+		if edit.Op != OpDelete {
+			b.WriteString(edit.Text)
+		}
+	}
+	return a.String(), b.String()
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Edit
+	}{
+		{"empty", "", "", []Edit{}},
+		{"equal", "hello world", "hello world", []Edit{{OpEqual, "hello world"}}},
+		{"insert", "", "hello", []Edit{{OpInsert, "hello"}}},
+		{"delete", "hello", "", []Edit{{OpDelete, "hello"}}},
+		{
+			"replace word",
+			"the quick fox",
+			"the slow fox",
+			[]Edit{{OpEqual, "the "}, {OpDelete, "quick"}, {OpInsert, "slow"}, {OpEqual, " fox"}},
+		},
+		{
+			"append words",
+			"hello",
+			"hello brave new world",
+			[]Edit{{OpEqual, "hello"}, {OpInsert, " brave new world"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Words(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if a, b := join(got); a != tt.a || b != tt.b {
+				t.Errorf("edits join into %q and %q, want %q and %q", a, b, tt.a, tt.b)
+			}
+		})
+	}
+}
+
+func TestWordsKeepsWhitespace(t *testing.T) {
+	a := "first line\n\nsecond  line\tend "
+	b := " first\tline\nthird line end"
+	if gotA, gotB := join(Words(a, b)); gotA != a || gotB != b {
+		t.Errorf("edits join into %q and %q, want %q and %q", gotA, gotB, a, b)
+	}
+}
+
+func TestTokensTooLong(t *testing.T) {
+	// The middle is too long to be compared token by token
+	n := 2000
+	a := []string{"start"}
+	b := []string{"start"}
+	for i := 0; i < n; i++ {
+		a = append(a, "a")
+		b = append(b, "b")
+	}
+	a = append(a, "end")
+	b = append(b, "end")
+
+	got := Tokens(a, b)
+	want := []Edit{
+		{OpEqual, "start"},
+		{OpDelete, strings.Repeat("a", n)},
+		{OpInsert, strings.Repeat("b", n)},
+		{OpEqual, "end"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens of long sequences = %v, want %v", got, want)
+	}
+}
+
+func TestTokensLongCommonPrefix(t *testing.T) {
+	// Usual edits of long texts are still diffed token by token
+	words := strings.Repeat("word ", 100000)
+	got := Words(words+"old", words+"new")
+	want := []Edit{{OpEqual, words}, {OpDelete, "old"}, {OpInsert, "new"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Words of texts with a long common prefix = %d edits, want %v", len(got), want)
+	}
+}
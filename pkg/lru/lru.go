@@ -59,6 +59,11 @@ func (c *LRUCache) Get(ctx context.Context, key string) (any, bool) {
 }
 
 func (c *LRUCache) Set(ctx context.Context, key string, value any) {
+	c.SetWithTTL(ctx, key, value, c.ttl)
+}
+
+// Same as Set, but the item will expire after `ttl` instead of the cache TTL
+func (c *LRUCache) SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) {
 	select {
 	case <-ctx.Done():
 		return
@@ -70,7 +75,7 @@ func (c *LRUCache) Set(ctx context.Context, key string, value any) {
 		if el, found := c.items[key]; found {
 			c.order.MoveToFront(el)
 			el.Value.(*CacheItem).Value = value
-			el.Value.(*CacheItem).Expiration = time.Now().Add(c.ttl)
+			el.Value.(*CacheItem).Expiration = time.Now().Add(ttl)
 			return
 		}
 
@@ -87,7 +92,7 @@ func (c *LRUCache) Set(ctx context.Context, key string, value any) {
 		item := &CacheItem{
 			Key:        key,
 			Value:      value,
-			Expiration: time.Now().Add(c.ttl),
+			Expiration: time.Now().Add(ttl),
 		}
 		el := c.order.PushFront(item)
 		c.items[key] = el
@@ -13,7 +13,7 @@ DECLARE
 BEGIN
     -- Insert random users
     FOR i IN 1..100 LOOP
-        INSERT INTO users (id, created_at, updated_at, username, email, password, first_name, last_name, is_deleted)
+        INSERT INTO users (id, created_at, updated_at, username, email, password, first_name, last_name)
         VALUES (
             gen_random_uuid(),
             NOW() - (interval '1 day' * (i % 365)),
@@ -22,14 +22,13 @@ BEGIN
             'user' || i || '@example.com',
             'hashed_password',
             first_names[(1 + random() * (array_length(first_names, 1) - 1))::int],
-            last_names[(1 + random() * (array_length(last_names, 1) - 1))::int],
-            FALSE
+            last_names[(1 + random() * (array_length(last_names, 1) - 1))::int]
         )
         RETURNING id INTO user_id;
 
         -- Each user creates some posts
         FOR j IN 1..(1 + random() * 20)::int LOOP
-            INSERT INTO posts (id, created_at, updated_at, title, content, user_id, tags, is_deleted, version)
+            INSERT INTO posts (id, created_at, updated_at, title, content, user_id, tags, version)
             VALUES (
                 gen_random_uuid(),
                 NOW() - (interval '1 day' * (j % 30)),
@@ -41,8 +40,7 @@ BEGIN
                     tags[(1 + random() * (array_length(tags, 1) - 1))::int],
                     tags[(1 + random() * (array_length(tags, 1) - 1))::int]
                 ],
-                FALSE,
-                1
+                0
             )
             RETURNING id INTO post_id;
 
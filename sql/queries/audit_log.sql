@@ -0,0 +1,8 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO audit_log (id, actor_id, action, target_type, target_id, details, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListAuditLog :many
+SELECT * FROM audit_log
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
@@ -0,0 +1,29 @@
+-- name: BlockByID :exec
+INSERT INTO blocks(created_at, user_id, blocked_id)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockByID :exec
+DELETE FROM blocks WHERE user_id = $1 AND blocked_id = $2;
+
+-- name: IsBlocked :one
+-- Whether any of the users blocked the other
+SELECT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE (user_id = $1 AND blocked_id = $2) OR (user_id = $2 AND blocked_id = $1)
+)::boolean AS blocked;
+
+-- name: ListBlockedUsers :many
+SELECT u.id, u.username, b.created_at
+FROM blocks b
+JOIN users u ON b.blocked_id = u.id
+WHERE b.user_id = $1
+ORDER BY b.created_at DESC;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM followers
+WHERE (user_id = $1 AND follower_id = $2) OR (user_id = $2 AND follower_id = $1);
+
+-- name: DeleteFollowRequestsBetween :exec
+DELETE FROM follow_requests
+WHERE (user_id = $1 AND requester_id = $2) OR (user_id = $2 AND requester_id = $1);
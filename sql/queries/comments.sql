@@ -1,10 +1,19 @@
 -- name: GetCommentsByPost :many
-SELECT comments.post_id, comments.id, comments.content, users.username, users.email, users.first_name, comments.created_at
+SELECT comments.post_id, comments.id, comments.content, comments.user_id, users.username, comments.created_at
 FROM comments
 LEFT JOIN users ON comments.user_id = users.id
 WHERE comments.post_id = $1
+	AND NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.user_id = comments.user_id AND b.blocked_id = @viewer_id) OR (b.user_id = @viewer_id AND b.blocked_id = comments.user_id)
+	)
 ORDER BY comments.created_at DESC;
 
 -- name: CreateCommentInPost :exec
 INSERT INTO comments (id, user_id, post_id, created_at, updated_at, content)
 VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetCommentsByUser :many
+SELECT * FROM comments
+WHERE user_id = $1
+ORDER BY created_at;
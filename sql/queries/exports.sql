@@ -0,0 +1,14 @@
+-- name: StartExport :one
+-- Takes the place of the pending export of the user only if it started before stale_before
+INSERT INTO pending_exports (user_id, id, started_at)
+VALUES (@user_id, @id, @now::timestamp)
+ON CONFLICT (user_id) DO UPDATE
+SET
+	id = EXCLUDED.id,
+	started_at = EXCLUDED.started_at
+WHERE pending_exports.started_at < @stale_before::timestamp
+RETURNING id;
+
+-- name: FinishExport :exec
+DELETE FROM pending_exports
+WHERE user_id = $1 AND id = $2;
@@ -1,12 +1,33 @@
 -- name: GetUserFeed :many
 SELECT
-	p.id, p.title, p.content, p.created_at, p.tags,
+	p.id, p.title, p.content, p.created_at, p.tags, p.edited_at,
 	author.id AS author_id, author.username, COUNT(c.id) AS comment_count
 FROM posts p
 LEFT JOIN comments c ON c.post_id = p.id
+	AND NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.user_id = c.user_id AND b.blocked_id = $1) OR (b.user_id = $1 AND b.blocked_id = c.user_id)
+	)
 LEFT JOIN users author ON p.user_id = author.id
-JOIN followers f ON f.follower_id = p.user_id OR p.user_id = $1
-WHERE f.user_id = $1 OR p.user_id = $1
+WHERE (
+		p.user_id = $1
+		OR EXISTS (SELECT 1 FROM followers f WHERE f.user_id = p.user_id AND f.follower_id = $1)
+	)
+	AND p.deleted_at IS NULL
+	AND NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.user_id = p.user_id AND b.blocked_id = $1) OR (b.user_id = $1 AND b.blocked_id = p.user_id)
+	)
+	AND NOT EXISTS (
+		SELECT 1 FROM mutes m
+		WHERE m.user_id = $1
+			AND (m.expires_at IS NULL OR m.expires_at > @now)
+			AND (
+				(m.kind = 'user' AND m.muted_user_id = p.user_id)
+				OR (m.kind = 'tag' AND EXISTS (SELECT 1 FROM unnest(p.tags) t WHERE lower(t) = m.value))
+				OR (m.kind = 'keyword' AND (strpos(lower(p.title), m.value) > 0 OR strpos(lower(p.content), m.value) > 0))
+			)
+	)
 GROUP BY p.id, author.id, author.username
 ORDER BY
 	CASE
@@ -14,4 +35,3 @@ ORDER BY
 	CASE
 		WHEN @sort::boolean THEN p.created_at END DESC
 LIMIT $2 OFFSET $3;
-
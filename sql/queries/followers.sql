@@ -1,6 +1,75 @@
 -- name: FollowByID :exec
 INSERT INTO followers(created_at, user_id, follower_id)
-VALUES ($1, $2, $3);
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
 
 -- name: UnfollowByID :exec
 DELETE FROM followers WHERE user_id = $1 AND follower_id = $2;
+
+-- name: IsFollowing :one
+SELECT EXISTS (
+	SELECT 1 FROM followers WHERE user_id = $1 AND follower_id = $2
+)::boolean AS following;
+
+-- name: CanViewPosts :one
+-- Public users, the viewer itself and the users followed by the viewer, unless any of them blocked the other
+SELECT EXISTS (
+	SELECT 1 FROM users u
+	WHERE u.id = @user_id
+		AND (
+			NOT u.is_private
+			OR u.id = @viewer_id
+			OR EXISTS (SELECT 1 FROM followers f WHERE f.user_id = u.id AND f.follower_id = @viewer_id)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.user_id = u.id AND b.blocked_id = @viewer_id) OR (b.user_id = @viewer_id AND b.blocked_id = u.id)
+		)
+)::boolean AS can_view;
+
+-- name: CreateFollowRequest :exec
+INSERT INTO follow_requests(created_at, user_id, requester_id)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequest :execrows
+DELETE FROM follow_requests WHERE user_id = $1 AND requester_id = $2;
+
+-- name: ListFollowRequests :many
+SELECT u.id, u.username, r.created_at
+FROM follow_requests r
+JOIN users u ON r.requester_id = u.id
+WHERE r.user_id = $1 AND u.deleted_at IS NULL
+ORDER BY r.created_at DESC;
+
+-- name: AcceptFollowRequests :exec
+INSERT INTO followers(created_at, user_id, follower_id)
+SELECT created_at, user_id, requester_id
+FROM follow_requests
+WHERE user_id = $1
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteFollowRequestsByUser :exec
+DELETE FROM follow_requests WHERE user_id = $1;
+
+-- name: ListFollowers :many
+-- Keyset pagination, newest first. The cursor is the follow (and the ID of the user) where the previous page ended.
+SELECT u.id, u.username, f.created_at
+FROM followers f
+JOIN users u ON f.follower_id = u.id
+WHERE f.user_id = @user_id
+	AND u.deleted_at IS NULL
+	AND (sqlc.narg('before')::timestamp IS NULL OR (f.created_at, u.id) < (sqlc.narg('before'), sqlc.narg('before_id')::uuid))
+ORDER BY f.created_at DESC, u.id DESC
+LIMIT $1;
+
+-- name: ListFollowing :many
+-- Keyset pagination, newest first. The cursor is the follow (and the ID of the user) where the previous page ended.
+SELECT u.id, u.username, f.created_at
+FROM followers f
+JOIN users u ON f.user_id = u.id
+WHERE f.follower_id = @user_id
+	AND u.deleted_at IS NULL
+	AND (sqlc.narg('before')::timestamp IS NULL OR (f.created_at, u.id) < (sqlc.narg('before'), sqlc.narg('before_id')::uuid))
+ORDER BY f.created_at DESC, u.id DESC
+LIMIT $1;
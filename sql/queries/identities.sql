@@ -0,0 +1,21 @@
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (provider, subject, user_id, email, created_at, last_login_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE provider = $1 AND subject = $2;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities
+SET last_login_at = $3
+WHERE provider = $1 AND subject = $2;
+
+-- name: CreateOIDCState :exec
+INSERT INTO oidc_states (state_hash, provider, nonce, code_verifier, expires_at)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ConsumeOIDCState :one
+DELETE FROM oidc_states
+WHERE state_hash = $1 AND expires_at > $2
+RETURNING provider, nonce, code_verifier;
@@ -0,0 +1,37 @@
+-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempts (id, email, ip, user_agent, outcome, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListLoginAttempts :many
+SELECT * FROM login_attempts
+WHERE
+	(sqlc.narg('email')::text IS NULL OR email = sqlc.narg('email'))
+	AND (sqlc.narg('ip')::text IS NULL OR ip = sqlc.narg('ip'))
+	AND (NOT @failed_only::boolean OR outcome <> 'success')
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (key, failures, last_failure_at)
+VALUES (@key, 1, @now::timestamp)
+ON CONFLICT (key) DO UPDATE
+SET
+	failures = CASE
+		WHEN login_throttles.last_failure_at < @window_start::timestamp THEN 1
+		ELSE login_throttles.failures + 1
+	END,
+	last_failure_at = EXCLUDED.last_failure_at
+RETURNING failures;
+
+-- name: LockLogin :exec
+UPDATE login_throttles
+SET locked_until = $2
+WHERE key = $1;
+
+-- name: GetLoginLock :one
+SELECT locked_until FROM login_throttles
+WHERE key = $1 AND locked_until > $2;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
@@ -0,0 +1,25 @@
+-- name: CreateMute :one
+-- Muting something already muted only changes its expiration
+INSERT INTO mutes (id, user_id, kind, muted_user_id, value, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, kind, muted_user_id, value) DO UPDATE
+SET
+	created_at = EXCLUDED.created_at,
+	expires_at = EXCLUDED.expires_at
+RETURNING id;
+
+-- name: ListMutes :many
+SELECT m.id, m.kind, m.muted_user_id, u.username, m.value, m.created_at, m.expires_at
+FROM mutes m
+LEFT JOIN users u ON m.muted_user_id = u.id
+WHERE m.user_id = $1 AND (m.expires_at IS NULL OR m.expires_at > $2)
+ORDER BY m.created_at DESC;
+
+-- name: UpdateMuteExpiration :execrows
+UPDATE mutes
+SET expires_at = $1
+WHERE id = $2 AND user_id = $3;
+
+-- name: DeleteMute :execrows
+DELETE FROM mutes
+WHERE id = $1 AND user_id = $2;
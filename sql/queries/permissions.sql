@@ -0,0 +1,18 @@
+-- name: ListPermissions :many
+SELECT * FROM permissions
+ORDER BY name;
+
+-- name: ListRolePermissions :many
+SELECT r.name AS role, rp.permission
+FROM role_permissions rp
+JOIN roles r ON r.id = rp.role_id
+ORDER BY r.name, rp.permission;
+
+-- name: GrantPermission :exec
+INSERT INTO role_permissions (role_id, permission)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RevokePermission :execrows
+DELETE FROM role_permissions
+WHERE role_id = $1 AND permission = $2;
@@ -0,0 +1,12 @@
+-- name: CreatePostRevision :exec
+INSERT INTO post_revisions (post_id, version, title, content, tags, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListPostRevisions :many
+SELECT * FROM post_revisions
+WHERE post_id = $1
+ORDER BY version DESC;
+
+-- name: GetPostRevision :one
+SELECT * FROM post_revisions
+WHERE post_id = $1 AND version = $2;
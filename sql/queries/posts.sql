@@ -2,27 +2,53 @@
 INSERT INTO posts (id, created_at, updated_at, user_id, title, content, tags)
 VALUES ($1, $2, $3, $4, $5, $6, $7);
 
--- name: HardDeletePostByID :exec
+-- name: HardDeletePostByID :execrows
 DELETE FROM posts WHERE id = $1 and version = $2;
 
--- name: SoftDeletePostByID :one
+-- name: SoftDeletePostByID :execrows
 UPDATE posts
-SET is_deleted = true
-WHERE id = $1 and version = $2
-RETURNING is_deleted;
+SET deleted_at = $3
+WHERE id = $1 AND version = $2 AND deleted_at IS NULL;
 
 -- name: UpdatePost :one
 UPDATE posts
 SET
 	updated_at = $1,
+	edited_at = $1,
+	version = version + 1,
 	title = coalesce(sqlc.narg('title'), title),
 	content = coalesce(sqlc.narg('content'), content),
 	tags = coalesce(sqlc.narg('tags'), tags)
-WHERE id = $2 AND is_deleted = false AND version = $3
+WHERE id = $2 AND deleted_at IS NULL AND version = $3
 RETURNING *;
 
+-- name: LockPost :one
+-- Returns no rows if the post was deleted or edited meanwhile
+SELECT * FROM posts
+WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+FOR UPDATE;
+
 -- name: GetPostByUser :many
-SELECT * FROM posts WHERE user_id = $1 AND is_deleted = false;
+-- Includes the soft deleted ones
+SELECT * FROM posts WHERE user_id = $1 ORDER BY created_at;
 
 -- name: GetPostById :one
-SELECT * FROM posts WHERE id = $1 AND is_deleted = false;
+SELECT * FROM posts WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: ListDeletedPosts :many
+SELECT p.* FROM posts p
+JOIN users u ON p.user_id = u.id
+WHERE p.deleted_at IS NOT NULL
+	AND (sqlc.narg('username')::text IS NULL OR u.username = sqlc.narg('username'))
+	AND (sqlc.narg('since')::timestamp IS NULL OR p.deleted_at >= sqlc.narg('since'))
+	AND (sqlc.narg('until')::timestamp IS NULL OR p.deleted_at < sqlc.narg('until'))
+ORDER BY p.deleted_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: RestorePost :one
+UPDATE posts
+SET
+	updated_at = $1,
+	deleted_at = NULL
+WHERE id = $2 AND deleted_at IS NOT NULL
+RETURNING *;
@@ -1,5 +1,11 @@
 -- name: GetRoles :many
-SELECT * FROM roles;
+SELECT * FROM roles
+ORDER BY level, name;
 
 -- name: GetRoleByName :one
 SELECT * FROM roles WHERE name = $1;
+
+-- name: CreateRole :one
+INSERT INTO roles (name, level, description)
+VALUES ($1, $2, $3)
+RETURNING *;
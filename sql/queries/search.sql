@@ -1,15 +1,39 @@
 -- name: SearchPosts :many
 SELECT
-    p.id, p.title, p.content, p.created_at, p.tags,
+    p.id, p.title, p.content, p.created_at, p.tags, p.edited_at,
     author.id AS author_id, author.username, COUNT(c.id) AS comment_count
 FROM posts p
 LEFT JOIN comments c ON c.post_id = p.id
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks b
+        WHERE (b.user_id = c.user_id AND b.blocked_id = @viewer_id) OR (b.user_id = @viewer_id AND b.blocked_id = c.user_id)
+    )
 LEFT JOIN users author ON p.user_id = author.id
 WHERE
-    (@search::text IS NULL OR p.content ILIKE '%' || @search || '%' OR p.title ILIKE '%' || @search || '%')
+    p.deleted_at IS NULL
+    AND (@search::text IS NULL OR p.content ILIKE '%' || @search || '%' OR p.title ILIKE '%' || @search || '%')
     AND (@tags::text[] IS NULL OR p.tags && @tags)
     AND (@since::timestamp IS NULL OR p.created_at >= @since)
     AND (@until::timestamp IS NULL OR p.created_at <= @until)
+    AND (
+        NOT author.is_private
+        OR author.id = @viewer_id
+        OR EXISTS (SELECT 1 FROM followers f WHERE f.user_id = author.id AND f.follower_id = @viewer_id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks b
+        WHERE (b.user_id = author.id AND b.blocked_id = @viewer_id) OR (b.user_id = @viewer_id AND b.blocked_id = author.id)
+    )
+    AND NOT EXISTS (
+        SELECT 1 FROM mutes m
+        WHERE m.user_id = @viewer_id
+            AND (m.expires_at IS NULL OR m.expires_at > @now)
+            AND (
+                (m.kind = 'user' AND m.muted_user_id = p.user_id)
+                OR (m.kind = 'tag' AND EXISTS (SELECT 1 FROM unnest(p.tags) t WHERE lower(t) = m.value))
+                OR (m.kind = 'keyword' AND (strpos(lower(p.title), m.value) > 0 OR strpos(lower(p.content), m.value) > 0))
+            )
+    )
 GROUP BY p.id, author.id, author.username
 ORDER BY
 	CASE WHEN @sort::boolean THEN p.created_at END DESC,
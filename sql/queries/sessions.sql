@@ -0,0 +1,29 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (id, user_id, ip, user_agent, created_at, last_seen_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen_at = $2, ip = $3, user_agent = $4
+WHERE id = $1;
+
+-- name: ListActiveSessionsByUser :many
+-- A session is active while the refresh token of its family can still be used
+SELECT s.* FROM sessions s
+WHERE s.user_id = $1
+	AND s.revoked_at IS NULL
+	AND EXISTS (
+		SELECT 1 FROM refresh_tokens rt
+		WHERE rt.family_id = s.id
+			AND rt.used_at IS NULL
+			AND rt.revoked_at IS NULL
+			AND rt.expires_at > @now
+	)
+ORDER BY s.last_seen_at DESC;
+
+-- name: RevokeSession :execrows
+UPDATE sessions
+SET revoked_at = $3
+WHERE id = $1
+	AND user_id = $2
+	AND revoked_at IS NULL;
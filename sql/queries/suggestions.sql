@@ -0,0 +1,99 @@
+-- name: DeleteSuggestions :exec
+DELETE FROM user_suggestions;
+
+-- name: RefreshSuggestions :execrows
+-- Candidates are the users followed by the users someone follows, the authors who post with the same tags and the
+-- most active authors, so users without follows nor posts get suggestions too. Only the best of each user are kept.
+-- Only the authors posting the most with a tag are candidates for it, otherwise popular tags would pair every
+-- author with each other.
+WITH recent_posts AS (
+	SELECT p.user_id, p.tags
+	FROM posts p
+	WHERE p.deleted_at IS NULL AND p.created_at > @since
+),
+activity AS (
+	SELECT rp.user_id, COUNT(*) AS posts
+	FROM recent_posts rp
+	GROUP BY rp.user_id
+),
+interests AS (
+	SELECT rp.user_id, lower(t.tag) AS tag, COUNT(*) AS posts
+	FROM recent_posts rp, unnest(rp.tags) AS t(tag)
+	GROUP BY rp.user_id, lower(t.tag)
+),
+tag_authors AS (
+	SELECT ranked_interests.user_id, ranked_interests.tag
+	FROM (
+		SELECT i.user_id, i.tag, row_number() OVER (PARTITION BY i.tag ORDER BY i.posts DESC, i.user_id) AS position
+		FROM interests i
+	) ranked_interests
+	WHERE ranked_interests.position <= @authors_per_tag::int
+),
+candidates AS (
+	SELECT f1.follower_id AS user_id, f2.user_id AS suggested_id, COUNT(*) AS mutual_count, 0 AS shared_tags
+	FROM followers f1
+	JOIN followers f2 ON f2.follower_id = f1.user_id
+	GROUP BY f1.follower_id, f2.user_id
+	UNION ALL
+	SELECT i.user_id, ta.user_id, 0, COUNT(*)
+	FROM interests i
+	JOIN tag_authors ta ON ta.tag = i.tag
+	GROUP BY i.user_id, ta.user_id
+	UNION ALL
+	SELECT u.id, top.user_id, 0, 0
+	FROM users u
+	CROSS JOIN (SELECT a.user_id FROM activity a ORDER BY a.posts DESC LIMIT 20) top
+),
+ranked AS (
+	SELECT
+		c.user_id,
+		c.suggested_id,
+		SUM(c.mutual_count) AS mutual_count,
+		SUM(c.shared_tags) AS shared_tags,
+		(3 * SUM(c.mutual_count) + 2 * SUM(c.shared_tags) + ln(1 + coalesce(MAX(a.posts), 0)))::float8 AS score
+	FROM candidates c
+	LEFT JOIN activity a ON a.user_id = c.suggested_id
+	GROUP BY c.user_id, c.suggested_id
+)
+INSERT INTO user_suggestions (user_id, suggested_id, score, mutual_count, shared_tags, created_at)
+SELECT s.user_id, s.suggested_id, s.score, s.mutual_count, s.shared_tags, @now::timestamp
+FROM (
+	SELECT r.*, row_number() OVER (PARTITION BY r.user_id ORDER BY r.score DESC, r.suggested_id) AS position
+	FROM ranked r
+	JOIN users u ON u.id = r.user_id
+	JOIN users su ON su.id = r.suggested_id
+	WHERE r.user_id <> r.suggested_id
+		AND u.is_active = true AND u.deleted_at IS NULL
+		AND su.is_active = true AND su.deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM followers f WHERE f.user_id = r.suggested_id AND f.follower_id = r.user_id)
+		AND NOT EXISTS (SELECT 1 FROM follow_requests fr WHERE fr.user_id = r.suggested_id AND fr.requester_id = r.user_id)
+		AND NOT EXISTS (
+			SELECT 1 FROM blocks b
+			WHERE (b.user_id = r.user_id AND b.blocked_id = r.suggested_id) OR (b.user_id = r.suggested_id AND b.blocked_id = r.user_id)
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM mutes m
+			WHERE m.user_id = r.user_id AND m.kind = 'user' AND m.muted_user_id = r.suggested_id
+				AND (m.expires_at IS NULL OR m.expires_at > @now::timestamp)
+		)
+) s
+WHERE s.position <= @max_per_user::int;
+
+-- name: ListSuggestions :many
+-- Suggestions may be stale, so users followed, blocked or deleted since they were computed are skipped
+SELECT u.id, u.username, s.score, s.mutual_count, s.shared_tags
+FROM user_suggestions s
+JOIN users u ON s.suggested_id = u.id
+WHERE s.user_id = @user_id
+	AND u.is_active = true AND u.deleted_at IS NULL
+	AND NOT EXISTS (SELECT 1 FROM followers f WHERE f.user_id = u.id AND f.follower_id = s.user_id)
+	AND NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.user_id = s.user_id AND b.blocked_id = u.id) OR (b.user_id = u.id AND b.blocked_id = s.user_id)
+	)
+ORDER BY s.score DESC, u.id
+LIMIT $1;
+
+-- name: TryLockSuggestions :one
+-- Locks the suggestions until the end of the transaction, if no one else holds the lock
+SELECT pg_try_advisory_xact_lock(hashtext('user_suggestions'));
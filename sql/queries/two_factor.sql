@@ -0,0 +1,60 @@
+-- name: UpsertTOTP :exec
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET
+	secret = EXCLUDED.secret,
+	created_at = EXCLUDED.created_at,
+	confirmed_at = NULL,
+	last_used_step = 0;
+
+-- name: GetTOTPByUser :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: ListTOTPsBySecretSize :many
+-- Secrets stored before they were encrypted are shorter than the encrypted ones
+SELECT * FROM user_totp
+WHERE length(secret) = @size::int;
+
+-- name: ReplaceTOTPSecret :execrows
+UPDATE user_totp
+SET secret = @secret
+WHERE user_id = @user_id AND secret = @old_secret;
+
+-- name: ConfirmTOTP :exec
+UPDATE user_totp
+SET
+	confirmed_at = $2,
+	last_used_step = $3
+WHERE user_id = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO user_recovery_codes (code_hash, user_id)
+VALUES ($1, $2);
+
+-- name: UseRecoveryCode :execrows
+DELETE FROM user_recovery_codes
+WHERE code_hash = $1 AND user_id = $2;
+
+-- name: DeleteRecoveryCodesByUser :exec
+DELETE FROM user_recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateTwoFactorChallenge :exec
+INSERT INTO two_factor_challenges (token_hash, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: ConsumeTwoFactorChallenge :one
+DELETE FROM two_factor_challenges
+WHERE token_hash = $1 AND expires_at > $2
+RETURNING user_id;
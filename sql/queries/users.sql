@@ -11,6 +11,12 @@ SELECT user_id
 FROM user_invitations
 WHERE token = $1 AND expires_at > $2;
 
+-- name: GetInvitationsByUser :many
+SELECT expires_at
+FROM user_invitations
+WHERE user_id = $1 AND expires_at > $2
+ORDER BY expires_at;
+
 -- name: ActivateUser :one
 UPDATE users
 SET is_active = true
@@ -24,22 +30,19 @@ WHERE token = $1;
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE email = $1
-	AND is_deleted = false
+	AND deleted_at IS NULL
 	AND is_active = true;
 
 -- name: GetUserByUsername :one
 SELECT
-	u.*, r.level, r.name
+	u.*, r.level, r.name,
+	(t.confirmed_at IS NOT NULL)::boolean AS two_factor_enabled
 FROM users u
 JOIN roles r ON u.role_id = r.id
+LEFT JOIN user_totp t ON t.user_id = u.id
 WHERE u.username = $1
-	AND is_deleted = false
-	AND is_active = true;
-
--- name: SoftDeleteUserByID :exec
-UPDATE users
-SET is_deleted = true
-WHERE id = $1;
+	AND u.deleted_at IS NULL
+	AND u.is_active = true;
 
 -- name: HardDeleteUserByID :exec
 DELETE FROM users
@@ -53,6 +56,192 @@ SET
 	email = coalesce(sqlc.narg('email'), email),
 	first_name = coalesce(sqlc.narg('first_name'), first_name),
 	last_name = coalesce(sqlc.narg('last_name'), last_name),
-	password = coalesce(sqlc.narg('password'), password)
-WHERE id = $2 AND is_deleted = false
+	password = coalesce(sqlc.narg('password'), password),
+	bio = coalesce(sqlc.narg('bio'), bio),
+	website = coalesce(sqlc.narg('website'), website),
+	location = coalesce(sqlc.narg('location'), location),
+	pronouns = coalesce(sqlc.narg('pronouns'), pronouns),
+	avatar_url = coalesce(sqlc.narg('avatar_url'), avatar_url),
+	banner_url = coalesce(sqlc.narg('banner_url'), banner_url)
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT
+	u.*, r.level, r.name,
+	(t.confirmed_at IS NOT NULL)::boolean AS two_factor_enabled
+FROM users u
+JOIN roles r ON u.role_id = r.id
+LEFT JOIN user_totp t ON t.user_id = u.id
+WHERE u.id = $1
+	AND u.deleted_at IS NULL
+	AND u.is_active = true;
+
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (token_hash, user_id, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: ConsumePasswordReset :one
+DELETE FROM password_resets
+WHERE token_hash = $1 AND expires_at > $2
+RETURNING user_id;
+
+-- name: DeletePasswordResetsByUser :exec
+DELETE FROM password_resets
+WHERE user_id = $1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET
+	updated_at = $1,
+	password = $2
+WHERE id = $3 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: CreateEmailChange :exec
+INSERT INTO user_email_changes (token_hash, user_id, new_email, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetEmailChange :one
+SELECT user_id, new_email
+FROM user_email_changes
+WHERE token_hash = $1 AND expires_at > $2;
+
+-- name: DeleteEmailChangesByUser :exec
+DELETE FROM user_email_changes
+WHERE user_id = $1;
+
+-- name: UpdateUserEmail :one
+UPDATE users
+SET
+	updated_at = $1,
+	email = $2
+WHERE id = $3 AND deleted_at IS NULL
 RETURNING *;
+
+-- name: UpdateUserRole :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	role_id = $2
+WHERE id = $3 AND deleted_at IS NULL;
+
+-- name: UpdateUserPrivacy :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	is_private = $2
+WHERE id = $3 AND deleted_at IS NULL;
+
+-- name: ScheduleUserDeletion :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	deletion_scheduled_at = $2
+WHERE id = $3 AND deleted_at IS NULL;
+
+-- name: CancelUserDeletion :execrows
+UPDATE users
+SET
+	updated_at = $1,
+	deletion_scheduled_at = NULL
+WHERE id = $2 AND deleted_at IS NULL;
+
+-- name: ListUsersToPurge :many
+SELECT id, username
+FROM users
+WHERE deletion_scheduled_at <= $1
+ORDER BY deletion_scheduled_at
+LIMIT $2;
+
+-- name: LockUserToPurge :one
+-- Returns no rows if the deletion was cancelled meanwhile
+SELECT id
+FROM users
+WHERE id = $1 AND deletion_scheduled_at <= $2
+FOR UPDATE;
+
+-- name: PurgeUser :exec
+-- Hard deletes the posts, the follow graph and the credentials of the user, and anonymizes it. Its comments are
+-- kept, but nothing points to the user anymore, and its username and email are freed.
+WITH deleted_posts AS (
+	DELETE FROM posts WHERE user_id = @id
+), deleted_follows AS (
+	DELETE FROM followers WHERE user_id = @id OR follower_id = @id
+), deleted_follow_requests AS (
+	DELETE FROM follow_requests WHERE user_id = @id OR requester_id = @id
+), deleted_blocks AS (
+	DELETE FROM blocks WHERE user_id = @id OR blocked_id = @id
+), deleted_mutes AS (
+	DELETE FROM mutes WHERE user_id = @id OR muted_user_id = @id
+), deleted_suggestions AS (
+	DELETE FROM user_suggestions WHERE user_id = @id OR suggested_id = @id
+), deleted_identities AS (
+	DELETE FROM user_identities WHERE user_id = @id
+), deleted_totp AS (
+	DELETE FROM user_totp WHERE user_id = @id
+), deleted_recovery_codes AS (
+	DELETE FROM user_recovery_codes WHERE user_id = @id
+), deleted_challenges AS (
+	DELETE FROM two_factor_challenges WHERE user_id = @id
+), deleted_personal_access_tokens AS (
+	DELETE FROM personal_access_tokens WHERE user_id = @id
+), deleted_refresh_tokens AS (
+	DELETE FROM refresh_tokens WHERE user_id = @id
+), deleted_sessions AS (
+	DELETE FROM sessions WHERE user_id = @id
+), deleted_invitations AS (
+	DELETE FROM user_invitations WHERE user_id = @id
+), deleted_password_resets AS (
+	DELETE FROM password_resets WHERE user_id = @id
+), deleted_email_changes AS (
+	DELETE FROM user_email_changes WHERE user_id = @id
+), deleted_login_attempts AS (
+	DELETE FROM login_attempts WHERE email = (SELECT u.email FROM users u WHERE u.id = @id)
+)
+UPDATE users
+SET
+	updated_at = @now,
+	username = 'deleted-' || id,
+	email = 'deleted-' || id || '@deleted.invalid',
+	password = ''::bytea,
+	first_name = NULL,
+	last_name = NULL,
+	bio = NULL,
+	website = NULL,
+	location = NULL,
+	pronouns = NULL,
+	avatar_url = NULL,
+	banner_url = NULL,
+	is_private = false,
+	deleted_at = coalesce(deleted_at, @now),
+	purged_at = @now,
+	deletion_scheduled_at = NULL
+WHERE id = @id;
+
+-- name: ListDeletedUsers :many
+-- Users pending deletion and soft deleted ones. Purged users are not listed, they can not be restored.
+SELECT
+	u.*, r.level, r.name,
+	(t.confirmed_at IS NOT NULL)::boolean AS two_factor_enabled
+FROM users u
+JOIN roles r ON u.role_id = r.id
+LEFT JOIN user_totp t ON t.user_id = u.id
+WHERE (u.deleted_at IS NOT NULL OR u.deletion_scheduled_at IS NOT NULL)
+	AND u.purged_at IS NULL
+	AND (sqlc.narg('username')::text IS NULL OR u.username = sqlc.narg('username'))
+	AND (sqlc.narg('since')::timestamp IS NULL OR coalesce(u.deleted_at, u.deletion_scheduled_at) >= sqlc.narg('since'))
+	AND (sqlc.narg('until')::timestamp IS NULL OR coalesce(u.deleted_at, u.deletion_scheduled_at) < sqlc.narg('until'))
+ORDER BY coalesce(u.deleted_at, u.deletion_scheduled_at) DESC
+LIMIT $1 OFFSET $2;
+
+-- name: RestoreUser :execrows
+-- Also cancels a pending deletion
+UPDATE users
+SET
+	updated_at = $1,
+	deleted_at = NULL,
+	deletion_scheduled_at = NULL
+WHERE id = $2
+	AND (deleted_at IS NOT NULL OR deletion_scheduled_at IS NOT NULL)
+	AND purged_at IS NULL;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS password_resets (
+	token_hash bytea PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_password_resets_user_id;
+
+DROP TABLE IF EXISTS password_resets;
@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret bytea NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	confirmed_at TIMESTAMP,
+	last_used_step BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+	code_hash bytea PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user_id ON user_recovery_codes (user_id);
+
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+	token_hash bytea PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS two_factor_challenges;
+
+DROP INDEX IF EXISTS idx_user_recovery_codes_user_id;
+DROP TABLE IF EXISTS user_recovery_codes;
+
+DROP TABLE IF EXISTS user_totp;
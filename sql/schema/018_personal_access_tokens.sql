@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	token_hash bytea NOT NULL UNIQUE,
+	scopes TEXT[] NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_user_id ON personal_access_tokens (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_personal_access_tokens_user_id;
+
+DROP TABLE IF EXISTS personal_access_tokens;
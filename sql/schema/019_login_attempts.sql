@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS login_attempts (
+	id UUID PRIMARY KEY,
+	email TEXT NOT NULL,
+	ip TEXT NOT NULL,
+	user_agent TEXT NOT NULL,
+	outcome TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_created_at ON login_attempts (created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts (email);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts (ip);
+
+-- Failed logins, keyed by account (email) or IP
+CREATE TABLE IF NOT EXISTS login_throttles (
+	key TEXT PRIMARY KEY,
+	failures INT NOT NULL,
+	last_failure_at TIMESTAMP NOT NULL,
+	locked_until TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS login_throttles;
+
+DROP INDEX IF EXISTS idx_login_attempts_ip;
+DROP INDEX IF EXISTS idx_login_attempts_email;
+DROP INDEX IF EXISTS idx_login_attempts_created_at;
+DROP TABLE IF EXISTS login_attempts;
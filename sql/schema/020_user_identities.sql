@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_identities (
+	provider TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	email TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	last_login_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+-- Pending logins with an external provider
+CREATE TABLE IF NOT EXISTS oidc_states (
+	state_hash bytea PRIMARY KEY,
+	provider TEXT NOT NULL,
+	nonce TEXT NOT NULL,
+	code_verifier TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS oidc_states;
+
+DROP INDEX IF EXISTS idx_user_identities_user_id;
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- NOTE: The ID of a session is the family of its refresh tokens, and the `sid` claim of its access tokens
+CREATE TABLE IF NOT EXISTS sessions (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	ip TEXT NOT NULL,
+	user_agent TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	last_seen_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_sessions_user_id;
+
+DROP TABLE IF EXISTS sessions;
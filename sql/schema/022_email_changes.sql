@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS user_email_changes (
+	token_hash bytea PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	new_email TEXT NOT NULL,
+	expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_email_changes_user_id ON user_email_changes (user_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_user_email_changes_user_id;
+
+DROP TABLE IF EXISTS user_email_changes;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS audit_log (
+	id UUID PRIMARY KEY,
+	actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+	action TEXT NOT NULL,
+	target_type TEXT NOT NULL,
+	target_id TEXT NOT NULL,
+	details JSONB NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS permissions (
+	name TEXT PRIMARY KEY,
+	description TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+	role_id INT NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+	permission TEXT NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+	PRIMARY KEY (role_id, permission)
+);
+
+INSERT INTO
+	permissions (name, description)
+VALUES
+	('posts:update_any', 'Update posts of other users'),
+	('posts:delete_any', 'Delete posts of other users'),
+	('posts:hard_delete', 'Permanently delete posts'),
+	('users:hard_delete', 'Permanently delete users'),
+	('roles:manage', 'Create roles, assign them to users and grant them permissions'),
+	('sessions:manage', 'List and revoke sessions of other users'),
+	('login_attempts:read', 'List login attempts'),
+	('audit_log:read', 'List the audit log');
+
+-- NOTE: Same behaviour as the previous role levels: moderators update posts, admins do everything
+INSERT INTO
+	role_permissions (role_id, permission)
+SELECT r.id, p.name
+FROM roles r, permissions p
+WHERE r.name = 'admin'
+	OR (r.name = 'moderator' AND p.name = 'posts:update_any');
+
+-- +goose Down
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
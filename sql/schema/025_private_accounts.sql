@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN is_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS follow_requests (
+	user_id UUID NOT NULL,
+	requester_id UUID NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+
+	PRIMARY KEY(user_id, requester_id),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY(requester_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS follow_requests;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS is_private;
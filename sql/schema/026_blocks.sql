@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS blocks (
+	user_id UUID NOT NULL,
+	blocked_id UUID NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+
+	PRIMARY KEY(user_id, blocked_id),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY(blocked_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked_id ON blocks (blocked_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_blocks_blocked_id;
+
+DROP TABLE IF EXISTS blocks;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS mutes (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	kind TEXT NOT NULL CHECK (kind IN ('user', 'tag', 'keyword')),
+	-- Only for muted users
+	muted_user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+	-- Lowercase tag or keyword, empty for muted users
+	value TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	expires_at TIMESTAMP,
+
+	CHECK ((kind = 'user') = (muted_user_id IS NOT NULL)),
+	UNIQUE NULLS NOT DISTINCT (user_id, kind, muted_user_id, value)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS mutes;
@@ -0,0 +1,65 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN follower_count INT NOT NULL DEFAULT 0,
+ADD COLUMN following_count INT NOT NULL DEFAULT 0,
+ADD COLUMN post_count INT NOT NULL DEFAULT 0;
+
+UPDATE users u
+SET
+	follower_count = (SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id),
+	following_count = (SELECT COUNT(*) FROM followers f WHERE f.follower_id = u.id),
+	post_count = (SELECT COUNT(*) FROM posts p WHERE p.user_id = u.id AND NOT p.is_deleted);
+
+-- Counters are kept by triggers, so every way of following (or deleting users, which cascades) updates them
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_follow_counts() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		UPDATE users SET follower_count = follower_count + 1 WHERE id = NEW.user_id;
+		UPDATE users SET following_count = following_count + 1 WHERE id = NEW.follower_id;
+	ELSE
+		UPDATE users SET follower_count = follower_count - 1 WHERE id = OLD.user_id;
+		UPDATE users SET following_count = following_count - 1 WHERE id = OLD.follower_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER followers_update_counts
+AFTER INSERT OR DELETE ON followers
+FOR EACH ROW EXECUTE FUNCTION update_follow_counts();
+
+-- Soft-deleted posts are not counted
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_post_count() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' AND NOT NEW.is_deleted THEN
+		UPDATE users SET post_count = post_count + 1 WHERE id = NEW.user_id;
+	ELSIF TG_OP = 'DELETE' AND NOT OLD.is_deleted THEN
+		UPDATE users SET post_count = post_count - 1 WHERE id = OLD.user_id;
+	ELSIF TG_OP = 'UPDATE' AND OLD.is_deleted <> NEW.is_deleted THEN
+		UPDATE users
+		SET post_count = post_count + CASE WHEN NEW.is_deleted THEN -1 ELSE 1 END
+		WHERE id = NEW.user_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER posts_update_count
+AFTER INSERT OR DELETE OR UPDATE OF is_deleted ON posts
+FOR EACH ROW EXECUTE FUNCTION update_post_count();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS posts_update_count ON posts;
+DROP FUNCTION IF EXISTS update_post_count;
+
+DROP TRIGGER IF EXISTS followers_update_counts ON followers;
+DROP FUNCTION IF EXISTS update_follow_counts;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS post_count,
+DROP COLUMN IF EXISTS following_count,
+DROP COLUMN IF EXISTS follower_count;
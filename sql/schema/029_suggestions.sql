@@ -0,0 +1,23 @@
+-- +goose Up
+-- Precomputed "who to follow" suggestions, see RefreshSuggestions
+CREATE TABLE IF NOT EXISTS user_suggestions (
+	user_id UUID NOT NULL,
+	suggested_id UUID NOT NULL,
+	score DOUBLE PRECISION NOT NULL,
+	-- Users followed by the user who follow the suggested one
+	mutual_count INT NOT NULL,
+	-- Tags used by both users on their recent posts
+	shared_tags INT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+
+	PRIMARY KEY(user_id, suggested_id),
+	FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+	FOREIGN KEY(suggested_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_followers_follower_id ON followers(follower_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_followers_follower_id;
+
+DROP TABLE IF EXISTS user_suggestions;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN bio TEXT,
+ADD COLUMN website TEXT,
+ADD COLUMN location TEXT,
+ADD COLUMN pronouns TEXT,
+-- URLs of the images
+ADD COLUMN avatar_url TEXT,
+ADD COLUMN banner_url TEXT;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN IF EXISTS bio,
+DROP COLUMN IF EXISTS website,
+DROP COLUMN IF EXISTS location,
+DROP COLUMN IF EXISTS pronouns,
+DROP COLUMN IF EXISTS avatar_url,
+DROP COLUMN IF EXISTS banner_url;
@@ -0,0 +1,15 @@
+-- +goose Up
+INSERT INTO
+	permissions (name, description)
+VALUES
+	('users:read_private', 'See the private information of other users, e.g. their email');
+
+INSERT INTO
+	role_permissions (role_id, permission)
+SELECT r.id, 'users:read_private'
+FROM roles r
+WHERE r.name = 'admin';
+
+-- +goose Down
+DELETE FROM permissions
+WHERE name = 'users:read_private';
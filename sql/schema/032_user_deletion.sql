@@ -0,0 +1,27 @@
+-- +goose Up
+-- When the account will be purged, unless the user logs in before
+ALTER TABLE users
+ADD COLUMN deletion_scheduled_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users (deletion_scheduled_at)
+WHERE deletion_scheduled_at IS NOT NULL;
+
+INSERT INTO
+	permissions (name, description)
+VALUES
+	('users:delete_any', 'Request the deletion of other users');
+
+INSERT INTO
+	role_permissions (role_id, permission)
+SELECT r.id, 'users:delete_any'
+FROM roles r
+WHERE r.name = 'admin';
+
+-- +goose Down
+DELETE FROM permissions
+WHERE name = 'users:delete_any';
+
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS deletion_scheduled_at;
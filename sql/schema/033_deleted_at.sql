@@ -0,0 +1,95 @@
+-- +goose Up
+-- When the row was soft deleted, NULL while it is not. Rows deleted before are assumed to be deleted on their last update.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+UPDATE users SET deleted_at = updated_at WHERE is_deleted;
+
+-- When the user was purged. Purged users are anonymized, so they can not be restored.
+ALTER TABLE users ADD COLUMN purged_at TIMESTAMP;
+
+ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMP;
+UPDATE posts SET deleted_at = updated_at WHERE is_deleted;
+
+-- The post counter depended on the old column
+DROP TRIGGER IF EXISTS posts_update_count ON posts;
+
+ALTER TABLE users DROP COLUMN is_deleted;
+ALTER TABLE posts DROP COLUMN is_deleted;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at)
+WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at)
+WHERE deleted_at IS NOT NULL;
+
+-- Soft-deleted posts are not counted
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_post_count() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' AND NEW.deleted_at IS NULL THEN
+		UPDATE users SET post_count = post_count + 1 WHERE id = NEW.user_id;
+	ELSIF TG_OP = 'DELETE' AND OLD.deleted_at IS NULL THEN
+		UPDATE users SET post_count = post_count - 1 WHERE id = OLD.user_id;
+	ELSIF TG_OP = 'UPDATE' AND (OLD.deleted_at IS NULL) <> (NEW.deleted_at IS NULL) THEN
+		UPDATE users
+		SET post_count = post_count + CASE WHEN NEW.deleted_at IS NULL THEN 1 ELSE -1 END
+		WHERE id = NEW.user_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER posts_update_count
+AFTER INSERT OR DELETE OR UPDATE OF deleted_at ON posts
+FOR EACH ROW EXECUTE FUNCTION update_post_count();
+
+INSERT INTO
+	permissions (name, description)
+VALUES
+	('users:restore', 'List and restore soft deleted users'),
+	('posts:restore', 'List and restore soft deleted posts');
+
+INSERT INTO
+	role_permissions (role_id, permission)
+SELECT r.id, p.name
+FROM roles r
+CROSS JOIN (VALUES ('users:restore'), ('posts:restore')) AS p(name)
+WHERE r.name = 'admin';
+
+-- +goose Down
+DELETE FROM permissions
+WHERE name IN ('users:restore', 'posts:restore');
+
+DROP TRIGGER IF EXISTS posts_update_count ON posts;
+
+DROP INDEX IF EXISTS idx_posts_deleted_at;
+DROP INDEX IF EXISTS idx_users_deleted_at;
+
+ALTER TABLE posts ADD COLUMN is_deleted BOOLEAN NOT NULL DEFAULT false;
+UPDATE posts SET is_deleted = true WHERE deleted_at IS NOT NULL;
+ALTER TABLE posts DROP COLUMN deleted_at;
+
+ALTER TABLE users ADD COLUMN is_deleted BOOLEAN NOT NULL DEFAULT false;
+UPDATE users SET is_deleted = true WHERE deleted_at IS NOT NULL;
+ALTER TABLE users DROP COLUMN purged_at;
+ALTER TABLE users DROP COLUMN deleted_at;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION update_post_count() RETURNS TRIGGER AS $$
+BEGIN
+	IF TG_OP = 'INSERT' AND NOT NEW.is_deleted THEN
+		UPDATE users SET post_count = post_count + 1 WHERE id = NEW.user_id;
+	ELSIF TG_OP = 'DELETE' AND NOT OLD.is_deleted THEN
+		UPDATE users SET post_count = post_count - 1 WHERE id = OLD.user_id;
+	ELSIF TG_OP = 'UPDATE' AND OLD.is_deleted <> NEW.is_deleted THEN
+		UPDATE users
+		SET post_count = post_count + CASE WHEN NEW.is_deleted THEN -1 ELSE 1 END
+		WHERE id = NEW.user_id;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER posts_update_count
+AFTER INSERT OR DELETE OR UPDATE OF is_deleted ON posts
+FOR EACH ROW EXECUTE FUNCTION update_post_count();
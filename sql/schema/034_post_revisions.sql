@@ -0,0 +1,20 @@
+-- +goose Up
+-- Previous contents of posts, one for each version replaced by an edit
+CREATE TABLE IF NOT EXISTS post_revisions (
+	post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+	version INT NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	tags TEXT[],
+	-- When the version was written
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY(post_id, version)
+);
+
+-- When the post was last edited, NULL if it never was
+ALTER TABLE posts ADD COLUMN edited_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE posts DROP COLUMN IF EXISTS edited_at;
+
+DROP TABLE IF EXISTS post_revisions;
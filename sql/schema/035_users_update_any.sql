@@ -0,0 +1,15 @@
+-- +goose Up
+INSERT INTO
+	permissions (name, description)
+VALUES
+	('users:update_any', 'Update the profile of other users');
+
+INSERT INTO
+	role_permissions (role_id, permission)
+SELECT r.id, 'users:update_any'
+FROM roles r
+WHERE r.name = 'admin';
+
+-- +goose Down
+DELETE FROM permissions
+WHERE name = 'users:update_any';
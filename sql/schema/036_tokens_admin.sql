@@ -0,0 +1,15 @@
+-- +goose Up
+INSERT INTO
+	permissions (name, description)
+VALUES
+	('tokens:admin', 'Create personal access tokens with the admin scope');
+
+INSERT INTO
+	role_permissions (role_id, permission)
+SELECT r.id, 'tokens:admin'
+FROM roles r
+WHERE r.name = 'admin';
+
+-- +goose Down
+DELETE FROM permissions
+WHERE name = 'tokens:admin';
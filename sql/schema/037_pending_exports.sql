@@ -0,0 +1,10 @@
+-- +goose Up
+-- Exports being generated, at most one for each user
+CREATE TABLE IF NOT EXISTS pending_exports (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	id UUID NOT NULL,
+	started_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS pending_exports;